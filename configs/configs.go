@@ -23,6 +23,13 @@ type DBServer interface {
 
 type Database struct {
 	DBServer DBServer
+	// TxOptions are used by WithTx and BeginTx, nil keeps the driver's default isolation level.
+	TxOptions *sql.TxOptions
+	// Meta keeps the metadata of the tables of this database, nil uses the Namespace of
+	// DefaultMetaFile.
+	Meta MetaStore
+	// Namespace identifies the database in shared metadata files, see NamespaceKey.
+	Namespace string
 }
 
 func (d *Database) DB() *sql.DB {
@@ -33,26 +40,56 @@ func (d *Database) Close() error {
 	return d.DBServer.Close()
 }
 
+// MetaStore returns the metadata store of the database. NewDatabase always sets Meta, without
+// it the namespace of the database in DefaultMetaFile is used; when that file cannot be loaded
+// the store knows no table and every change fails with the error.
+func (d *Database) MetaStore() MetaStore {
+	if d == nil {
+		return DefaultMetaStore()
+	}
+	if d.Meta == nil {
+		return defaultNamespace(d.Namespace)
+	}
+	return d.Meta
+}
+
 type MetaTableDetails struct {
-	Schema    map[string]FieldSchema `json:"schema"`
-	Timestamp string                 `json:"timestamp"`
-	Details   string                 `json:"details"`
+	Schema    TableSchema `json:"schema"`
+	Timestamp string      `json:"timestamp"`
+	Details   string      `json:"details"`
 }
 
+// MetaTableList is the MetaStore of one namespace of a MetaFile, or only kept in memory when it
+// has no file.
 type MetaTableList struct {
 	mu             sync.Mutex
+	file           *MetaFile
+	namespace      string
 	ExistingTables map[string]MetaTableDetails `json:"existing_tables"`
 }
 
 var (
-	instance *MetaTableList
-	once     sync.Once
+	defaultFile    *MetaFile
+	defaultFileErr error
+	once           sync.Once
 )
 
 type FieldSchema struct {
 	Type string `json:"Type"`
 	Null string `json:"Null"`
 	Key  string `json:"Key"`
-	// Default interface{} `json:"Default"`
-	Extra string `json:"Extra"`
+	// Default is the DEFAULT clause as written in DDL, nil when the column has none:
+	// 'active' for a string, 0, CURRENT_TIMESTAMP or (uuid()).
+	Default *string `json:"Default,omitempty"`
+	Extra   string  `json:"Extra"`
+	Comment string  `json:"Comment,omitempty"`
+	// Collation and Charset are passed through to the dialect, e.g. utf8mb4_unicode_ci on MySQL.
+	Collation string `json:"Collation,omitempty"`
+	Charset   string `json:"Charset,omitempty"`
+	Unsigned  bool   `json:"Unsigned,omitempty"`
+	// OnUpdate is the ON UPDATE expression of a MySQL column, e.g. CURRENT_TIMESTAMP.
+	OnUpdate string `json:"OnUpdate,omitempty"`
+	// Generated is the expression of a generated column, GeneratedType is VIRTUAL or STORED.
+	Generated     string `json:"Generated,omitempty"`
+	GeneratedType string `json:"GeneratedType,omitempty"`
 }
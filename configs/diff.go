@@ -0,0 +1,344 @@
+package configs
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ChangeKind tells how the database differs from the expected schema. Added means the
+// expected schema has something the database lacks, Removed that the database has something
+// the expected schema does not declare.
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "modified"
+)
+
+// ColumnChange is a column that is missing, unexpected or defined differently. Attributes
+// names the FieldSchema fields that differ for a modified column.
+type ColumnChange struct {
+	Kind       ChangeKind   `json:"kind"`
+	Name       string       `json:"name"`
+	Expected   *FieldSchema `json:"expected,omitempty"`
+	Actual     *FieldSchema `json:"actual,omitempty"`
+	Attributes []string     `json:"attributes,omitempty"`
+}
+
+// PrimaryKeyChange holds both primary keys when they cover different columns.
+type PrimaryKeyChange struct {
+	Expected []string `json:"expected"`
+	Actual   []string `json:"actual"`
+}
+
+// IndexChange is a unique constraint or index that is missing, unexpected or of another type.
+type IndexChange struct {
+	Kind     ChangeKind `json:"kind"`
+	Expected *IndexDef  `json:"expected,omitempty"`
+	Actual   *IndexDef  `json:"actual,omitempty"`
+}
+
+// ForeignKeyChange is a foreign key that is missing, unexpected or references something else.
+type ForeignKeyChange struct {
+	Kind     ChangeKind     `json:"kind"`
+	Expected *ForeignKeyDef `json:"expected,omitempty"`
+	Actual   *ForeignKeyDef `json:"actual,omitempty"`
+}
+
+// SchemaDiff lists the differences between the expected schema of a table and the database.
+type SchemaDiff struct {
+	Table       string             `json:"table"`
+	Columns     []ColumnChange     `json:"columns,omitempty"`
+	PrimaryKey  *PrimaryKeyChange  `json:"primary_key,omitempty"`
+	Uniques     []IndexChange      `json:"uniques,omitempty"`
+	Indexes     []IndexChange      `json:"indexes,omitempty"`
+	ForeignKeys []ForeignKeyChange `json:"foreign_keys,omitempty"`
+}
+
+// Empty reports whether the table matches its expected schema.
+func (d SchemaDiff) Empty() bool {
+	return len(d.Columns) == 0 && d.PrimaryKey == nil && len(d.Uniques) == 0 && len(d.Indexes) == 0 && len(d.ForeignKeys) == 0
+}
+
+// Diff compares an expected schema with the schema read from the database.
+//
+// Columns are matched by name, unique constraints and indexes by their columns and foreign
+// keys by their local columns; names are not compared since not every database keeps them.
+// A single column unique constraint and Key UNI on its column are the same.
+// Types, keywords and expressions are compared case insensitively and a NOT NULL primary key,
+// a missing Null and YES are treated alike. Key only matters for PRI and UNI, other indexes
+// are compared on their own.
+func Diff(expected TableSchema, actual TableSchema) SchemaDiff {
+	expected = foldUniques(expected)
+	actual = foldUniques(actual)
+	diff := SchemaDiff{}
+	expectedKeys := expected.PrimaryKeyColumns()
+	actualKeys := actual.PrimaryKeyColumns()
+
+	for _, column := range expected.Columns {
+		expectedField := normalizeField(column.FieldSchema, contains(expectedKeys, column.Name))
+		actualField, ok := actual.Column(column.Name)
+		if !ok {
+			fieldSchema := column.FieldSchema
+			diff.Columns = append(diff.Columns, ColumnChange{Kind: ChangeAdded, Name: column.Name, Expected: &fieldSchema})
+			continue
+		}
+		attributes := fieldDifferences(expectedField, normalizeField(actualField, contains(actualKeys, column.Name)))
+		if len(attributes) > 0 {
+			fieldSchema := column.FieldSchema
+			diff.Columns = append(diff.Columns, ColumnChange{Kind: ChangeModified, Name: column.Name, Expected: &fieldSchema, Actual: &actualField, Attributes: attributes})
+		}
+	}
+	for _, column := range actual.Columns {
+		if !expected.HasColumn(column.Name) {
+			fieldSchema := column.FieldSchema
+			diff.Columns = append(diff.Columns, ColumnChange{Kind: ChangeRemoved, Name: column.Name, Actual: &fieldSchema})
+		}
+	}
+
+	if !reflect.DeepEqual(lowerAll(expectedKeys), lowerAll(actualKeys)) {
+		diff.PrimaryKey = &PrimaryKeyChange{Expected: expectedKeys, Actual: actualKeys}
+	}
+	diff.Uniques = diffIndexes(expected.Uniques, actual.Uniques)
+	diff.Indexes = diffIndexes(expected.Indexes, actual.Indexes)
+	diff.ForeignKeys = diffForeignKeys(expected.ForeignKeys, actual.ForeignKeys)
+	return diff
+}
+
+// foldUniques moves single column unique constraints onto the Key of their column, which is how
+// the databases report them.
+func foldUniques(schema TableSchema) TableSchema {
+	schema = schema.Copy()
+	uniques := schema.Uniques[:0]
+	for _, unique := range schema.Uniques {
+		if len(unique.Columns) != 1 || !schema.HasColumn(unique.Columns[0]) {
+			uniques = append(uniques, unique)
+			continue
+		}
+		fieldSchema, _ := schema.Column(unique.Columns[0])
+		if fieldSchema.Key != "PRI" {
+			fieldSchema.Key = "UNI"
+			schema.SetColumn(unique.Columns[0], fieldSchema)
+		}
+	}
+	schema.Uniques = uniques
+	return schema
+}
+
+var (
+	integerDisplayWidth = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|integer|bigint)\(\d+\)`)
+	spaces              = regexp.MustCompile(`\s+`)
+)
+
+// normalizeField brings a column definition into the form Diff compares.
+func normalizeField(fieldSchema FieldSchema, primary bool) FieldSchema {
+	fieldSchema.Type = normalizeType(fieldSchema.Type)
+	switch {
+	case primary:
+		fieldSchema.Null = "NO"
+		fieldSchema.Key = "PRI"
+	case fieldSchema.Key != "UNI":
+		fieldSchema.Key = ""
+	}
+	if fieldSchema.Null != "NO" {
+		fieldSchema.Null = "YES"
+	}
+	fieldSchema.Extra = normalizeExpression(fieldSchema.Extra)
+	fieldSchema.OnUpdate = normalizeExpression(fieldSchema.OnUpdate)
+	fieldSchema.Generated = normalizeExpression(fieldSchema.Generated)
+	fieldSchema.GeneratedType = strings.ToUpper(fieldSchema.GeneratedType)
+	fieldSchema.Collation = strings.ToLower(fieldSchema.Collation)
+	fieldSchema.Charset = strings.ToLower(fieldSchema.Charset)
+	if fieldSchema.Default != nil {
+		value := normalizeDefault(*fieldSchema.Default)
+		fieldSchema.Default = &value
+	}
+	return fieldSchema
+}
+
+// normalizeType lower cases a type and drops the display width of integer types, which newer
+// MySQL versions no longer report. tinyint(1) keeps it as it stands for a boolean.
+func normalizeType(columnType string) string {
+	columnType = spaces.ReplaceAllString(strings.ToLower(strings.TrimSpace(columnType)), " ")
+	columnType = strings.ReplaceAll(columnType, ", ", ",")
+	if columnType == "tinyint(1)" {
+		return columnType
+	}
+	return integerDisplayWidth.ReplaceAllString(columnType, "$1")
+}
+
+// normalizeExpression lower cases an expression outside of quoted literals, drops the
+// whitespace and the parentheses wrapped around all of it.
+func normalizeExpression(expression string) string {
+	var sb strings.Builder
+	var quote rune
+	for _, r := range strings.TrimSpace(expression) {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ' ' || r == '\t' || r == '\n' || r == '`':
+			continue
+		default:
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return trimParens(sb.String())
+}
+
+// normalizeDefault keeps a quoted literal as it is and normalizes any other default.
+func normalizeDefault(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value
+	}
+	return normalizeExpression(value)
+}
+
+// trimParens removes parentheses that enclose the whole expression.
+func trimParens(expression string) string {
+	for len(expression) >= 2 && expression[0] == '(' && expression[len(expression)-1] == ')' {
+		depth := 0
+		enclosed := true
+		for i, r := range expression {
+			switch r {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			if depth == 0 && i < len(expression)-1 {
+				enclosed = false
+				break
+			}
+		}
+		if !enclosed {
+			break
+		}
+		expression = expression[1 : len(expression)-1]
+	}
+	return expression
+}
+
+// fieldDifferences names the attributes in which two normalized columns differ.
+func fieldDifferences(expected FieldSchema, actual FieldSchema) []string {
+	var attributes []string
+	add := func(name string, differs bool) {
+		if differs {
+			attributes = append(attributes, name)
+		}
+	}
+	add("Type", expected.Type != actual.Type)
+	add("Null", expected.Null != actual.Null)
+	add("Key", expected.Key != actual.Key)
+	add("Default", (expected.Default == nil) != (actual.Default == nil) ||
+		(expected.Default != nil && *expected.Default != *actual.Default))
+	add("Extra", expected.Extra != actual.Extra)
+	add("Comment", expected.Comment != actual.Comment)
+	add("Collation", expected.Collation != actual.Collation)
+	add("Charset", expected.Charset != actual.Charset)
+	add("Unsigned", expected.Unsigned != actual.Unsigned)
+	add("OnUpdate", expected.OnUpdate != actual.OnUpdate)
+	add("Generated", expected.Generated != actual.Generated)
+	add("GeneratedType", expected.GeneratedType != actual.GeneratedType)
+	return attributes
+}
+
+// diffIndexes matches indexes by their columns, an index over the same columns with another
+// type is modified.
+func diffIndexes(expected []IndexDef, actual []IndexDef) []IndexChange {
+	var changes []IndexChange
+	for i := range expected {
+		match := findIndex(actual, expected[i].Columns)
+		switch {
+		case match == nil:
+			changes = append(changes, IndexChange{Kind: ChangeAdded, Expected: &expected[i]})
+		case !strings.EqualFold(expected[i].Type, match.Type):
+			changes = append(changes, IndexChange{Kind: ChangeModified, Expected: &expected[i], Actual: match})
+		}
+	}
+	for i := range actual {
+		if findIndex(expected, actual[i].Columns) == nil {
+			changes = append(changes, IndexChange{Kind: ChangeRemoved, Actual: &actual[i]})
+		}
+	}
+	return changes
+}
+
+func findIndex(indexes []IndexDef, columns []string) *IndexDef {
+	for i := range indexes {
+		if reflect.DeepEqual(lowerAll(indexes[i].Columns), lowerAll(columns)) {
+			return &indexes[i]
+		}
+	}
+	return nil
+}
+
+// diffForeignKeys matches foreign keys by their local columns. An empty action and NO ACTION
+// are the same.
+func diffForeignKeys(expected []ForeignKeyDef, actual []ForeignKeyDef) []ForeignKeyChange {
+	var changes []ForeignKeyChange
+	for i := range expected {
+		match := findForeignKey(actual, expected[i].Columns)
+		switch {
+		case match == nil:
+			changes = append(changes, ForeignKeyChange{Kind: ChangeAdded, Expected: &expected[i]})
+		case !sameReference(expected[i], *match):
+			changes = append(changes, ForeignKeyChange{Kind: ChangeModified, Expected: &expected[i], Actual: match})
+		}
+	}
+	for i := range actual {
+		if findForeignKey(expected, actual[i].Columns) == nil {
+			changes = append(changes, ForeignKeyChange{Kind: ChangeRemoved, Actual: &actual[i]})
+		}
+	}
+	return changes
+}
+
+func findForeignKey(foreignKeys []ForeignKeyDef, columns []string) *ForeignKeyDef {
+	for i := range foreignKeys {
+		if reflect.DeepEqual(lowerAll(foreignKeys[i].Columns), lowerAll(columns)) {
+			return &foreignKeys[i]
+		}
+	}
+	return nil
+}
+
+func sameReference(a ForeignKeyDef, b ForeignKeyDef) bool {
+	return strings.EqualFold(a.ReferencedTable, b.ReferencedTable) &&
+		reflect.DeepEqual(lowerAll(a.ReferencedColumns), lowerAll(b.ReferencedColumns)) &&
+		normalizeAction(a.OnDelete) == normalizeAction(b.OnDelete) &&
+		normalizeAction(a.OnUpdate) == normalizeAction(b.OnUpdate)
+}
+
+func normalizeAction(action string) string {
+	action = strings.ToUpper(strings.Join(strings.Fields(action), " "))
+	if action == "NO ACTION" {
+		return ""
+	}
+	return action
+}
+
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, value := range values {
+		lowered[i] = strings.ToLower(value)
+	}
+	return lowered
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package configs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func column(name string, fieldType string, null string, key string) ColumnDef {
+	return ColumnDef{Name: name, FieldSchema: FieldSchema{Type: fieldType, Null: null, Key: key}}
+}
+
+func withDefault(c ColumnDef, value string) ColumnDef {
+	c.Default = &value
+	return c
+}
+
+// changes summarizes a diff as one line per change.
+func changes(d SchemaDiff) []string {
+	var lines []string
+	for _, c := range d.Columns {
+		lines = append(lines, strings.TrimSpace(fmt.Sprintf("column %s %s %s", c.Kind, c.Name, strings.Join(c.Attributes, ","))))
+	}
+	if d.PrimaryKey != nil {
+		lines = append(lines, fmt.Sprintf("primary key %v -> %v", d.PrimaryKey.Actual, d.PrimaryKey.Expected))
+	}
+	index := func(kind string, c IndexChange) {
+		def := c.Expected
+		if def == nil {
+			def = c.Actual
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %s", kind, c.Kind, strings.Join(def.Columns, ",")))
+	}
+	for _, c := range d.Uniques {
+		index("unique", c)
+	}
+	for _, c := range d.Indexes {
+		index("index", c)
+	}
+	for _, c := range d.ForeignKeys {
+		def := c.Expected
+		if def == nil {
+			def = c.Actual
+		}
+		lines = append(lines, fmt.Sprintf("foreign key %s %s", c.Kind, strings.Join(def.Columns, ",")))
+	}
+	return lines
+}
+
+func TestDiff(t *testing.T) {
+	users := NewTableSchema(
+		column("id", "int", "NO", "PRI"),
+		column("email", "varchar(255)", "NO", "UNI"),
+		withDefault(column("status", "varchar(20)", "YES", ""), "'active'"),
+	)
+	tests := []struct {
+		name     string
+		expected TableSchema
+		actual   TableSchema
+		want     []string
+	}{
+		{
+			name:     "identical",
+			expected: users,
+			actual:   users,
+		},
+		{
+			name:     "type case, display width and spacing",
+			expected: NewTableSchema(column("id", "INT", "NO", "PRI"), column("price", "decimal(10, 2)", "", "")),
+			actual:   NewTableSchema(column("id", "int(11)", "", "PRI"), column("price", "DECIMAL(10,2)", "YES", "MUL")),
+		},
+		{
+			name:     "tinyint(1) keeps its width",
+			expected: NewTableSchema(column("active", "tinyint(1)", "YES", "")),
+			actual:   NewTableSchema(column("active", "tinyint(4)", "YES", "")),
+			want:     []string{"column modified active Type"},
+		},
+		{
+			name:     "added and removed columns",
+			expected: NewTableSchema(column("id", "int", "NO", "PRI"), column("name", "text", "YES", "")),
+			actual:   NewTableSchema(column("id", "int", "NO", "PRI"), column("legacy", "text", "YES", "")),
+			want:     []string{"column added name", "column removed legacy"},
+		},
+		{
+			name:     "modified attributes",
+			expected: NewTableSchema(withDefault(column("status", "varchar(20)", "NO", ""), "'active'")),
+			actual:   NewTableSchema(withDefault(column("status", "varchar(10)", "YES", ""), "'Active'")),
+			want:     []string{"column modified status Type,Null,Default"},
+		},
+		{
+			name:     "default expressions",
+			expected: NewTableSchema(withDefault(column("created_at", "timestamp", "YES", ""), "CURRENT_TIMESTAMP")),
+			actual:   NewTableSchema(withDefault(column("created_at", "timestamp", "YES", ""), "(current_timestamp)")),
+		},
+		{
+			name:     "missing default",
+			expected: NewTableSchema(withDefault(column("n", "int", "YES", ""), "0")),
+			actual:   NewTableSchema(column("n", "int", "YES", "")),
+			want:     []string{"column modified n Default"},
+		},
+		{
+			name:     "single column unique is the key of its column",
+			expected: TableSchema{Columns: []ColumnDef{column("email", "text", "YES", "")}, Uniques: []IndexDef{{Columns: []string{"email"}, Type: "UNIQUE"}}},
+			actual:   NewTableSchema(column("email", "text", "YES", "UNI")),
+		},
+		{
+			name:     "lost unique key",
+			expected: NewTableSchema(column("email", "text", "YES", "UNI")),
+			actual:   NewTableSchema(column("email", "text", "YES", "")),
+			want:     []string{"column modified email Key"},
+		},
+		{
+			name: "composite primary key",
+			expected: TableSchema{
+				Columns:    []ColumnDef{column("a", "int", "NO", ""), column("b", "int", "NO", "")},
+				PrimaryKey: []string{"a", "b"},
+			},
+			actual: TableSchema{
+				Columns:    []ColumnDef{column("a", "int", "NO", "PRI"), column("b", "int", "NO", "")},
+				PrimaryKey: []string{"a"},
+			},
+			want: []string{"column modified b Key", "primary key [a] -> [a b]"},
+		},
+		{
+			name: "indexes by columns, not by name",
+			expected: TableSchema{
+				Columns: []ColumnDef{column("a", "int", "YES", ""), column("b", "int", "YES", "")},
+				Uniques: []IndexDef{{Name: "uq_a_b", Columns: []string{"a", "b"}, Type: "UNIQUE"}},
+				Indexes: []IndexDef{{Name: "idx_a", Columns: []string{"a"}}, {Columns: []string{"b"}, Type: "FULLTEXT"}},
+			},
+			actual: TableSchema{
+				Columns: []ColumnDef{column("a", "int", "YES", ""), column("b", "int", "YES", "")},
+				Uniques: []IndexDef{{Name: "something_else", Columns: []string{"A", "B"}, Type: "unique"}},
+				Indexes: []IndexDef{{Name: "idx_b", Columns: []string{"b"}}, {Name: "idx_a_b", Columns: []string{"a", "b"}}},
+			},
+			want: []string{"index added a", "index modified b", "index removed a,b"},
+		},
+		{
+			name: "foreign keys",
+			expected: TableSchema{
+				Columns: []ColumnDef{column("user_id", "int", "YES", ""), column("team_id", "int", "YES", ""), column("org_id", "int", "YES", "")},
+				ForeignKeys: []ForeignKeyDef{
+					{Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}, OnDelete: "NO ACTION"},
+					{Columns: []string{"team_id"}, ReferencedTable: "teams", ReferencedColumns: []string{"id"}, OnDelete: "CASCADE"},
+					{Columns: []string{"org_id"}, ReferencedTable: "orgs", ReferencedColumns: []string{"id"}},
+				},
+			},
+			actual: TableSchema{
+				Columns: []ColumnDef{column("user_id", "int", "YES", ""), column("team_id", "int", "YES", ""), column("org_id", "int", "YES", "")},
+				ForeignKeys: []ForeignKeyDef{
+					{Name: "fk_1", Columns: []string{"user_id"}, ReferencedTable: "USERS", ReferencedColumns: []string{"ID"}},
+					{Name: "fk_2", Columns: []string{"team_id"}, ReferencedTable: "teams", ReferencedColumns: []string{"id"}, OnDelete: "set null"},
+					{Name: "fk_3", Columns: []string{"owner_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+				},
+			},
+			want: []string{"foreign key modified team_id", "foreign key added org_id", "foreign key removed owner_id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Diff(tt.expected, tt.actual)
+			got := changes(d)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff\n got %q\nwant %q", got, tt.want)
+			}
+			if d.Empty() != (len(tt.want) == 0) {
+				t.Errorf("Empty() = %v for %q", d.Empty(), got)
+			}
+		})
+	}
+}
+
+func TestDiffDoesNotModifyItsArguments(t *testing.T) {
+	expected := TableSchema{
+		Columns: []ColumnDef{column("email", "text", "YES", "")},
+		Uniques: []IndexDef{{Columns: []string{"email"}, Type: "UNIQUE"}},
+	}
+	Diff(expected, NewTableSchema())
+	if len(expected.Uniques) != 1 || expected.Columns[0].Key != "" {
+		t.Errorf("expected schema changed to %+v", expected)
+	}
+}
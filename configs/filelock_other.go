@@ -0,0 +1,15 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package configs
+
+import "os"
+
+// lockFile does nothing where no file locking is available, the file is only guarded against
+// other goroutines of the process.
+func lockFile(file *os.File) error {
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
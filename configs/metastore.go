@@ -0,0 +1,121 @@
+package configs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// MetaTable is the table the DatabaseMetaStore keeps its metadata in.
+const MetaTable = "sqldocify_meta"
+
+// MetaStore keeps the schema details of the tables of one database. FindMetaTable returns a
+// copy, nil when the table is unknown.
+type MetaStore interface {
+	FindMetaTable(name string) *MetaTableDetails
+	MetaTables() map[string]MetaTableDetails
+	UpdateMetaTable(name string, details MetaTableDetails) error
+	RemoveMetaTable(name string) error
+}
+
+var (
+	_ MetaStore = (*MetaTableList)(nil)
+	_ MetaStore = (*DatabaseMetaStore)(nil)
+	_ MetaStore = unavailableMetaStore{}
+)
+
+// DatabaseMetaStore keeps the metadata in MetaTable of the database it describes, one row per
+// table with its details as JSON. Reads are served from memory, writes go through to the table.
+type DatabaseMetaStore struct {
+	db    *Database
+	cache *MetaTableList
+}
+
+// NewDatabaseMetaStore creates MetaTable when needed and loads the stored tables.
+func NewDatabaseMetaStore(db *Database) (*DatabaseMetaStore, error) {
+	if db == nil || db.DB() == nil {
+		return nil, errors.New("no active database connection")
+	}
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (table_name VARCHAR(255) NOT NULL PRIMARY KEY, details TEXT NOT NULL)", MetaTable)
+	if _, err := db.DB().Exec(query); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", MetaTable, err)
+	}
+
+	rows, err := db.DB().Query(fmt.Sprintf("SELECT table_name, details FROM %s", MetaTable))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", MetaTable, err)
+	}
+	defer rows.Close()
+
+	store := &DatabaseMetaStore{db: db, cache: NewMemoryMetaStore()}
+	for rows.Next() {
+		var name, data string
+		if err := rows.Scan(&name, &data); err != nil {
+			return nil, err
+		}
+		var details MetaTableDetails
+		if err := json.Unmarshal([]byte(data), &details); err != nil {
+			return nil, fmt.Errorf("invalid metadata of table %s: %w", name, err)
+		}
+		store.cache.ExistingTables[name] = details
+	}
+	return store, rows.Err()
+}
+
+func (s *DatabaseMetaStore) FindMetaTable(name string) *MetaTableDetails {
+	return s.cache.FindMetaTable(name)
+}
+
+func (s *DatabaseMetaStore) MetaTables() map[string]MetaTableDetails {
+	return s.cache.MetaTables()
+}
+
+// UpdateMetaTable replaces the row of the table, the cache only changes once it is written.
+func (s *DatabaseMetaStore) UpdateMetaTable(name string, details MetaTableDetails) error {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	err = s.db.WithTx(context.Background(), func(tx *Tx) error {
+		if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE table_name = %s", MetaTable, bindPlaceholder(1)), name); err != nil {
+			return err
+		}
+		_, err := tx.Exec(fmt.Sprintf("INSERT INTO %s (table_name, details) VALUES (%s, %s)", MetaTable, bindPlaceholder(1), bindPlaceholder(2)), name, string(data))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store metadata of table %s: %w", name, err)
+	}
+	return s.cache.UpdateMetaTable(name, details)
+}
+
+func (s *DatabaseMetaStore) RemoveMetaTable(name string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE table_name = %s", MetaTable, bindPlaceholder(1))
+	if _, err := s.db.DB().Exec(query, name); err != nil {
+		return fmt.Errorf("failed to remove metadata of table %s: %w", name, err)
+	}
+	return s.cache.RemoveMetaTable(name)
+}
+
+// unavailableMetaStore stands in for a store that could not be loaded. It knows no table and
+// every change fails with err.
+type unavailableMetaStore struct {
+	err error
+}
+
+func (s unavailableMetaStore) FindMetaTable(name string) *MetaTableDetails {
+	return nil
+}
+
+func (s unavailableMetaStore) MetaTables() map[string]MetaTableDetails {
+	return map[string]MetaTableDetails{}
+}
+
+func (s unavailableMetaStore) UpdateMetaTable(name string, details MetaTableDetails) error {
+	return s.err
+}
+
+func (s unavailableMetaStore) RemoveMetaTable(name string) error {
+	return s.err
+}
@@ -0,0 +1,463 @@
+package configs
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MigrationsTable records the migrations applied to a database.
+const MigrationsTable = "sqldocify_migrations"
+
+// Migration is one versioned schema change. A Go migration sets Up and Down, a file based
+// one UpSQL and DownSQL. Up and Down run inside a transaction together with the update of
+// the history table; a migration without a down step cannot be rolled back.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *Tx) error
+	Down    func(tx *Tx) error
+	UpSQL   string
+	DownSQL string
+}
+
+// Checksum identifies the content of a migration, it is stored when the migration is applied
+// so later edits are detected. SQL migrations hash their up script, Go migrations their
+// version and name.
+func (m Migration) Checksum() string {
+	content := m.UpSQL
+	if m.Up != nil {
+		content = fmt.Sprintf("%d:%s", m.Version, m.Name)
+	}
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func (m Migration) hasDown() bool {
+	return m.Down != nil || strings.TrimSpace(m.DownSQL) != ""
+}
+
+// MigrationStatus describes a registered or applied migration. Modified is set when an
+// applied migration changed since, Missing when it is no longer registered.
+type MigrationStatus struct {
+	Version   int64         `json:"version"`
+	Name      string        `json:"name"`
+	Applied   bool          `json:"applied"`
+	Checksum  string        `json:"checksum,omitempty"`
+	AppliedAt time.Time     `json:"applied_at,omitempty"`
+	Duration  time.Duration `json:"duration,omitempty"`
+	Modified  bool          `json:"modified,omitempty"`
+	Missing   bool          `json:"missing,omitempty"`
+}
+
+var (
+	migrationsMu sync.Mutex
+	migrations   = make(map[int64]Migration)
+)
+
+// RegisterMigration adds a Go migration, usually from an init function. It panics when the
+// version is not positive or already registered.
+func RegisterMigration(migration Migration) {
+	if err := addMigration(migration); err != nil {
+		panic(err)
+	}
+}
+
+func addMigration(migration Migration) error {
+	if migration.Version <= 0 {
+		return fmt.Errorf("migration %q needs a positive version", migration.Name)
+	}
+	if migration.Up == nil && strings.TrimSpace(migration.UpSQL) == "" {
+		return fmt.Errorf("migration %d has no up step", migration.Version)
+	}
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+	if existing, ok := migrations[migration.Version]; ok {
+		return fmt.Errorf("migration %d is registered twice: %s and %s", migration.Version, existing.Name, migration.Name)
+	}
+	migrations[migration.Version] = migration
+	return nil
+}
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// RegisterMigrationsDir registers the SQL migrations of a directory. Files are named
+// NNNN_name.up.sql and NNNN_name.down.sql, the number is the version; the down file is optional.
+func RegisterMigrationsDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read migrations directory %s: %w", dir, err)
+	}
+	found := make(map[int64]*Migration)
+	var versions []int64
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		migration, ok := found[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			found[version] = migration
+			versions = append(versions, version)
+		} else if migration.Name != match[2] {
+			return fmt.Errorf("migration %d has files named %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.UpSQL = string(content)
+		} else {
+			migration.DownSQL = string(content)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	for _, version := range versions {
+		if err := addMigration(*found[version]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registeredMigrations returns the registered migrations by ascending version.
+func registeredMigrations() []Migration {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+	list := make([]Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		list = append(list, migration)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list
+}
+
+// Migrate applies the registered migrations that are not applied yet, by ascending version,
+// and returns the ones it applied. Each migration commits together with its history row, so a
+// failing migration leaves the earlier ones applied. Applied migrations whose checksum changed
+// stop the run before anything is applied. MySQL commits DDL statements on its own, a failing
+// MySQL migration may be partly applied.
+//
+// Instances migrating the same database at the same time wait for each other through an
+// advisory lock on MySQL and PostgreSQL, and the primary key of the history table keeps a
+// version from being recorded twice.
+func (d *Database) Migrate(ctx context.Context) ([]MigrationStatus, error) {
+	var applied []MigrationStatus
+	err := d.withMigrationLock(ctx, func() error {
+		history, err := d.migrationHistory(ctx)
+		if err != nil {
+			return err
+		}
+		pending := registeredMigrations()
+		for _, migration := range pending {
+			if record, ok := history[migration.Version]; ok && record.Checksum != migration.Checksum() {
+				return fmt.Errorf("migration %d (%s) was changed after it was applied", migration.Version, migration.Name)
+			}
+		}
+		for _, migration := range pending {
+			if _, ok := history[migration.Version]; ok {
+				continue
+			}
+			status, err := d.applyMigration(ctx, migration)
+			if err != nil {
+				return err
+			}
+			applied = append(applied, status)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Rollback reverts the last n applied migrations, newest first, and returns the ones it
+// reverted. A migration that is no longer registered or has no down step stops the rollback.
+func (d *Database) Rollback(ctx context.Context, n int) ([]MigrationStatus, error) {
+	var reverted []MigrationStatus
+	err := d.withMigrationLock(ctx, func() error {
+		history, err := d.migrationHistory(ctx)
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(history))
+		for version := range history {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+		if n < 0 {
+			n = 0
+		}
+		if n < len(versions) {
+			versions = versions[:n]
+		}
+
+		registered := make(map[int64]Migration)
+		for _, migration := range registeredMigrations() {
+			registered[migration.Version] = migration
+		}
+		for _, version := range versions {
+			migration, ok := registered[version]
+			if !ok {
+				return fmt.Errorf("cannot roll back migration %d, it is not registered", version)
+			}
+			if !migration.hasDown() {
+				return fmt.Errorf("cannot roll back migration %d (%s), it has no down step", version, migration.Name)
+			}
+			err := d.WithTx(ctx, func(tx *Tx) error {
+				if err := runMigrationStep(tx, migration.Down, migration.DownSQL); err != nil {
+					return err
+				}
+				_, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE version = %s", MigrationsTable, bindPlaceholder(1)), version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to roll back migration %d (%s): %w", version, migration.Name, err)
+			}
+			reverted = append(reverted, history[version])
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists the registered and the applied migrations by ascending version.
+func (d *Database) Status(ctx context.Context) ([]MigrationStatus, error) {
+	if err := d.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	history, err := d.migrationHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []MigrationStatus
+	for _, migration := range registeredMigrations() {
+		status, ok := history[migration.Version]
+		if ok {
+			status.Modified = status.Checksum != migration.Checksum()
+			delete(history, migration.Version)
+		} else {
+			status = MigrationStatus{Version: migration.Version, Name: migration.Name, Checksum: migration.Checksum()}
+		}
+		statuses = append(statuses, status)
+	}
+	for _, status := range history {
+		status.Missing = true
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+func (d *Database) applyMigration(ctx context.Context, migration Migration) (MigrationStatus, error) {
+	status := MigrationStatus{Version: migration.Version, Name: migration.Name, Applied: true, Checksum: migration.Checksum()}
+	start := time.Now()
+	err := d.WithTx(ctx, func(tx *Tx) error {
+		if err := runMigrationStep(tx, migration.Up, migration.UpSQL); err != nil {
+			return err
+		}
+		status.AppliedAt = time.Now().UTC()
+		status.Duration = time.Since(start)
+		query := fmt.Sprintf("INSERT INTO %s (version, name, checksum, applied_at, duration_ms) VALUES (%s, %s, %s, %s, %s)",
+			MigrationsTable, bindPlaceholder(1), bindPlaceholder(2), bindPlaceholder(3), bindPlaceholder(4), bindPlaceholder(5))
+		_, err := tx.Exec(query, status.Version, status.Name, status.Checksum, status.AppliedAt.Format(time.RFC3339Nano), status.Duration.Milliseconds())
+		return err
+	})
+	if err != nil {
+		return status, fmt.Errorf("failed to apply migration %d (%s): %w", migration.Version, migration.Name, err)
+	}
+	return status, nil
+}
+
+// runMigrationStep runs a Go step, or else the statements of a SQL step one by one.
+func runMigrationStep(tx *Tx, step func(tx *Tx) error, script string) error {
+	if step != nil {
+		return step(tx)
+	}
+	for _, statement := range splitStatements(script) {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Database) ensureMigrationsTable(ctx context.Context) error {
+	if d == nil || d.DB() == nil {
+		return errors.New("no active database connection")
+	}
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (version BIGINT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL,
+		checksum VARCHAR(64) NOT NULL, applied_at VARCHAR(64) NOT NULL, duration_ms BIGINT NOT NULL)`, MigrationsTable)
+	if _, err := d.DB().ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create %s: %w", MigrationsTable, err)
+	}
+	return nil
+}
+
+// migrationHistory reads the applied migrations keyed by version.
+func (d *Database) migrationHistory(ctx context.Context) (map[int64]MigrationStatus, error) {
+	query := fmt.Sprintf("SELECT version, name, checksum, applied_at, duration_ms FROM %s", MigrationsTable)
+	rows, err := d.DB().QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", MigrationsTable, err)
+	}
+	defer rows.Close()
+
+	history := make(map[int64]MigrationStatus)
+	for rows.Next() {
+		status := MigrationStatus{Applied: true}
+		var appliedAt string
+		var duration int64
+		if err := rows.Scan(&status.Version, &status.Name, &status.Checksum, &appliedAt, &duration); err != nil {
+			return nil, err
+		}
+		status.AppliedAt, _ = time.Parse(time.RFC3339Nano, appliedAt)
+		status.Duration = time.Duration(duration) * time.Millisecond
+		history[status.Version] = status
+	}
+	return history, rows.Err()
+}
+
+// migrationLockKey identifies the advisory lock taken while migrating.
+const migrationLockKey = 7340512983
+
+// withMigrationLock runs fn while holding the migration lock of the database. SQLite needs no
+// lock, its writes are serialized by the database file.
+func (d *Database) withMigrationLock(ctx context.Context, fn func() error) (err error) {
+	if err := d.ensureMigrationsTable(ctx); err != nil {
+		return err
+	}
+	dbType := GetDBType()
+	if dbType != "mysql" && dbType != "postgres" {
+		return fn()
+	}
+
+	// Advisory locks belong to a session, so lock and unlock use the same connection.
+	conn, err := d.DB().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var unlock string
+	if dbType == "mysql" {
+		// GET_LOCK returns 1 once locked and 0 when the timeout passed.
+		var locked sql.NullInt64
+		if err := conn.QueryRowContext(ctx, fmt.Sprintf("SELECT GET_LOCK('%s', 600)", MigrationsTable)).Scan(&locked); err != nil {
+			return fmt.Errorf("failed to lock %s: %w", MigrationsTable, err)
+		}
+		if locked.Int64 != 1 {
+			return fmt.Errorf("timed out waiting for the lock on %s", MigrationsTable)
+		}
+		unlock = fmt.Sprintf("SELECT RELEASE_LOCK('%s')", MigrationsTable)
+	} else {
+		// pg_advisory_lock waits until it is locked and returns void.
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("SELECT pg_advisory_lock(%d)", migrationLockKey)); err != nil {
+			return fmt.Errorf("failed to lock %s: %w", MigrationsTable, err)
+		}
+		unlock = fmt.Sprintf("SELECT pg_advisory_unlock(%d)", migrationLockKey)
+	}
+	defer func() {
+		if _, unlockErr := conn.ExecContext(context.Background(), unlock); unlockErr != nil {
+			// The session keeps the lock, closing the connection ends it instead of pooling it.
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+			err = errors.Join(err, fmt.Errorf("failed to unlock %s: %w", MigrationsTable, unlockErr))
+		}
+	}()
+	return fn()
+}
+
+func bindPlaceholder(index int) string {
+	if GetDBType() == "postgres" {
+		return fmt.Sprintf("$%d", index)
+	}
+	return "?"
+}
+
+// splitStatements splits a SQL script on the semicolons that end its statements. Semicolons in
+// quotes, comments and PostgreSQL dollar quoted bodies are kept.
+func splitStatements(script string) []string {
+	var statements []string
+	var sb strings.Builder
+	flush := func() {
+		if statement := strings.TrimSpace(sb.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		sb.Reset()
+	}
+	for i := 0; i < len(script); {
+		rest := script[i:]
+		switch {
+		case rest[0] == '\'' || rest[0] == '"' || rest[0] == '`':
+			end := spanEnd(rest, 1, rest[:1])
+			sb.WriteString(rest[:end])
+			i += end
+		case dollarTag(rest) != "":
+			tag := dollarTag(rest)
+			end := spanEnd(rest, len(tag), tag)
+			sb.WriteString(rest[:end])
+			i += end
+		case strings.HasPrefix(rest, "--"):
+			// A comment separates tokens like whitespace, the line break is kept.
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			i += end
+		case strings.HasPrefix(rest, "/*"):
+			sb.WriteByte(' ')
+			i += spanEnd(rest, 2, "*/")
+		case rest[0] == ';':
+			flush()
+			i++
+		default:
+			sb.WriteByte(rest[0])
+			i++
+		}
+	}
+	flush()
+	return statements
+}
+
+// dollarTag returns the opening $$ or $tag$ of a PostgreSQL dollar quoted string at the start of
+// text, empty when there is none. A tag starts like an identifier, so $1 placeholders are not tags.
+func dollarTag(text string) string {
+	if len(text) < 2 || text[0] != '$' {
+		return ""
+	}
+	for i := 1; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '$':
+			return text[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// spanEnd returns the index just past the first terminator in text after from, or the length
+// of text when it is not terminated.
+func spanEnd(text string, from int, terminator string) int {
+	if end := strings.Index(text[from:], terminator); end >= 0 {
+		return from + end + len(terminator)
+	}
+	return len(text)
+}
@@ -0,0 +1,88 @@
+package configs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "statements",
+			script: "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);",
+			want:   []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"},
+		},
+		{
+			name:   "no trailing semicolon and empty statements",
+			script: ";; SELECT 1;\n\n;SELECT 2",
+			want:   []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:   "quotes",
+			script: `INSERT INTO a VALUES ('x;y', "c;d", ` + "`e;f`" + `); SELECT 'it''s; fine';`,
+			want:   []string{`INSERT INTO a VALUES ('x;y', "c;d", ` + "`e;f`" + `)`, `SELECT 'it''s; fine'`},
+		},
+		{
+			name:   "line comments",
+			script: "-- first; comment\nSELECT 1; -- trailing; comment\nSELECT a--c;\nFROM b;",
+			want:   []string{"SELECT 1", "SELECT a\nFROM b"},
+		},
+		{
+			name:   "block comments",
+			script: "/* a; b */ SELECT 1;\nSELECT a/*;*/FROM b;",
+			want:   []string{"SELECT 1", "SELECT a FROM b"},
+		},
+		{
+			name:   "dollar quoted body",
+			script: "CREATE FUNCTION f() RETURNS void AS $$ BEGIN PERFORM 1; END; $$ LANGUAGE plpgsql;\nSELECT f();",
+			want:   []string{"CREATE FUNCTION f() RETURNS void AS $$ BEGIN PERFORM 1; END; $$ LANGUAGE plpgsql", "SELECT f()"},
+		},
+		{
+			name:   "tagged dollar quoted body",
+			script: "DO $body$ BEGIN RAISE NOTICE '$$;'; END $body$;\nSELECT $1;",
+			want:   []string{"DO $body$ BEGIN RAISE NOTICE '$$;'; END $body$", "SELECT $1"},
+		},
+		{
+			name:   "unterminated quote",
+			script: "SELECT 'a; b",
+			want:   []string{"SELECT 'a; b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements(%q)\n got %q\nwant %q", tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrationChecksum(t *testing.T) {
+	up := func(tx *Tx) error { return nil }
+	tests := []struct {
+		name string
+		a, b Migration
+		same bool
+	}{
+		{"same SQL", Migration{Version: 1, UpSQL: "CREATE TABLE a (id INT);"}, Migration{Version: 1, UpSQL: "CREATE TABLE a (id INT);"}, true},
+		{"changed SQL", Migration{Version: 1, UpSQL: "CREATE TABLE a (id INT);"}, Migration{Version: 1, UpSQL: "CREATE TABLE a (id BIGINT);"}, false},
+		{"down SQL is not part of it", Migration{Version: 1, UpSQL: "SELECT 1;", DownSQL: "SELECT 2;"}, Migration{Version: 1, UpSQL: "SELECT 1;"}, true},
+		{"functions by version and name", Migration{Version: 2, Name: "seed", Up: up}, Migration{Version: 2, Name: "seed", Up: up}, true},
+		{"renamed function", Migration{Version: 2, Name: "seed", Up: up}, Migration{Version: 2, Name: "seed_users", Up: up}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := tt.a.Checksum(), tt.b.Checksum()
+			if len(a) != 64 {
+				t.Fatalf("checksum %q is not a hex SHA-256", a)
+			}
+			if (a == b) != tt.same {
+				t.Errorf("checksums %s and %s, want same = %v", a, b, tt.same)
+			}
+		})
+	}
+}
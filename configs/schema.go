@@ -0,0 +1,195 @@
+package configs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// ColumnDef is one column of a table schema.
+type ColumnDef struct {
+	Name string `json:"Name"`
+	FieldSchema
+}
+
+// IndexDef is an index or unique constraint over one or more columns. An empty name is
+// filled in by the query generator.
+type IndexDef struct {
+	Name    string   `json:"Name,omitempty"`
+	Columns []string `json:"Columns"`
+	// Type is empty for a plain index, UNIQUE or FULLTEXT.
+	Type string `json:"Type,omitempty"`
+}
+
+// ForeignKeyDef references columns of another table. OnDelete and OnUpdate hold the
+// referential action, e.g. CASCADE or SET NULL, empty means the default NO ACTION.
+type ForeignKeyDef struct {
+	Name              string   `json:"Name,omitempty"`
+	Columns           []string `json:"Columns"`
+	ReferencedTable   string   `json:"ReferencedTable"`
+	ReferencedColumns []string `json:"ReferencedColumns"`
+	OnDelete          string   `json:"OnDelete,omitempty"`
+	OnUpdate          string   `json:"OnUpdate,omitempty"`
+}
+
+// TableSchema describes a table with its columns in table order.
+//
+// A single column primary key or unique constraint can be declared through the Key of the
+// column, PrimaryKey is needed for composite keys and lists the key columns in key order.
+// Uniques are unique constraints of the table, Indexes its secondary indexes and ForeignKeys
+// its relationships to other tables.
+type TableSchema struct {
+	Columns     []ColumnDef     `json:"columns"`
+	PrimaryKey  []string        `json:"primary_key,omitempty"`
+	Uniques     []IndexDef      `json:"uniques,omitempty"`
+	Indexes     []IndexDef      `json:"indexes,omitempty"`
+	ForeignKeys []ForeignKeyDef `json:"foreign_keys,omitempty"`
+}
+
+// NewTableSchema builds a schema from columns given in table order.
+func NewTableSchema(columns ...ColumnDef) TableSchema {
+	return TableSchema{Columns: columns}
+}
+
+// SchemaFromMap converts an unordered column map, columns are sorted by name.
+func SchemaFromMap(schema map[string]FieldSchema) TableSchema {
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	columns := make([]ColumnDef, 0, len(names))
+	for _, name := range names {
+		columns = append(columns, ColumnDef{Name: name, FieldSchema: schema[name]})
+	}
+	return TableSchema{Columns: columns}
+}
+
+// Column returns the definition of a column.
+func (s TableSchema) Column(name string) (FieldSchema, bool) {
+	for _, column := range s.Columns {
+		if column.Name == name {
+			return column.FieldSchema, true
+		}
+	}
+	return FieldSchema{}, false
+}
+
+// HasColumn reports whether the schema has a column of that name.
+func (s TableSchema) HasColumn(name string) bool {
+	_, ok := s.Column(name)
+	return ok
+}
+
+// ColumnNames returns the column names in table order.
+func (s TableSchema) ColumnNames() []string {
+	names := make([]string, 0, len(s.Columns))
+	for _, column := range s.Columns {
+		names = append(names, column.Name)
+	}
+	return names
+}
+
+// SetColumn replaces the definition of a column, or appends it when the column is new.
+func (s *TableSchema) SetColumn(name string, fieldSchema FieldSchema) {
+	for i, column := range s.Columns {
+		if column.Name == name {
+			s.Columns[i].FieldSchema = fieldSchema
+			return
+		}
+	}
+	s.Columns = append(s.Columns, ColumnDef{Name: name, FieldSchema: fieldSchema})
+}
+
+// PrimaryKeyColumns returns the primary key columns, PrimaryKey when it is set and otherwise
+// the columns with Key PRI in table order.
+func (s TableSchema) PrimaryKeyColumns() []string {
+	if len(s.PrimaryKey) > 0 {
+		return s.PrimaryKey
+	}
+	var keys []string
+	for _, column := range s.Columns {
+		if column.Key == "PRI" {
+			keys = append(keys, column.Name)
+		}
+	}
+	return keys
+}
+
+// Copy returns a schema that can be changed without touching s.
+func (s TableSchema) Copy() TableSchema {
+	return TableSchema{
+		Columns:     append([]ColumnDef{}, s.Columns...),
+		PrimaryKey:  append([]string(nil), s.PrimaryKey...),
+		Uniques:     copyIndexes(s.Uniques),
+		Indexes:     copyIndexes(s.Indexes),
+		ForeignKeys: copyForeignKeys(s.ForeignKeys),
+	}
+}
+
+func copyForeignKeys(foreignKeys []ForeignKeyDef) []ForeignKeyDef {
+	if foreignKeys == nil {
+		return nil
+	}
+	copied := make([]ForeignKeyDef, len(foreignKeys))
+	for i, foreignKey := range foreignKeys {
+		copied[i] = foreignKey
+		copied[i].Columns = append([]string{}, foreignKey.Columns...)
+		copied[i].ReferencedColumns = append([]string{}, foreignKey.ReferencedColumns...)
+	}
+	return copied
+}
+
+func copyIndexes(indexes []IndexDef) []IndexDef {
+	if indexes == nil {
+		return nil
+	}
+	copied := make([]IndexDef, len(indexes))
+	for i, index := range indexes {
+		copied[i] = index
+		copied[i].Columns = append([]string{}, index.Columns...)
+	}
+	return copied
+}
+
+// UnmarshalJSON also reads the earlier format, an object keyed by column name, keeping the
+// columns in the order they appear in the file.
+func (s *TableSchema) UnmarshalJSON(data []byte) error {
+	type plain TableSchema
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return err
+	}
+	if raw, ok := probe["columns"]; ok && bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		var decoded plain
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return err
+		}
+		*s = TableSchema(decoded)
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	columns := []ColumnDef{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		name, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("unexpected schema key %v", token)
+		}
+		var fieldSchema FieldSchema
+		if err := decoder.Decode(&fieldSchema); err != nil {
+			return fmt.Errorf("column %s: %w", name, err)
+		}
+		columns = append(columns, ColumnDef{Name: name, FieldSchema: fieldSchema})
+	}
+	s.Columns = columns
+	return nil
+}
@@ -0,0 +1,67 @@
+package configs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTableSchemaJSON(t *testing.T) {
+	schema := NewTableSchema(
+		column("zeta", "int", "NO", "PRI"),
+		withDefault(column("alpha", "varchar(20)", "YES", ""), "'x'"),
+		column("mid", "text", "YES", ""),
+	)
+	schema.Indexes = []IndexDef{{Name: "idx_mid", Columns: []string{"mid"}}}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded TableSchema
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, schema) {
+		t.Errorf("round trip = %+v\nwant %+v", decoded, schema)
+	}
+
+	// The earlier format keyed the columns by name, the order of the file is kept.
+	legacy := `{"zeta": {"Type": "int", "Null": "NO", "Key": "PRI"}, "alpha": {"Type": "varchar(20)", "Null": "YES", "Default": "'x'"}, "mid": {"Type": "text", "Null": "YES"}}`
+	decoded = TableSchema{}
+	if err := json.Unmarshal([]byte(legacy), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.ColumnNames(); !reflect.DeepEqual(got, []string{"zeta", "alpha", "mid"}) {
+		t.Errorf("legacy columns = %v", got)
+	}
+	if alpha, _ := decoded.Column("alpha"); alpha.Default == nil || *alpha.Default != "'x'" {
+		t.Errorf("alpha = %+v", alpha)
+	}
+	if err := json.Unmarshal([]byte(`{"zeta": 1}`), &decoded); err == nil {
+		t.Error("a legacy column that is not an object was accepted")
+	}
+}
+
+func TestTableSchemaColumns(t *testing.T) {
+	schema := SchemaFromMap(map[string]FieldSchema{"b": {Type: "int"}, "a": {Type: "int"}, "c": {Type: "int", Key: "PRI"}})
+	if got := schema.ColumnNames(); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("SchemaFromMap columns = %v", got)
+	}
+
+	copied := schema.Copy()
+	copied.SetColumn("b", FieldSchema{Type: "text"})
+	copied.SetColumn("d", FieldSchema{Type: "text"})
+	if got := copied.ColumnNames(); !reflect.DeepEqual(got, []string{"a", "b", "c", "d"}) {
+		t.Errorf("columns after SetColumn = %v", got)
+	}
+	if b, _ := schema.Column("b"); b.Type != "int" || schema.HasColumn("d") {
+		t.Error("changing a copy changed the original schema")
+	}
+	if got := schema.PrimaryKeyColumns(); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("primary key = %v", got)
+	}
+	schema.PrimaryKey = []string{"c", "a"}
+	if got := schema.PrimaryKeyColumns(); !reflect.DeepEqual(got, []string{"c", "a"}) {
+		t.Errorf("composite primary key = %v", got)
+	}
+}
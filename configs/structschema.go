@@ -0,0 +1,360 @@
+package configs
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// TagName is the struct tag that maps a field onto a column.
+//
+//	Email string `sqldoc:"email,type=varchar(255),unique,notnull"`
+//
+// The first part is the column name, "-" skips the field and an empty name falls back to the
+// snake_case of the field name. The options describe the column for SchemaFromStruct:
+// type=<sql type>, null, notnull, unique, primary (or pk), auto_increment, extra=<text>,
+// default=<DDL default>, comment=<text>, collation=<name>, charset=<name>, unsigned,
+// on_update=<expression>, generated=<expression> with stored or virtual.
+// Index options: unique=<name>, index, index=<name>, fulltext and fulltext=<name>; fields
+// sharing a name form a composite constraint or index. Several primary fields form a
+// composite primary key in field order.
+// A foreign key is declared with references=<table>(<column>), optionally followed by
+// on delete <action> and on update <action>, e.g. references=users(id) on delete cascade.
+const TagName = "sqldoc"
+
+// ParseTag splits a sqldoc tag into the column name and its options. Commas inside
+// parentheses or quotes belong to the option, so type=decimal(10,2) stays in one piece.
+// Flag options are stored with an empty value.
+func ParseTag(tag string) (string, map[string]string) {
+	parts := splitTag(tag)
+	options := make(map[string]string)
+	for _, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		options[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(parts[0]), options
+}
+
+func splitTag(tag string) []string {
+	var parts []string
+	depth := 0
+	var quote rune
+	start := 0
+	for i, r := range tag {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, tag[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tag[start:])
+}
+
+// SnakeCase turns a Go field name into a column name, UserID becomes user_id.
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// SchemaFromStruct derives a table schema from the sqldoc tags of a struct, so the schema
+// passed to CreateTable cannot drift from the model. model is a struct or a pointer to one,
+// the columns follow the field order.
+// Without a type option the column type is inferred from the Go type; pointer and sql.Null*
+// fields are nullable unless tagged notnull, other fields are NOT NULL unless tagged null.
+func SchemaFromStruct(model interface{}) (TableSchema, error) {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return TableSchema{}, fmt.Errorf("schema model must be a struct, got %T", model)
+	}
+	var schema TableSchema
+	if err := collectSchema(t, &schema); err != nil {
+		return TableSchema{}, err
+	}
+	if len(schema.Columns) == 0 {
+		return TableSchema{}, fmt.Errorf("struct %s has no columns", t)
+	}
+	if keys := schema.PrimaryKeyColumns(); len(keys) > 1 {
+		schema.PrimaryKey = keys
+	}
+	return schema, nil
+}
+
+func collectSchema(t reflect.Type, schema *TableSchema) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options := ParseTag(field.Tag.Get(TagName))
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct && embedded != reflect.TypeOf(time.Time{}) {
+				if err := collectSchema(embedded, schema); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = SnakeCase(field.Name)
+		}
+		if schema.HasColumn(name) {
+			return fmt.Errorf("column %s is mapped by more than one field", name)
+		}
+
+		fieldSchema, err := fieldSchemaFromStruct(field, options)
+		if err != nil {
+			return err
+		}
+		schema.Columns = append(schema.Columns, ColumnDef{Name: name, FieldSchema: fieldSchema})
+		addIndexOptions(schema, name, options)
+		if value := options["references"]; value != "" {
+			foreignKey, err := parseReferences(name, value)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			schema.ForeignKeys = append(schema.ForeignKeys, foreignKey)
+		}
+	}
+	return nil
+}
+
+// addIndexOptions adds a column to the unique constraints and indexes named in its tag.
+// Fields naming the same constraint or index form one composite constraint or index.
+func addIndexOptions(schema *TableSchema, column string, options map[string]string) {
+	if name := options["unique"]; name != "" {
+		schema.Uniques = appendIndexColumn(schema.Uniques, IndexDef{Name: name}, column)
+	}
+	if name, ok := options["index"]; ok {
+		schema.Indexes = appendIndexColumn(schema.Indexes, IndexDef{Name: name}, column)
+	}
+	if name, ok := options["fulltext"]; ok {
+		schema.Indexes = appendIndexColumn(schema.Indexes, IndexDef{Name: name, Type: "FULLTEXT"}, column)
+	}
+}
+
+var referencesOption = regexp.MustCompile(`(?i)^([A-Za-z0-9_.]+)\s*\(\s*([A-Za-z0-9_]+)\s*\)((?:\s+on\s+(?:delete|update)\s+(?:cascade|restrict|set\s+null|set\s+default|no\s+action))*)$`)
+var referentialActionOption = regexp.MustCompile(`(?i)on\s+(delete|update)\s+(cascade|restrict|set\s+null|set\s+default|no\s+action)`)
+
+// parseReferences turns a references tag option into a foreign key on column.
+func parseReferences(column string, value string) (ForeignKeyDef, error) {
+	match := referencesOption.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return ForeignKeyDef{}, fmt.Errorf("invalid references option %q, expected table(column)", value)
+	}
+	foreignKey := ForeignKeyDef{Columns: []string{column}, ReferencedTable: match[1], ReferencedColumns: []string{match[2]}}
+	for _, action := range referentialActionOption.FindAllStringSubmatch(match[3], -1) {
+		rule := strings.ToUpper(strings.Join(strings.Fields(action[2]), " "))
+		if strings.EqualFold(action[1], "delete") {
+			foreignKey.OnDelete = rule
+		} else {
+			foreignKey.OnUpdate = rule
+		}
+	}
+	return foreignKey, nil
+}
+
+func appendIndexColumn(indexes []IndexDef, index IndexDef, column string) []IndexDef {
+	if index.Name != "" {
+		for i := range indexes {
+			if indexes[i].Name == index.Name {
+				indexes[i].Columns = append(indexes[i].Columns, column)
+				return indexes
+			}
+		}
+	}
+	index.Columns = []string{column}
+	return append(indexes, index)
+}
+
+func fieldSchemaFromStruct(field reflect.StructField, options map[string]string) (FieldSchema, error) {
+	columnType, nullable := inferColumnType(field.Type)
+	unsigned := isUnsigned(field.Type)
+	if value, ok := options["type"]; ok && value != "" {
+		columnType = value
+		unsigned = false
+	}
+	if columnType == "" {
+		return FieldSchema{}, fmt.Errorf("cannot infer a column type for field %s of type %s, add a type= option", field.Name, field.Type)
+	}
+
+	fieldSchema := FieldSchema{Type: columnType, Null: "NO"}
+	if nullable {
+		fieldSchema.Null = "YES"
+	}
+	if _, ok := options["null"]; ok {
+		fieldSchema.Null = "YES"
+	}
+	if _, ok := options["notnull"]; ok {
+		fieldSchema.Null = "NO"
+	}
+	if value, ok := options["unique"]; ok && value == "" {
+		fieldSchema.Key = "UNI"
+	}
+	_, primary := options["primary"]
+	_, pk := options["pk"]
+	if primary || pk {
+		fieldSchema.Key = "PRI"
+		fieldSchema.Null = "NO"
+	}
+	_, autoIncrement := options["auto_increment"]
+	_, autoincrement := options["autoincrement"]
+	if autoIncrement || autoincrement {
+		fieldSchema.Extra = "auto_increment"
+	}
+	if value, ok := options["extra"]; ok && value != "" {
+		fieldSchema.Extra = strings.TrimSpace(fieldSchema.Extra + " " + value)
+	}
+
+	if value, ok := options["default"]; ok {
+		fieldSchema.Default = &value
+	}
+	fieldSchema.Comment = unquote(options["comment"])
+	fieldSchema.Collation = options["collation"]
+	fieldSchema.Charset = options["charset"]
+	if _, ok := options["unsigned"]; ok || unsigned {
+		fieldSchema.Unsigned = true
+	}
+	fieldSchema.OnUpdate = options["on_update"]
+	if value := options["generated"]; value != "" {
+		fieldSchema.Generated = strings.TrimSpace(value)
+		fieldSchema.GeneratedType = "VIRTUAL"
+		if _, ok := options["stored"]; ok {
+			fieldSchema.GeneratedType = "STORED"
+		}
+	}
+	return fieldSchema, nil
+}
+
+var (
+	nullStringType  = reflect.TypeOf(sql.NullString{})
+	nullInt64Type   = reflect.TypeOf(sql.NullInt64{})
+	nullInt32Type   = reflect.TypeOf(sql.NullInt32{})
+	nullInt16Type   = reflect.TypeOf(sql.NullInt16{})
+	nullByteType    = reflect.TypeOf(sql.NullByte{})
+	nullFloat64Type = reflect.TypeOf(sql.NullFloat64{})
+	nullBoolType    = reflect.TypeOf(sql.NullBool{})
+	nullTimeType    = reflect.TypeOf(sql.NullTime{})
+	timeType        = reflect.TypeOf(time.Time{})
+)
+
+// inferColumnType picks a column type for a Go type and reports whether it can hold NULL.
+// An empty type means there is no sensible default.
+func inferColumnType(t reflect.Type) (string, bool) {
+	nullable := false
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	switch t {
+	case nullStringType:
+		return "varchar(255)", true
+	case nullInt64Type:
+		return "bigint", true
+	case nullInt32Type:
+		return "int", true
+	case nullInt16Type:
+		return "smallint", true
+	case nullByteType:
+		return "tinyint", true
+	case nullFloat64Type:
+		return "double", true
+	case nullBoolType:
+		return "tinyint(1)", true
+	case nullTimeType:
+		return "datetime", true
+	case timeType:
+		return "datetime", nullable
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "tinyint(1)", nullable
+	case reflect.Int8:
+		return "tinyint", nullable
+	case reflect.Int16:
+		return "smallint", nullable
+	case reflect.Int32, reflect.Int:
+		return "int", nullable
+	case reflect.Int64:
+		return "bigint", nullable
+	case reflect.Uint8:
+		return "tinyint", nullable
+	case reflect.Uint16:
+		return "smallint", nullable
+	case reflect.Uint32, reflect.Uint:
+		return "int", nullable
+	case reflect.Uint64:
+		return "bigint", nullable
+	case reflect.Float32:
+		return "float", nullable
+	case reflect.Float64:
+		return "double", nullable
+	case reflect.String:
+		return "varchar(255)", nullable
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "blob", true
+		}
+	}
+	return "", nullable
+}
+
+// isUnsigned reports whether a field holds an unsigned integer, behind a pointer or not.
+func isUnsigned(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// unquote removes the quotes around a tag value such as comment='Login name'.
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,145 @@
+package configs
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type audited struct {
+	CreatedAt time.Time `sqldoc:"created_at,default=CURRENT_TIMESTAMP"`
+	UpdatedAt *time.Time
+}
+
+type account struct {
+	ID       uint64         `sqldoc:"id,primary,auto_increment"`
+	Email    string         `sqldoc:"email,type=varchar(255),unique,notnull,comment='Login name'"`
+	Price    float64        `sqldoc:"price,type=decimal(10,2),default=0"`
+	Nickname *string        `sqldoc:",notnull"`
+	Bio      sql.NullString `sqldoc:"bio"`
+	Active   bool           `sqldoc:"active,null"`
+	TenantID int64          `sqldoc:"tenant_id,unique=uq_tenant_slug,references=tenants(id) on delete cascade on update set null"`
+	Slug     string         `sqldoc:"slug,unique=uq_tenant_slug,index=idx_slug_kind,collation=utf8mb4_bin"`
+	Kind     string         `sqldoc:"kind,type=enum('a','b'),index=idx_slug_kind"`
+	Body     string         `sqldoc:"body,type=text,fulltext"`
+	Total    float64        `sqldoc:"total,type=decimal(12,2),generated=price * 2,stored"`
+	Skipped  string         `sqldoc:"-"`
+	internal string
+	audited
+}
+
+func TestSchemaFromStruct(t *testing.T) {
+	schema, err := SchemaFromStruct(&account{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero, now := "0", "CURRENT_TIMESTAMP"
+	want := TableSchema{
+		Columns: []ColumnDef{
+			{Name: "id", FieldSchema: FieldSchema{Type: "bigint", Null: "NO", Key: "PRI", Extra: "auto_increment", Unsigned: true}},
+			{Name: "email", FieldSchema: FieldSchema{Type: "varchar(255)", Null: "NO", Key: "UNI", Comment: "Login name"}},
+			{Name: "price", FieldSchema: FieldSchema{Type: "decimal(10,2)", Null: "NO", Default: &zero}},
+			{Name: "nickname", FieldSchema: FieldSchema{Type: "varchar(255)", Null: "NO"}},
+			{Name: "bio", FieldSchema: FieldSchema{Type: "varchar(255)", Null: "YES"}},
+			{Name: "active", FieldSchema: FieldSchema{Type: "tinyint(1)", Null: "YES"}},
+			{Name: "tenant_id", FieldSchema: FieldSchema{Type: "bigint", Null: "NO"}},
+			{Name: "slug", FieldSchema: FieldSchema{Type: "varchar(255)", Null: "NO", Collation: "utf8mb4_bin"}},
+			{Name: "kind", FieldSchema: FieldSchema{Type: "enum('a','b')", Null: "NO"}},
+			{Name: "body", FieldSchema: FieldSchema{Type: "text", Null: "NO"}},
+			{Name: "total", FieldSchema: FieldSchema{Type: "decimal(12,2)", Null: "NO", Generated: "price * 2", GeneratedType: "STORED"}},
+			{Name: "created_at", FieldSchema: FieldSchema{Type: "datetime", Null: "NO", Default: &now}},
+			{Name: "updated_at", FieldSchema: FieldSchema{Type: "datetime", Null: "YES"}},
+		},
+		Uniques: []IndexDef{{Name: "uq_tenant_slug", Columns: []string{"tenant_id", "slug"}}},
+		Indexes: []IndexDef{
+			{Name: "idx_slug_kind", Columns: []string{"slug", "kind"}},
+			{Type: "FULLTEXT", Columns: []string{"body"}},
+		},
+		ForeignKeys: []ForeignKeyDef{
+			{Columns: []string{"tenant_id"}, ReferencedTable: "tenants", ReferencedColumns: []string{"id"}, OnDelete: "CASCADE", OnUpdate: "SET NULL"},
+		},
+	}
+	if !reflect.DeepEqual(schema, want) {
+		for i := range want.Columns {
+			if i < len(schema.Columns) && !reflect.DeepEqual(schema.Columns[i], want.Columns[i]) {
+				t.Errorf("column %d = %+v, want %+v", i, schema.Columns[i], want.Columns[i])
+			}
+		}
+		t.Errorf("schema = %+v\nwant %+v", schema, want)
+	}
+}
+
+func TestSchemaFromStructPrimaryKey(t *testing.T) {
+	type membership struct {
+		UserID  int64 `sqldoc:"user_id,primary"`
+		GroupID int64 `sqldoc:"group_id,pk"`
+	}
+	schema, err := SchemaFromStruct(membership{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(schema.PrimaryKey, []string{"user_id", "group_id"}) {
+		t.Errorf("primary key = %v", schema.PrimaryKey)
+	}
+
+	type single struct {
+		ID int `sqldoc:"id,primary"`
+	}
+	if schema, err := SchemaFromStruct(single{}); err != nil || schema.PrimaryKey != nil || schema.Columns[0].Key != "PRI" {
+		t.Errorf("single key schema = %+v, %v", schema, err)
+	}
+}
+
+func TestSchemaFromStructErrors(t *testing.T) {
+	type duplicate struct {
+		A string `sqldoc:"name"`
+		B string `sqldoc:"name"`
+	}
+	type uninferable struct {
+		Tags map[string]string
+	}
+	type badReference struct {
+		UserID int64 `sqldoc:"user_id,references=users"`
+	}
+	type empty struct {
+		Skipped string `sqldoc:"-"`
+	}
+	tests := []struct {
+		model interface{}
+		want  string
+	}{
+		{42, "must be a struct"},
+		{(*account)(nil), ""},
+		{duplicate{}, "mapped by more than one field"},
+		{uninferable{}, "add a type= option"},
+		{badReference{}, "invalid references option"},
+		{empty{}, "has no columns"},
+	}
+	for _, tt := range tests {
+		_, err := SchemaFromStruct(tt.model)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("SchemaFromStruct(%T) = %v", tt.model, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("SchemaFromStruct(%T) = %v, want %q", tt.model, err, tt.want)
+		}
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	name, options := ParseTag("price, type=decimal(10,2), default='a,b', notnull")
+	want := map[string]string{"type": "decimal(10,2)", "default": "'a,b'", "notnull": ""}
+	if name != "price" || !reflect.DeepEqual(options, want) {
+		t.Errorf("ParseTag = %q, %v", name, options)
+	}
+	for field, column := range map[string]string{"UserID": "user_id", "ID": "id", "HTTPServer": "http_server", "createdAt": "created_at"} {
+		if got := SnakeCase(field); got != column {
+			t.Errorf("SnakeCase(%s) = %s, want %s", field, got, column)
+		}
+	}
+}
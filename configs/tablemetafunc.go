@@ -1,25 +1,239 @@
 package configs
 
 import (
+	"bytes"
 	"encoding/json"
-	"io/ioutil"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"sync"
 )
 
+// DefaultMetaFile is the metadata file used when a Database has no store.
+const DefaultMetaFile = "activetables.json"
+
+// LegacyNamespace holds the tables of files written before metadata was namespaced.
+const LegacyNamespace = ""
+
+// NamespaceKey names the namespace of a database in a metadata file, e.g.
+// mysql://127.0.0.1:3306/gotest1.
+func NamespaceKey(dialect string, host string, database string) string {
+	return dialect + "://" + host + "/" + database
+}
+
+// MetaFile is a JSON metadata file. Every database keeps its tables in its own namespace of the
+// file, so one file can serve all the connections of a process.
+type MetaFile struct {
+	mu         sync.Mutex
+	path       string
+	namespaces map[string]map[string]MetaTableDetails
+	views      map[string]*MetaTableList
+}
+
+var metaFiles = struct {
+	mu    sync.Mutex
+	files map[string]*MetaFile
+}{files: make(map[string]*MetaFile)}
+
+// GetMetaTableInstance returns LegacyNamespace of DefaultMetaFile and panics when the file
+// cannot be loaded. Use DefaultMetaStore or OpenMetaFile to get the error instead.
 func GetMetaTableInstance() *MetaTableList {
+	file, err := defaultMetaFile()
+	if err != nil {
+		panic(err)
+	}
+	return file.Namespace(LegacyNamespace)
+}
+
+// DefaultMetaStore returns LegacyNamespace of DefaultMetaFile. When the file cannot be loaded
+// the store knows no table and every change fails with the error of loading it.
+func DefaultMetaStore() MetaStore {
+	return defaultNamespace(LegacyNamespace)
+}
+
+// defaultNamespace returns a namespace of DefaultMetaFile, or a store failing with the error of
+// loading the file.
+func defaultNamespace(namespace string) MetaStore {
+	file, err := defaultMetaFile()
+	if err != nil {
+		return unavailableMetaStore{err: err}
+	}
+	return file.Namespace(namespace)
+}
+
+func defaultMetaFile() (*MetaFile, error) {
 	once.Do(func() {
-		instance = &MetaTableList{
-			ExistingTables: make(map[string]MetaTableDetails),
+		defaultFile, defaultFileErr = OpenMetaFile(DefaultMetaFile)
+		if defaultFileErr != nil {
+			defaultFileErr = fmt.Errorf("failed to load the metadata from %s: %w", DefaultMetaFile, defaultFileErr)
 		}
-		if err := loadActiveMetaTables(instance); err != nil {
-			panic(err)
+	})
+	return defaultFile, defaultFileErr
+}
+
+// OpenMetaFile loads the metadata file at path, creating it when it does not exist. Opening the
+// same path again returns the same MetaFile.
+func OpenMetaFile(path string) (*MetaFile, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	metaFiles.mu.Lock()
+	defer metaFiles.mu.Unlock()
+	if file, ok := metaFiles.files[absPath]; ok {
+		return file, nil
+	}
+	file := &MetaFile{path: absPath, views: make(map[string]*MetaTableList)}
+	if err := loadActiveMetaTables(file); err != nil {
+		return nil, err
+	}
+	metaFiles.files[absPath] = file
+	return file, nil
+}
+
+// NewMemoryMetaStore returns a store that is never written anywhere, e.g. for tests.
+func NewMemoryMetaStore() *MetaTableList {
+	return &MetaTableList{ExistingTables: make(map[string]MetaTableDetails)}
+}
+
+// Path returns the absolute path of the file.
+func (f *MetaFile) Path() string {
+	return f.path
+}
+
+// Namespace returns the store of a namespace. A namespace nobody wrote to is empty and only
+// added to the file by its first update.
+func (f *MetaFile) Namespace(name string) *MetaTableList {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if view, ok := f.views[name]; ok {
+		return view
+	}
+	view := &MetaTableList{file: f, namespace: name}
+	view.ExistingTables = f.tables(name)
+	f.views[name] = view
+	return view
+}
+
+// Namespaces lists the namespaces of the file, sorted.
+func (f *MetaFile) Namespaces() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	names := make([]string, 0, len(f.namespaces))
+	for name := range f.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RemoveNamespace drops a namespace and all its tables from the file.
+func (f *MetaFile) RemoveNamespace(name string) error {
+	return f.modify(func(namespaces map[string]map[string]MetaTableDetails) error {
+		delete(namespaces, name)
+		return nil
+	})
+}
+
+// RenameNamespace moves the tables of a namespace to another one, e.g. the tables of
+// LegacyNamespace to the namespace of a database. The target must not exist yet.
+func (f *MetaFile) RenameNamespace(from string, to string) error {
+	return f.modify(func(namespaces map[string]map[string]MetaTableDetails) error {
+		tables, ok := namespaces[from]
+		if !ok {
+			return fmt.Errorf("metadata namespace %q does not exist", from)
+		}
+		if _, ok := namespaces[to]; ok {
+			return fmt.Errorf("metadata namespace %q already exists", to)
 		}
+		delete(namespaces, from)
+		namespaces[to] = tables
+		return nil
 	})
-	return instance
 }
+
+// AdoptLegacyNamespace moves the tables of LegacyNamespace to the namespace to when to does not
+// exist yet, e.g. the first time a database opens a file written before metadata was
+// namespaced. It reports whether tables were moved.
+func (f *MetaFile) AdoptLegacyNamespace(to string) (bool, error) {
+	f.mu.Lock()
+	_, hasLegacy := f.namespaces[LegacyNamespace]
+	_, hasTarget := f.namespaces[to]
+	f.mu.Unlock()
+	if to == LegacyNamespace || !hasLegacy || hasTarget {
+		return false, nil
+	}
+
+	moved := false
+	err := f.modify(func(namespaces map[string]map[string]MetaTableDetails) error {
+		// Another process may have moved the tables since the file was loaded.
+		tables, ok := namespaces[LegacyNamespace]
+		if _, exists := namespaces[to]; !ok || exists {
+			return nil
+		}
+		delete(namespaces, LegacyNamespace)
+		namespaces[to] = tables
+		moved = true
+		return nil
+	})
+	return moved, err
+}
+
+// tables returns the tables of a namespace, callers hold f.mu.
+func (f *MetaFile) tables(name string) map[string]MetaTableDetails {
+	if tables, ok := f.namespaces[name]; ok {
+		return tables
+	}
+	return make(map[string]MetaTableDetails)
+}
+
+// modify applies change to the namespaces and writes them to the file. The file is locked and
+// read again first, so changes made by other processes are kept; when change or writing fails
+// the file and the stores are left as they were.
+func (f *MetaFile) modify(change func(namespaces map[string]map[string]MetaTableDetails) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	unlock, err := lockMetaFile(f.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	namespaces, err := readMetaFile(f.path)
+	if err != nil {
+		return err
+	}
+	if err := change(namespaces); err != nil {
+		return err
+	}
+	if err := writeMetaFile(f.path, namespaces); err != nil {
+		return err
+	}
+	f.namespaces = namespaces
+	for name, view := range f.views {
+		view.ExistingTables = f.tables(name)
+	}
+	return nil
+}
+
+// Namespace returns the namespace of the store in its file, empty for an in-memory store.
+func (tl *MetaTableList) Namespace() string {
+	return tl.namespace
+}
+
+// lock guards ExistingTables, which the file replaces for all its stores on every write.
+func (tl *MetaTableList) lock() func() {
+	if tl.file != nil {
+		tl.file.mu.Lock()
+		return tl.file.mu.Unlock
+	}
+	tl.mu.Lock()
+	return tl.mu.Unlock
+}
+
 func (t *MetaTableList) FindMetaTable(name string) *MetaTableDetails {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+	defer t.lock()()
 
 	if details, exists := t.ExistingTables[name]; exists {
 		return &details
@@ -27,52 +241,174 @@ func (t *MetaTableList) FindMetaTable(name string) *MetaTableDetails {
 	return nil
 }
 
-func (tl *MetaTableList) UpdateMetaTable(tableName string, details MetaTableDetails) {
-	tl.mu.Lock()
-	defer tl.mu.Unlock()
-	tl.ExistingTables[tableName] = details
-	saveActiveMetaTables(tl, "activetables.json")
+// MetaTables returns a copy of the stored tables.
+func (tl *MetaTableList) MetaTables() map[string]MetaTableDetails {
+	defer tl.lock()()
+	tables := make(map[string]MetaTableDetails, len(tl.ExistingTables))
+	for name, details := range tl.ExistingTables {
+		tables[name] = details
+	}
+	return tables
 }
 
-func (tl *MetaTableList) RemoveMetaTable(tableName string) {
-	tl.mu.Lock()
-	defer tl.mu.Unlock()
-	delete(tl.ExistingTables, tableName)
-	saveActiveMetaTables(tl, "activetables.json")
+func (tl *MetaTableList) UpdateMetaTable(tableName string, details MetaTableDetails) error {
+	return tl.modify(func(tables map[string]MetaTableDetails) {
+		tables[tableName] = details
+	})
 }
 
-func loadActiveMetaTables(tl *MetaTableList) error {
-	fileName := "activetables.json"
+func (tl *MetaTableList) RemoveMetaTable(tableName string) error {
+	return tl.modify(func(tables map[string]MetaTableDetails) {
+		delete(tables, tableName)
+	})
+}
 
-	if _, err := os.Stat(fileName); os.IsNotExist(err) {
-		file, err := os.Create(fileName)
-		if err != nil {
-			return err
+// modify applies change to the tables of the store, through its file when it has one.
+func (tl *MetaTableList) modify(change func(tables map[string]MetaTableDetails)) error {
+	if tl.file == nil {
+		defer tl.lock()()
+		change(tl.ExistingTables)
+		return nil
+	}
+	return tl.file.modify(func(namespaces map[string]map[string]MetaTableDetails) error {
+		tables, ok := namespaces[tl.namespace]
+		if !ok {
+			tables = make(map[string]MetaTableDetails)
+			namespaces[tl.namespace] = tables
 		}
-		defer file.Close()
+		change(tables)
+		return nil
+	})
+}
 
-		tl.ExistingTables = make(map[string]MetaTableDetails)
-		return saveActiveMetaTables(tl, fileName)
+func loadActiveMetaTables(f *MetaFile) error {
+	unlock, err := lockMetaFile(f.path)
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	file, err := os.Open(fileName)
+	if _, err := os.Stat(f.path); os.IsNotExist(err) {
+		f.namespaces = make(map[string]map[string]MetaTableDetails)
+		return writeMetaFile(f.path, f.namespaces)
+	}
+	namespaces, err := readMetaFile(f.path)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	f.namespaces = namespaces
+	return nil
+}
+
+// MetaFileVersion is the format version written to metadata files. Version 0 files hold the
+// bare table map, version 1 wraps the tables in a versioned document and version 2 keeps them
+// per namespace.
+const MetaFileVersion = 2
 
-	byteValue, err := ioutil.ReadAll(file)
+type metaFile struct {
+	Version    int                                    `json:"version"`
+	Tables     map[string]MetaTableDetails            `json:"tables,omitempty"`
+	Namespaces map[string]map[string]MetaTableDetails `json:"namespaces"`
+}
+
+// readMetaFile reads the namespaces of a metadata file, an empty file holds none.
+func readMetaFile(fileName string) (map[string]map[string]MetaTableDetails, error) {
+	data, err := os.ReadFile(fileName)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	namespaces, err := decodeMetaFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", fileName, err)
+	}
+	return namespaces, nil
+}
 
-	return json.Unmarshal(byteValue, &tl.ExistingTables)
+// decodeMetaFile decodes every known version of the format. The tables of versions before 2
+// belong to LegacyNamespace.
+func decodeMetaFile(data []byte) (map[string]map[string]MetaTableDetails, error) {
+	namespaces := make(map[string]map[string]MetaTableDetails)
+	if len(bytes.TrimSpace(data)) == 0 {
+		return namespaces, nil
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+	var version int
+	if raw, ok := probe["version"]; !ok || json.Unmarshal(raw, &version) != nil {
+		// Version 0, the tables themselves.
+		tables := make(map[string]MetaTableDetails)
+		if err := json.Unmarshal(data, &tables); err != nil {
+			return nil, err
+		}
+		namespaces[LegacyNamespace] = tables
+		return namespaces, nil
+	}
+	if version > MetaFileVersion {
+		return nil, fmt.Errorf("metadata format version %d is newer than the supported version %d", version, MetaFileVersion)
+	}
+	var file metaFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if version < 2 {
+		if file.Tables != nil {
+			namespaces[LegacyNamespace] = file.Tables
+		}
+		return namespaces, nil
+	}
+	for name, tables := range file.Namespaces {
+		if tables == nil {
+			tables = make(map[string]MetaTableDetails)
+		}
+		namespaces[name] = tables
+	}
+	return namespaces, nil
 }
 
-func saveActiveMetaTables(tl *MetaTableList, fileName string) error {
-	data, err := json.MarshalIndent(tl.ExistingTables, "", "  ")
+// writeMetaFile replaces the file through a temporary file in the same directory, so readers
+// see either the old or the new namespaces and never a partly written file.
+func writeMetaFile(fileName string, namespaces map[string]map[string]MetaTableDetails) error {
+	data, err := json.MarshalIndent(metaFile{Version: MetaFileVersion, Namespaces: namespaces}, "", "  ")
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(fileName, data, 0644)
+	temp, err := os.CreateTemp(filepath.Dir(fileName), filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), fileName)
+}
+
+// lockMetaFile locks fileName against other processes through fileName.lock, which is kept
+// because the metadata file itself is replaced on every write.
+func lockMetaFile(fileName string) (func(), error) {
+	file, err := os.OpenFile(fileName+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", fileName, err)
+	}
+	return func() {
+		unlockFile(file)
+		file.Close()
+	}, nil
 }
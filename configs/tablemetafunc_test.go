@@ -0,0 +1,208 @@
+package configs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// writeFile writes data to name in a new temporary directory and returns its path.
+func writeFile(t *testing.T, name string, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDecodeMetaFile(t *testing.T) {
+	users := `{"schema": {"columns": [{"name": "id", "type": "INTEGER", "null": "NO", "key": "PRI"}]}}`
+	tests := []struct {
+		name string
+		data string
+		want map[string][]string // namespace to tables
+	}{
+		{"empty", "  \n", map[string][]string{}},
+		{"version 0", `{"users": ` + users + `}`, map[string][]string{LegacyNamespace: {"users"}}},
+		{"version 1", `{"version": 1, "tables": {"users": ` + users + `}}`, map[string][]string{LegacyNamespace: {"users"}}},
+		{"version 1 without tables", `{"version": 1}`, map[string][]string{}},
+		{"version 2", `{"version": 2, "namespaces": {"sqlite:///a.db": {"users": ` + users + `}, "sqlite:///b.db": null}}`,
+			map[string][]string{"sqlite:///a.db": {"users"}, "sqlite:///b.db": {}}},
+	}
+	for _, tt := range tests {
+		namespaces, err := decodeMetaFile([]byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(namespaces) != len(tt.want) {
+			t.Errorf("%s: namespaces = %v", tt.name, namespaces)
+		}
+		for namespace, tables := range tt.want {
+			got, ok := namespaces[namespace]
+			if !ok || got == nil || len(got) != len(tables) {
+				t.Errorf("%s: namespace %q = %v", tt.name, namespace, got)
+				continue
+			}
+			for _, nm := range tables {
+				if len(got[nm].Schema.Columns) != 1 || got[nm].Schema.Columns[0].Name != "id" {
+					t.Errorf("%s: table %s = %+v", tt.name, nm, got[nm])
+				}
+			}
+		}
+	}
+
+	for _, data := range []string{`{"version": 3, "namespaces": {}}`, `[]`, `{"users": 1}`} {
+		if _, err := decodeMetaFile([]byte(data)); err == nil {
+			t.Errorf("decodeMetaFile(%s) succeeded", data)
+		}
+	}
+}
+
+func TestMetaFileWrites(t *testing.T) {
+	path := writeFile(t, "meta.json", `{"users": {"details": "legacy"}}`)
+	file, err := OpenMetaFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := OpenMetaFile(path); err != nil || again != file {
+		t.Errorf("opening %s again = %p, %v, want %p", path, again, err, file)
+	}
+	if err := file.Namespace("sqlite:///app.db").UpdateMetaTable("orders", MetaTableDetails{Details: "orders"}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written metaFile
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatal(err)
+	}
+	if written.Version != MetaFileVersion || written.Tables != nil {
+		t.Errorf("written version %d, tables %v", written.Version, written.Tables)
+	}
+	if written.Namespaces[LegacyNamespace]["users"].Details != "legacy" || written.Namespaces["sqlite:///app.db"]["orders"].Details != "orders" {
+		t.Errorf("written namespaces = %+v", written.Namespaces)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("temporary file %s left behind", entry.Name())
+		}
+	}
+}
+
+func TestMetaFileKeepsOtherWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meta.json")
+	file, err := OpenMetaFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := file.Namespace("sqlite:///app.db")
+	if err := store.UpdateMetaTable("users", MetaTableDetails{}); err != nil {
+		t.Fatal(err)
+	}
+	// Another process adds a namespace and a table behind the back of file.
+	namespaces, err := readMetaFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	namespaces["sqlite:///app.db"]["orders"] = MetaTableDetails{}
+	namespaces["sqlite:///other.db"] = map[string]MetaTableDetails{"events": {}}
+	if err := writeMetaFile(path, namespaces); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.UpdateMetaTable("items", MetaTableDetails{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, nm := range []string{"users", "orders", "items"} {
+		if store.FindMetaTable(nm) == nil {
+			t.Errorf("%s is missing after the update", nm)
+		}
+	}
+	if file.Namespace("sqlite:///other.db").FindMetaTable("events") == nil {
+		t.Error("the namespace of the other process was dropped")
+	}
+}
+
+func TestMetaFileConcurrentUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meta.json")
+	file, err := OpenMetaFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			namespace := fmt.Sprintf("sqlite:///db%d.db", i%2)
+			errs <- file.Namespace(namespace).UpdateMetaTable(fmt.Sprintf("table%d", i), MetaTableDetails{})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	namespaces, err := readMetaFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < writers; i++ {
+		if _, ok := namespaces[fmt.Sprintf("sqlite:///db%d.db", i%2)][fmt.Sprintf("table%d", i)]; !ok {
+			t.Errorf("table%d was lost", i)
+		}
+	}
+}
+
+func TestMetaFileErrors(t *testing.T) {
+	if _, err := OpenMetaFile(writeFile(t, "newer.json", `{"version": 99, "namespaces": {}}`)); err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Errorf("opening a newer version = %v", err)
+	}
+	if _, err := OpenMetaFile(writeFile(t, "broken.json", `{"users":`)); err == nil {
+		t.Error("opening a broken file succeeded")
+	}
+
+	dir := filepath.Join(t.TempDir(), "meta")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file, err := OpenMetaFile(filepath.Join(dir, "meta.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := file.Namespace("sqlite:///app.db")
+	if err := store.UpdateMetaTable("users", MetaTableDetails{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateMetaTable("orders", MetaTableDetails{}); err == nil {
+		t.Error("an update of a removed file succeeded")
+	}
+	if err := store.RemoveMetaTable("users"); err == nil {
+		t.Error("a removal from a removed file succeeded")
+	}
+	if store.FindMetaTable("orders") != nil || store.FindMetaTable("users") == nil {
+		t.Error("the store changed although the file was not written")
+	}
+}
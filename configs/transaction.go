@@ -0,0 +1,127 @@
+package configs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Tx is a database transaction. Calling WithTx on it nests the work inside a savepoint,
+// so an inner failure only undoes the inner work.
+type Tx struct {
+	tx    *sql.Tx
+	ctx   context.Context
+	depth int
+}
+
+// BeginTx starts a transaction. opts may be nil, which falls back to d.TxOptions.
+func (d *Database) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	if d == nil || d.DB() == nil {
+		return nil, errors.New("no active database connection")
+	}
+	if opts == nil {
+		opts = d.TxOptions
+	}
+	tx, err := d.DB().BeginTx(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	return &Tx{tx: tx, ctx: ctx}, nil
+}
+
+// WithTx runs fn in a transaction using d.TxOptions. The transaction is committed when fn
+// returns nil and rolled back when it returns an error or panics.
+func (d *Database) WithTx(ctx context.Context, fn func(tx *Tx) error) error {
+	return d.WithTxOptions(ctx, d.TxOptions, fn)
+}
+
+// WithTxOptions is WithTx with explicit options, e.g. a different isolation level.
+func (d *Database) WithTxOptions(ctx context.Context, opts *sql.TxOptions, fn func(tx *Tx) error) (err error) {
+	tx, err := d.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	return tx.tx.Commit()
+}
+
+// WithTx runs fn inside a savepoint of this transaction. The savepoint is released when fn
+// returns nil and rolled back to when it returns an error or panics; the outer transaction
+// stays usable either way.
+func (tx *Tx) WithTx(ctx context.Context, fn func(tx *Tx) error) error {
+	nested := &Tx{tx: tx.tx, ctx: ctx, depth: tx.depth + 1}
+	savepoint := fmt.Sprintf("sqldocify_sp_%d", nested.depth)
+	if _, err := tx.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+			panic(p)
+		}
+	}()
+
+	if err := fn(nested); err != nil {
+		if _, rbErr := tx.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); rbErr != nil {
+			return fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	if _, err := tx.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
+
+// Commit commits a transaction started with BeginTx. It cannot be used inside a savepoint.
+func (tx *Tx) Commit() error {
+	if tx.depth > 0 {
+		return errors.New("cannot commit a savepoint, return from WithTx instead")
+	}
+	return tx.tx.Commit()
+}
+
+// Rollback rolls back a transaction started with BeginTx. It cannot be used inside a savepoint.
+func (tx *Tx) Rollback() error {
+	if tx.depth > 0 {
+		return errors.New("cannot roll back a savepoint, return an error from WithTx instead")
+	}
+	return tx.tx.Rollback()
+}
+
+// Tx returns the underlying *sql.Tx.
+func (tx *Tx) Tx() *sql.Tx {
+	return tx.tx
+}
+
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.tx.ExecContext(tx.context(), query, args...)
+}
+
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.tx.QueryContext(tx.context(), query, args...)
+}
+
+func (tx *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.tx.QueryRowContext(tx.context(), query, args...)
+}
+
+func (tx *Tx) context() context.Context {
+	if tx.ctx == nil {
+		return context.Background()
+	}
+	return tx.ctx
+}
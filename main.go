@@ -3,49 +3,16 @@ package main
 import (
 	"fmt"
 	"log"
-	"sqldocify/configs"
 	"sqldocify/servers"
 	"sqldocify/table"
 )
 
-type FieldSchema struct {
-	Type  string `json:"Type"`
-	Null  string `json:"Null"`
-	Key   string `json:"Key"`
-	Extra string `json:"Extra"`
-}
-
-var userTableSchema = map[string]configs.FieldSchema{
-	"email": {
-		Type:  "varchar(255)",
-		Null:  "NO",
-		Key:   "UNI",
-		Extra: "",
-	},
-	"id": {
-		Type:  "int",
-		Null:  "NO",
-		Key:   "PRI",
-		Extra: "auto_increment",
-	},
-	"password": {
-		Type:  "varchar(255)",
-		Null:  "NO",
-		Key:   "",
-		Extra: "",
-	},
-	"status": {
-		Type:  "enum('active','inactive','banned')",
-		Null:  "NO",
-		Key:   "",
-		Extra: "",
-	},
-	"username": {
-		Type:  "varchar(255)",
-		Null:  "NO",
-		Key:   "UNI",
-		Extra: "",
-	},
+type User struct {
+	ID       int64  `sqldoc:"id,type=int,primary,auto_increment"`
+	Email    string `sqldoc:"email,type=varchar(255),unique,notnull"`
+	Password string `sqldoc:"password,type=varchar(255),notnull"`
+	Status   string `sqldoc:"status,type=enum('active','inactive','banned'),notnull"`
+	Username string `sqldoc:"username,type=varchar(255),unique,notnull"`
 }
 
 func main() {
@@ -62,7 +29,7 @@ func main() {
 		}
 	}()
 	tablespec, _ := table.AddSelectedDB()
-	tablespec.CreateTable(db.DB(), "uwe", userTableSchema)
+	tablespec.CreateTableFromStruct(db, "uwe", User{})
 	fmt.Println("Database connection established successfully!")
 
 	fmt.Println("Operations completed.")
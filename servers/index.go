@@ -4,16 +4,87 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"time"
 
 	"sqldocify/configs"
 	"sqldocify/servers/mysql"
+	"sqldocify/servers/postgres"
 	"sqldocify/servers/sqlite"
 	"sqldocify/table"
 	"sqldocify/validators"
 )
 
-// NewDatabase initializes a new database connection and manages the active tables list.
-func NewDatabase(dbtype, config string) (*configs.Database, error) {
+// ReconcilePolicy tells InitialTablesCheck what to do about tables that are only known to one
+// side, the database or its metadata store. The policies combine.
+type ReconcilePolicy uint8
+
+const (
+	// ImportUnknown adds tables found in the database to the metadata.
+	ImportUnknown ReconcilePolicy = 1 << iota
+	// CreateMissing creates the tables of the metadata the database lacks.
+	CreateMissing
+	// Strict fails when drift is left after the other policies ran: unknown or missing tables,
+	// or tables whose schema differs from the metadata.
+	Strict
+	// ReportOnly changes nothing, even combined with ImportUnknown or CreateMissing. It is a
+	// policy of its own so that a zero Options.Policy can stand for DefaultPolicy.
+	ReportOnly
+
+	// DefaultPolicy is used when NewDatabase gets no options or a zero Options.Policy.
+	DefaultPolicy = ImportUnknown | CreateMissing
+)
+
+// ErrSchemaDrift is returned under the Strict policy when the database and the metadata differ.
+var ErrSchemaDrift = errors.New("database schema differs from the metadata")
+
+// Options configures NewDatabase.
+type Options struct {
+	// Policy is DefaultPolicy when zero, ReportOnly leaves both sides as they are.
+	Policy ReconcilePolicy
+	// Report, when set, receives the reconciliation report of the startup check.
+	Report *ReconcileReport
+	// MetaStore keeps the metadata of the database, e.g. configs.NewMemoryMetaStore() in tests.
+	MetaStore configs.MetaStore
+	// MetaFile is the metadata file used without MetaStore, configs.DefaultMetaFile when empty.
+	// The database keeps its tables in its own namespace of the file; the tables of a file
+	// written before namespaces move to it when the namespace does not exist yet.
+	MetaFile string
+	// MetaInDatabase keeps the metadata in the configs.MetaTable table of the database itself
+	// instead of a store or file.
+	MetaInDatabase bool
+}
+
+// ReconcileReport is the outcome of InitialTablesCheck. Unknown and Missing list what was found,
+// Imported and Created what the policy changed; Drifted holds the tables present on both sides
+// whose schema differs.
+type ReconcileReport struct {
+	Policy   ReconcilePolicy      `json:"policy"`
+	Unknown  []string             `json:"unknown,omitempty"`
+	Missing  []string             `json:"missing,omitempty"`
+	Imported []string             `json:"imported,omitempty"`
+	Created  []string             `json:"created,omitempty"`
+	Drifted  []configs.SchemaDiff `json:"drifted,omitempty"`
+	Errors   []string             `json:"errors,omitempty"`
+}
+
+// InSync reports whether nothing is left unknown, missing or drifted.
+func (r *ReconcileReport) InSync() bool {
+	return len(r.Unknown) == len(r.Imported) && len(r.Missing) == len(r.Created) && len(r.Drifted) == 0
+}
+
+// NewDatabase initializes a new database connection and reconciles the database with its
+// metadata store. Without options or a Policy DefaultPolicy applies; when the check fails the
+// connection is closed and the error returned.
+func NewDatabase(dbtype, config string, options ...Options) (*configs.Database, error) {
+	var opts Options
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Policy == 0 {
+		opts.Policy = DefaultPolicy
+	}
+
 	validator := &validators.ServerValidator{}
 	var dbServer configs.DBServer
 
@@ -24,6 +95,9 @@ func NewDatabase(dbtype, config string) (*configs.Database, error) {
 	case "sqlite":
 		dbServer = &sqlite.SQLiteServer{Validator: validator}
 		configs.SetDBType("sqlite")
+	case "postgres", "postgresql":
+		dbServer = &postgres.PostgreSQLServer{Validator: validator}
+		configs.SetDBType("postgres")
 	default:
 		return nil, errors.New("database type not supported")
 	}
@@ -32,25 +106,88 @@ func NewDatabase(dbtype, config string) (*configs.Database, error) {
 		return nil, err
 	}
 	table.AddSelectedDB()
-	configs.GetMetaTableInstance() //It will load all the data in the list
-	InitialTablesCheck(&configs.Database{DBServer: dbServer})
-	return &configs.Database{DBServer: dbServer}, nil
+	db := &configs.Database{DBServer: dbServer, Meta: opts.MetaStore}
+	if err := openMetaStore(db, dbtype, config, opts); err != nil {
+		db.Close()
+		return nil, err
+	}
+	report, err := InitialTablesCheck(db, opts.Policy)
+	if opts.Report != nil && report != nil {
+		*opts.Report = *report
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// namespacer is implemented by servers that tell which database a config points at.
+type namespacer interface {
+	Namespace(config string) (string, error)
+}
+
+// openMetaStore sets the namespace of db and loads the metadata store selected by opts.
+func openMetaStore(db *configs.Database, dbtype string, config string, opts Options) error {
+	namespace := configs.NamespaceKey(dbtype, "", config)
+	if server, ok := db.DBServer.(namespacer); ok {
+		var err error
+		if namespace, err = server.Namespace(config); err != nil {
+			return err
+		}
+	}
+	db.Namespace = namespace
+
+	switch {
+	case opts.MetaInDatabase:
+		store, err := configs.NewDatabaseMetaStore(db)
+		if err != nil {
+			return fmt.Errorf("failed to load the metadata: %w", err)
+		}
+		db.Meta = store
+	case opts.MetaStore == nil:
+		fileName := opts.MetaFile
+		if fileName == "" {
+			fileName = configs.DefaultMetaFile
+		}
+		file, err := configs.OpenMetaFile(fileName)
+		if err != nil {
+			return fmt.Errorf("failed to load the metadata: %w", err)
+		}
+		moved, err := file.AdoptLegacyNamespace(namespace)
+		if err != nil {
+			return fmt.Errorf("failed to move the legacy metadata to %s: %w", namespace, err)
+		}
+		if moved {
+			log.Printf("sqldocify: moved the tables of the legacy metadata of %s to namespace %s", file.Path(), namespace)
+		}
+		db.Meta = file.Namespace(namespace)
+	}
+	return nil
 }
 
-func InitialTablesCheck(db *configs.Database) error {
-	metaTables := configs.GetMetaTableInstance()
+// InitialTablesCheck compares the tables of the database with the metadata store of db and
+// applies policy. The report is returned along with any error.
+func InitialTablesCheck(db *configs.Database, policy ReconcilePolicy) (*ReconcileReport, error) {
+	report := &ReconcileReport{Policy: policy}
+	if policy&ReportOnly != 0 {
+		policy &^= ImportUnknown | CreateMissing
+	}
+	metaTables := db.MetaStore()
 	tableSpec, err := table.AddSelectedDB()
 	if err != nil {
-		return err
+		return report, err
 	}
-	dbtablelist, err := tableSpec.GetAllTablesList(db.DB())
+	dbtablelist, err := tableSpec.GetAllTablesList(db)
 	if err != nil {
-		return fmt.Errorf("failed to fetch database tables: %v", err)
+		return report, fmt.Errorf("failed to fetch database tables: %v", err)
 	}
+	metaTableList := metaTables.MetaTables()
 	var metatablearray []string
-	for tableName := range metaTables.ExistingTables {
+	for tableName := range metaTableList {
 		metatablearray = append(metatablearray, tableName)
 	}
+	sort.Strings(metatablearray)
 	tableExistsInArray := func(tableName string, tableArray []string) bool {
 		for _, name := range tableArray {
 			if name == tableName {
@@ -59,37 +196,73 @@ func InitialTablesCheck(db *configs.Database) error {
 		}
 		return false
 	}
+	var errs []error
 
-	// 1. If any table exists in dbtablelist but not in metatablearray, update metaTables
+	// 1. Tables in dbtablelist but not in metatablearray are unknown, imported into metaTables
+	// under ImportUnknown. The migration history and the metadata table are not tracked tables.
 	for _, dbTable := range dbtablelist {
-		if !tableExistsInArray(dbTable, metatablearray) {
-			tableschema, err := tableSpec.GetMetaDataSchema(db.DB(), dbTable)
-			if err != nil {
-				log.Printf("failed to get schema for table %s: %v", dbTable, err)
-				continue
-			}
-			metatabledetails := configs.MetaTableDetails{
-				Schema:    tableschema,
-				Timestamp: "Timestamp",
-				Details:   "Details",
-			}
-			metaTables.UpdateMetaTable(dbTable, metatabledetails)
-			log.Printf("Table %s added to metaTables", dbTable)
+		if dbTable == configs.MigrationsTable || dbTable == configs.MetaTable || tableExistsInArray(dbTable, metatablearray) {
+			continue
+		}
+		report.Unknown = append(report.Unknown, dbTable)
+		if policy&ImportUnknown == 0 {
+			continue
+		}
+		tableschema, err := tableSpec.GetTableSchema(db, dbTable)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get schema for table %s: %w", dbTable, err))
+			continue
+		}
+		metatabledetails := configs.MetaTableDetails{
+			Schema:    tableschema,
+			Timestamp: time.Now().String(),
+			Details:   "Details",
+		}
+		if err := metaTables.UpdateMetaTable(dbTable, metatabledetails); err != nil {
+			errs = append(errs, fmt.Errorf("failed to store metadata of table %s: %w", dbTable, err))
+			continue
 		}
+		report.Imported = append(report.Imported, dbTable)
 	}
-	// 2. If any table exists in metatablearray but not in dbtablelist, create the table
+
+	// 2. Tables in metatablearray but not in dbtablelist are missing, created under CreateMissing.
+	// Missing tables are created together, so foreign keys between them are created in order.
+	missingTables := make(map[string]configs.TableSchema)
 	for _, metaTable := range metatablearray {
 		if !tableExistsInArray(metaTable, dbtablelist) {
-			tableschema := metaTables.ExistingTables[metaTable].Schema
-			if err := tableSpec.CreateTable(db.DB(), metaTable, tableschema); err != nil {
-				log.Printf("Failed to create table %s: %v", metaTable, err)
-				continue
-			}
+			report.Missing = append(report.Missing, metaTable)
+			missingTables[metaTable] = metaTableList[metaTable].Schema
+		}
+	}
+	if policy&CreateMissing != 0 && len(missingTables) > 0 {
+		created, err := tableSpec.CreateTables(db, missingTables)
+		report.Created = created
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to create missing tables: %w", err))
+		}
+	}
 
-			log.Printf("Table %s was missing and has been created", metaTable)
+	// 3. Tables on both sides are compared with their metadata schema.
+	for _, metaTable := range metatablearray {
+		if !tableExistsInArray(metaTable, dbtablelist) {
+			continue
+		}
+		diff, err := tableSpec.DiffTable(db, metaTable)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to compare table %s: %w", metaTable, err))
+			continue
+		}
+		if !diff.Empty() {
+			report.Drifted = append(report.Drifted, diff)
 		}
 	}
 
-	metaTables = configs.GetMetaTableInstance()
-	return nil
+	for _, err := range errs {
+		report.Errors = append(report.Errors, err.Error())
+	}
+	if policy&Strict != 0 && !report.InSync() {
+		errs = append(errs, fmt.Errorf("%w: %d unknown, %d missing, %d drifted tables", ErrSchemaDrift,
+			len(report.Unknown)-len(report.Imported), len(report.Missing)-len(report.Created), len(report.Drifted)))
+	}
+	return report, errors.Join(errs...)
 }
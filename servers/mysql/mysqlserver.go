@@ -4,10 +4,11 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"sqldocify/configs"
 	"sqldocify/validators"
 	"strings"
 
-	_ "github.com/go-sql-driver/mysql"
+	driver "github.com/go-sql-driver/mysql"
 )
 
 type MySQLServer struct {
@@ -67,3 +68,12 @@ func (m *MySQLServer) Close() error {
 func (m *MySQLServer) GetDB() *sql.DB {
 	return m.DB
 }
+
+// Namespace returns the metadata namespace of the database config points at.
+func (m *MySQLServer) Namespace(config string) (string, error) {
+	cfg, err := driver.ParseDSN(config)
+	if err != nil {
+		return "", fmt.Errorf("invalid config format: %w", err)
+	}
+	return configs.NamespaceKey("mysql", cfg.Addr, cfg.DBName), nil
+}
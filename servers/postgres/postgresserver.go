@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sqldocify/configs"
+	"sqldocify/table/queries"
+	"sqldocify/validators"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+type PostgreSQLServer struct {
+	DB        *sql.DB
+	Validator *validators.ServerValidator
+}
+
+func (p *PostgreSQLServer) Connect(config string) (*sql.DB, error) {
+	if valid, err := p.Validator.ValidatePostgresURL(config); !valid || err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config format: %s", config)
+	}
+	dbName := strings.TrimPrefix(u.Path, "/")
+
+	// Connect to the maintenance database first, the target one may not exist yet.
+	maintenance := *u
+	maintenance.Path = "/postgres"
+	db, err := sql.Open("postgres", maintenance.String())
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var exists int
+	err = db.QueryRow("SELECT 1 FROM pg_database WHERE datname = $1", dbName).Scan(&exists)
+
+	if err == sql.ErrNoRows {
+		createDBQuery := "CREATE DATABASE " + (&queries.PostgreSQLQueryGenerator{}).EscapeIdentifier(dbName)
+		_, err = db.Exec(createDBQuery)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create database: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to check database existence: %w", err)
+	}
+
+	db, err = sql.Open("postgres", config)
+	if err != nil {
+		return nil, err
+	}
+
+	p.DB = db
+	return db, nil
+}
+
+func (p *PostgreSQLServer) Close() error {
+	if p.DB != nil {
+		return p.DB.Close()
+	}
+	return errors.New("no active PostgreSQL connection to close")
+}
+
+func (p *PostgreSQLServer) GetDB() *sql.DB {
+	return p.DB
+}
+
+// Namespace returns the metadata namespace of the database config points at. Host and port
+// default to localhost:5432 like for libpq.
+func (p *PostgreSQLServer) Namespace(config string) (string, error) {
+	u, err := url.Parse(config)
+	if err != nil {
+		return "", fmt.Errorf("invalid config format: %s", config)
+	}
+	host, port := u.Hostname(), u.Port()
+	if host == "" {
+		host = "localhost"
+	}
+	if port == "" {
+		port = "5432"
+	}
+	dbName := strings.TrimPrefix(u.Path, "/")
+	if dbName == "" && u.User != nil {
+		dbName = u.User.Username()
+	}
+	return configs.NamespaceKey("postgres", net.JoinHostPort(host, port), dbName), nil
+}
@@ -3,8 +3,12 @@ package sqlite
 import (
 	"database/sql"
 	"errors"
+	"path/filepath"
+	"sqldocify/configs"
 	"sqldocify/validators"
-	// _ "github.com/mattn/go-sqlite3"
+	"strings"
+
+	_ "modernc.org/sqlite"
 )
 
 type SQLiteServer struct {
@@ -13,14 +17,26 @@ type SQLiteServer struct {
 }
 
 func (s *SQLiteServer) Connect(config string) (*sql.DB, error) {
-	if valid, err := s.Validator.ValidateConnectionPath(config); !valid || err != nil {
+	if valid, err := s.Validator.ValidateFilePath(config); !valid || err != nil {
 		return nil, err
 	}
 
-	db, err := sql.Open("sqlite3", config)
+	dsn := config
+	if !strings.Contains(dsn, "_pragma=foreign_keys") {
+		if strings.Contains(dsn, "?") {
+			dsn += "&_pragma=foreign_keys(1)"
+		} else {
+			dsn += "?_pragma=foreign_keys(1)"
+		}
+	}
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, err
 	}
+	if strings.Contains(config, ":memory:") {
+		// Every pooled connection would otherwise open its own empty in-memory database.
+		db.SetMaxOpenConns(1)
+	}
 	s.DB = db
 	return db, nil
 }
@@ -35,3 +51,20 @@ func (s *SQLiteServer) Close() error {
 func (s *SQLiteServer) GetDB() *sql.DB {
 	return s.DB
 }
+
+// Namespace returns the metadata namespace of the database file config points at, named by its
+// absolute path.
+func (s *SQLiteServer) Namespace(config string) (string, error) {
+	path := strings.TrimPrefix(config, "file:")
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+	if path != ":memory:" {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return "", err
+		}
+		path = strings.TrimPrefix(filepath.ToSlash(absPath), "/")
+	}
+	return configs.NamespaceKey("sqlite", "", path), nil
+}
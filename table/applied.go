@@ -1,11 +1,14 @@
 package table
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"log"
+	"fmt"
+	"sort"
 	"sqldocify/configs"
 	"sqldocify/table/queries"
+	"strings"
 	"time"
 )
 
@@ -14,6 +17,16 @@ var QGType queries.QueryGenerator
 type TableSpec struct {
 	TableName string
 	QGType    queries.QueryGenerator
+	tx        *configs.Tx
+}
+
+var _ ITableSpec = (*TableSpec)(nil)
+
+// executor is the part of *sql.DB and *sql.Tx the table operations need.
+type executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
 }
 
 func AddSelectedDB() (*TableSpec, error) {
@@ -26,62 +39,709 @@ func AddSelectedDB() (*TableSpec, error) {
 	}, nil
 }
 
+// NewTableSpec returns a TableSpec bound to the given table for the selected database type.
+func NewTableSpec(nm string) (*TableSpec, error) {
+	tableSpec, err := AddSelectedDB()
+	if err != nil {
+		return nil, err
+	}
+	tableSpec.TableName = nm
+	return tableSpec, nil
+}
+
 func (t *TableSpec) GetSelectedDB() queries.QueryGenerator {
 	return t.QGType
 }
-func (t *TableSpec) GetAllTablesList(db *sql.DB) ([]string, error) {
-	if db == nil {
+
+// Builder returns a query builder for the selected database type.
+func (t *TableSpec) Builder() *queries.Builder {
+	return queries.NewBuilder(t.QGType)
+}
+
+// generator returns the query generator reading stored schemas from the metadata of db.
+func (t *TableSpec) generator(db *configs.Database) queries.QueryGenerator {
+	if reader, ok := t.QGType.(queries.MetaStoreReader); ok {
+		return reader.WithMetaStore(db.MetaStore())
+	}
+	return t.QGType
+}
+
+// WithTx returns a copy of the table spec whose operations run in tx, e.g. inside
+// configs.Database.WithTx. Updates made through it use savepoints of tx.
+func (t *TableSpec) WithTx(tx *configs.Tx) *TableSpec {
+	bound := *t
+	bound.tx = tx
+	return &bound
+}
+
+// executor returns the running transaction if there is one, the connection pool otherwise.
+func (t *TableSpec) executor(db *configs.Database) (executor, error) {
+	if t.tx != nil {
+		return t.tx, nil
+	}
+	if db == nil || db.DB() == nil {
+		return nil, errors.New("no active database connection")
+	}
+	return db.DB(), nil
+}
+
+func (t *TableSpec) GetAllTablesList(db *configs.Database) ([]string, error) {
+	if db == nil || db.DB() == nil {
 		return nil, errors.New("no active database connection")
 	}
-	return t.QGType.GenerateGetAllTablesQuery(db)
+	return t.QGType.GenerateGetAllTablesQuery(db.DB())
 }
 
-func (t *TableSpec) GetMetaDataSchema(db *sql.DB, tname string) (map[string]configs.FieldSchema, error) {
-	return t.QGType.GenerateGetSchemaQuery(db, tname)
-	// return nil, nil
+// GetTableSchema reads the schema of a table from the database itself.
+func (t *TableSpec) GetTableSchema(db *configs.Database, tname string) (configs.TableSchema, error) {
+	if db == nil || db.DB() == nil {
+		return configs.TableSchema{}, errors.New("no active database connection")
+	}
+	return t.QGType.GenerateGetSchemaQuery(db.DB(), tname)
 }
-func (t *TableSpec) TableExists(nm string, db *sql.DB) bool {
-	metatables := configs.GetMetaTableInstance()
+
+// GetMetaDataSchema returns the schema stored in the metadata of db for the bound table.
+func (t *TableSpec) GetMetaDataSchema(db *configs.Database) (interface{}, error) {
+	if t.TableName == "" {
+		return nil, errors.New("table spec is not bound to a table")
+	}
+	details := db.MetaStore().FindMetaTable(t.TableName)
+	if details == nil {
+		return nil, fmt.Errorf("table %s not found in metadata", t.TableName)
+	}
+	return details.Schema, nil
+}
+
+// DiffTable compares the schema stored in the metadata for a table with the table in the database.
+func (t *TableSpec) DiffTable(db *configs.Database, nm string) (configs.SchemaDiff, error) {
+	details := db.MetaStore().FindMetaTable(nm)
+	if details == nil {
+		return configs.SchemaDiff{}, fmt.Errorf("table %s not found in metadata", nm)
+	}
+	actual, err := t.GetTableSchema(db, nm)
+	if err != nil {
+		return configs.SchemaDiff{}, err
+	}
+	diff := configs.Diff(t.QGType.NormalizeSchema(details.Schema), actual)
+	diff.Table = nm
+	return diff, nil
+}
+
+// DiffTables compares every table of the metadata that exists in the database and returns the
+// tables that drifted from their schema, sorted by name. Missing tables are left to
+// InitialTablesCheck.
+func (t *TableSpec) DiffTables(db *configs.Database) ([]configs.SchemaDiff, error) {
+	tables, err := t.GetAllTablesList(db)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(tables)
+	var diffs []configs.SchemaDiff
+	for _, nm := range tables {
+		if db.MetaStore().FindMetaTable(nm) == nil {
+			continue
+		}
+		diff, err := t.DiffTable(db, nm)
+		if err != nil {
+			return nil, err
+		}
+		if !diff.Empty() {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs, nil
+}
+
+func (t *TableSpec) TableExists(nm string, db *configs.Database) bool {
+	metatables := db.MetaStore()
 	if metatables.FindMetaTable(nm) != nil {
 		return true
 	}
-	//check from metatables
 
-	tableExistsQuery := t.QGType.GenerateTableExistsQuery(nm)
-	if db == nil {
-		errors.New("No active database connection.")
+	exec, err := t.executor(db)
+	if err != nil {
+		return false
+	}
+	query, args := t.QGType.GenerateTableExistsQuery(nm)
+	rows, err := exec.Query(query, args...)
+	if err != nil {
 		return false
 	}
-	db.Exec(tableExistsQuery)
-	return true
+	defer rows.Close()
+	return rows.Next()
 }
-func (t *TableSpec) CreateTable(db *sql.DB, nm string, schema map[string]configs.FieldSchema) error {
+
+func (t *TableSpec) CreateTable(db *configs.Database, nm string, schema configs.TableSchema) error {
+	if len(schema.Columns) == 0 {
+		return fmt.Errorf("cannot create table %s without columns", nm)
+	}
+	exec, err := t.executor(db)
+	if err != nil {
+		return err
+	}
 	createQuery := t.QGType.GenerateCreateTableQuery(nm, schema)
-	if db == nil {
-		return errors.New("no active database connection")
+	if _, err := exec.Exec(createQuery); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", nm, err)
 	}
-	_, err := db.Exec(createQuery)
-	metaTables := configs.GetMetaTableInstance()
+	metaTables := db.MetaStore()
 	if metaTables.FindMetaTable(nm) == nil {
-		println("Table not found in metadata")
 		metatabledetails := configs.MetaTableDetails{
 			Schema:    schema,
 			Timestamp: time.Now().String(),
 			Details:   "Details",
 		}
-		metaTables.UpdateMetaTable(nm, metatabledetails)
+		if err := metaTables.UpdateMetaTable(nm, metatabledetails); err != nil {
+			return fmt.Errorf("table %s created but its metadata was not stored: %w", nm, err)
+		}
+	}
+	return nil
+}
+
+// CreateTableFromStruct creates a table from the schema described by the sqldoc tags of model.
+func (t *TableSpec) CreateTableFromStruct(db *configs.Database, nm string, model interface{}) error {
+	schema, err := configs.SchemaFromStruct(model)
+	if err != nil {
+		return err
+	}
+	return t.CreateTable(db, nm, schema)
+}
+
+// CreateTables creates several tables, a referenced table before the tables referencing it.
+// Foreign keys between tables that reference each other are added once all of them exist.
+// A failing table does not stop the others, the created tables are returned in creation order.
+func (t *TableSpec) CreateTables(db *configs.Database, schemas map[string]configs.TableSchema) ([]string, error) {
+	order, deferred := creationOrder(schemas)
+	var created []string
+	var errs []error
+	for _, nm := range order {
+		schema := schemas[nm]
+		if len(deferred[nm]) > 0 {
+			schema = schema.Copy()
+			schema.ForeignKeys = nil
+			for _, foreignKey := range schemas[nm].ForeignKeys {
+				if !containsForeignKey(deferred[nm], foreignKey) {
+					schema.ForeignKeys = append(schema.ForeignKeys, foreignKey)
+				}
+			}
+		}
+		if err := t.CreateTable(db, nm, schema); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// The deferred foreign keys reach the metadata through AddForeignKey. Stored upfront, e.g.
+		// by a table recreated from its metadata, the SQLite generator would take them for
+		// existing keys and skip the rebuild.
+		if details := db.MetaStore().FindMetaTable(nm); len(deferred[nm]) > 0 && details != nil {
+			details.Schema = schema
+			if err := db.MetaStore().UpdateMetaTable(nm, *details); err != nil {
+				errs = append(errs, fmt.Errorf("table %s created but its metadata was not stored: %w", nm, err))
+				continue
+			}
+		}
+		created = append(created, nm)
+	}
+	for _, nm := range created {
+		for _, foreignKey := range deferred[nm] {
+			if err := t.AddForeignKey(db, nm, foreignKey); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return created, errors.Join(errs...)
+}
+
+// AddForeignKey adds a foreign key to an existing table and records it in the metadata.
+func (t *TableSpec) AddForeignKey(db *configs.Database, nm string, foreignKey configs.ForeignKeyDef) error {
+	exec, err := t.executor(db)
+	if err != nil {
+		return err
+	}
+	// An empty statement means the table already has the foreign key.
+	if query := t.generator(db).GenerateAddForeignKeyConstraintQuery(nm, foreignKey); query != "" {
+		if _, err := exec.Exec(query); err != nil {
+			return fmt.Errorf("failed to add foreign key on %s(%s) to table %s: %w", nm, strings.Join(foreignKey.Columns, ", "), foreignKey.ReferencedTable, err)
+		}
+	}
+	metaTables := db.MetaStore()
+	if details := metaTables.FindMetaTable(nm); details != nil && !containsForeignKey(details.Schema.ForeignKeys, foreignKey) {
+		details.Schema = details.Schema.Copy()
+		// A foreign key of the same name is replaced.
+		var kept []configs.ForeignKeyDef
+		for _, existing := range details.Schema.ForeignKeys {
+			if foreignKey.Name == "" || !strings.EqualFold(existing.Name, foreignKey.Name) {
+				kept = append(kept, existing)
+			}
+		}
+		details.Schema.ForeignKeys = append(kept, foreignKey)
+		if err := metaTables.UpdateMetaTable(nm, *details); err != nil {
+			return fmt.Errorf("foreign key added but the metadata of table %s was not stored: %w", nm, err)
+		}
+	}
+	return nil
+}
+
+// Insert stores a struct, a pointer to a struct or a column/value map as a new row.
+// A zero auto_increment primary key is left to the database and, when dt is a pointer,
+// the generated id is written back into the struct.
+func (t *TableSpec) Insert(dt interface{}, db *configs.Database) error {
+	if err := t.requireTable(); err != nil {
+		return err
+	}
+	record, structValue, err := toRecord(dt, t.TableName, t.metaSchema(db))
+	if err != nil {
+		return err
+	}
+	autoKey := t.autoIncrementKey(db)
+	generated := false
+	if value, ok := record[autoKey]; ok && autoKey != "" && isZeroValue(value) {
+		delete(record, autoKey)
+		generated = true
+	}
+	if len(record) == 0 {
+		return fmt.Errorf("nothing to insert into %s", t.TableName)
+	}
+	exec, err := t.executor(db)
+	if err != nil {
+		return err
+	}
+
+	columns := sortedColumns(record)
+	values := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		values = append(values, record[column])
+	}
+	if !generated || !structValue.CanSet() {
+		insertQuery, args := t.QGType.GenerateInsertQuery(t.TableName, columns, values)
+		if _, err := exec.Exec(insertQuery, args...); err != nil {
+			return fmt.Errorf("failed to insert into %s: %w", t.TableName, err)
+		}
+		return nil
+	}
+
+	var id int64
+	if returning, ok := t.QGType.(queries.ReturningInsertGenerator); ok {
+		insertQuery, args := returning.GenerateInsertReturningQuery(t.TableName, columns, values, autoKey)
+		if err := exec.QueryRow(insertQuery, args...).Scan(&id); err != nil {
+			return fmt.Errorf("failed to insert into %s: %w", t.TableName, err)
+		}
+	} else {
+		insertQuery, args := t.QGType.GenerateInsertQuery(t.TableName, columns, values)
+		res, err := exec.Exec(insertQuery, args...)
+		if err != nil {
+			return fmt.Errorf("failed to insert into %s: %w", t.TableName, err)
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to read generated id of %s: %w", t.TableName, err)
+		}
+	}
+	return setGeneratedID(structValue, autoKey, id)
+}
+
+// Update loads the row identified by the primary key of dt, compares it with dt and writes
+// only the columns that changed. onUpdate runs inside the same transaction when anything
+// changed; an error from it rolls the update back. The changed columns are returned.
+func (t *TableSpec) Update(dt interface{}, onUpdate func() error, db *configs.Database) (UpdateDiffs, error) {
+	var diffs UpdateDiffs
+	err := t.inTransaction(db, func(exec executor) error {
+		var err error
+		diffs, err = t.updateRecord(db, exec, dt)
+		if err != nil {
+			return err
+		}
+		if len(diffs) > 0 && onUpdate != nil {
+			return onUpdate()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diffs, nil
+}
+
+func (t *TableSpec) updateRecord(db *configs.Database, exec executor, dt interface{}) (UpdateDiffs, error) {
+	if err := t.requireTable(); err != nil {
+		return nil, err
+	}
+	record, err := t.recordValues(db, dt)
+	if err != nil {
+		return nil, err
+	}
+	primaryKeys, err := t.primaryKeys(db)
+	if err != nil {
+		return nil, err
+	}
+
+	keyValues := make(map[string]interface{}, len(primaryKeys))
+	for _, key := range primaryKeys {
+		value, ok := record[key]
+		if !ok {
+			return nil, fmt.Errorf("update on %s requires primary key column %s", t.TableName, key)
+		}
+		keyValues[key] = value
+	}
+	var columns []string
+	for _, column := range sortedColumns(record) {
+		if _, isKey := keyValues[column]; !isKey {
+			columns = append(columns, column)
+		}
+	}
+	if len(columns) == 0 {
+		return nil, nil
+	}
+
+	keyCondition := queries.EqMap(keyValues)
+	selectQuery, args, err := t.QGType.GenerateSelectQuery(t.TableName, queries.SelectOptions{
+		Columns: columns,
+		Where:   keyCondition,
+		OrderBy: queries.OrderByColumns(primaryKeys...),
+		Limit:   1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	rows, err := exec.Query(selectQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load current row of %s: %w", t.TableName, err)
+	}
+	current, err := scanRowMaps(rows)
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load current row of %s: %w", t.TableName, err)
+	}
+	if len(current) == 0 {
+		return nil, fmt.Errorf("no row in %s with primary key %v: %w", t.TableName, keyValues, sql.ErrNoRows)
+	}
+
+	var diffs UpdateDiffs
+	updates := make(map[string]interface{})
+	for _, column := range columns {
+		oldValue := current[0][column]
+		if sameValue(oldValue, record[column]) {
+			continue
+		}
+		updates[column] = record[column]
+		diffs = append(diffs, FieldDiff{FieldName: column, OldValue: oldValue, NewValue: record[column]})
+	}
+	if len(updates) == 0 {
+		return nil, nil
+	}
+
+	updateQuery, args, err := t.QGType.GenerateUpdateQuery(t.TableName, updates, keyCondition)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := exec.Exec(updateQuery, args...); err != nil {
+		return nil, fmt.Errorf("failed to update %s: %w", t.TableName, err)
+	}
+	return diffs, nil
+}
+
+func (t *TableSpec) Delete(condition interface{}, db *configs.Database) error {
+	if err := t.requireTable(); err != nil {
+		return err
+	}
+	if isEmptyCondition(condition) {
+		return fmt.Errorf("delete from %s requires a condition", t.TableName)
+	}
+	where, err := t.buildCondition(condition)
+	if err != nil {
+		return err
+	}
+	exec, err := t.executor(db)
+	if err != nil {
+		return err
+	}
+	deleteQuery, args, err := t.QGType.GenerateDeleteQuery(t.TableName, where)
+	if err != nil {
+		return err
+	}
+	if _, err := exec.Exec(deleteQuery, args...); err != nil {
+		return fmt.Errorf("failed to delete from %s: %w", t.TableName, err)
+	}
+	return nil
+}
+
+// Fetch loads the rows matching condition into result. result is a pointer to a struct or a
+// *map[string]interface{} for the first row, or a pointer to a slice of structs, struct pointers
+// or maps for every row. Struct fields are matched to columns by their sqldoc tag.
+func (t *TableSpec) Fetch(condition interface{}, result interface{}, db *configs.Database) error {
+	if err := t.requireTable(); err != nil {
+		return err
 	}
+	where, err := t.buildCondition(condition)
+	if err != nil {
+		return err
+	}
+	exec, err := t.executor(db)
+	if err != nil {
+		return err
+	}
+
+	options := queries.SelectOptions{Where: where}
+	if schema := t.metaSchema(db); schema != nil && len(schema.Columns) > 0 {
+		options.Columns = schema.ColumnNames()
+		options.OrderBy = queries.OrderByColumns(options.Columns[0])
+		if primaryKeys, err := t.primaryKeys(db); err == nil {
+			options.OrderBy = queries.OrderByColumns(primaryKeys...)
+		}
+	}
+	if singleResult(result) {
+		options.Limit = 1
+	}
+	selectQuery, args, err := t.QGType.GenerateSelectQuery(t.TableName, options)
+	if err != nil {
+		return err
+	}
+	rows, err := exec.Query(selectQuery, args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch from %s: %w", t.TableName, err)
+	}
+	defer rows.Close()
+
+	switch out := result.(type) {
+	case *[]map[string]interface{}:
+		records, err := scanRowMaps(rows)
+		if err != nil {
+			return fmt.Errorf("failed to read rows of %s: %w", t.TableName, err)
+		}
+		*out = records
+	case *map[string]interface{}:
+		records, err := scanRowMaps(rows)
+		if err != nil {
+			return fmt.Errorf("failed to read rows of %s: %w", t.TableName, err)
+		}
+		if len(records) == 0 {
+			return sql.ErrNoRows
+		}
+		*out = records[0]
+	default:
+		if err := scanInto(rows, result); err != nil {
+			if err == sql.ErrNoRows {
+				return err
+			}
+			return fmt.Errorf("failed to read rows of %s: %w", t.TableName, err)
+		}
+	}
+	return nil
+}
+
+// BeginTransaction starts a transaction using db.TxOptions and returns a copy of the table spec
+// whose operations run in it, like WithTx. The spec it is called on keeps running outside of the
+// transaction; end it with CommitTransaction or RollbackTransaction of the copy.
+func (t *TableSpec) BeginTransaction(db *configs.Database) (*TableSpec, error) {
+	if t.tx != nil {
+		return nil, errors.New("transaction already in progress")
+	}
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return t.WithTx(tx), nil
+}
+
+// CommitTransaction commits the transaction of a table spec returned by BeginTransaction.
+func (t *TableSpec) CommitTransaction(db *configs.Database) error {
+	if t.tx == nil {
+		return errors.New("no transaction in progress")
+	}
+	err := t.tx.Commit()
+	t.tx = nil
 	return err
 }
 
-func (t *TableSpec) Insert(dt interface{}, db *sql.DB) error {
-	columns := []string{"column1", "column2"}
-	values := []interface{}{dt, dt}
+// RollbackTransaction rolls back the transaction of a table spec returned by BeginTransaction.
+func (t *TableSpec) RollbackTransaction(db *configs.Database) error {
+	if t.tx == nil {
+		return errors.New("no transaction in progress")
+	}
+	err := t.tx.Rollback()
+	t.tx = nil
+	return err
+}
+
+func (t *TableSpec) BatchInsert(dts []interface{}, db *configs.Database) error {
+	if err := t.requireTable(); err != nil {
+		return err
+	}
+	if len(dts) == 0 {
+		return nil
+	}
+
+	var columns []string
+	autoKey := t.autoIncrementKey(db)
+	batchValues := make([][]interface{}, 0, len(dts))
+	for i, dt := range dts {
+		record, err := t.recordValues(db, dt)
+		if err != nil {
+			return err
+		}
+		if value, ok := record[autoKey]; ok && autoKey != "" && isZeroValue(value) {
+			delete(record, autoKey)
+		}
+		if i == 0 {
+			columns = sortedColumns(record)
+		} else if !sameColumns(columns, record) {
+			return fmt.Errorf("record %d of the batch has different columns than the first one", i)
+		}
+		values := make([]interface{}, 0, len(columns))
+		for _, column := range columns {
+			values = append(values, record[column])
+		}
+		batchValues = append(batchValues, values)
+	}
+
+	exec, err := t.executor(db)
+	if err != nil {
+		return err
+	}
+	insertQuery, args := t.QGType.GenerateBatchInsertQuery(t.TableName, columns, batchValues)
+	if _, err := exec.Exec(insertQuery, args...); err != nil {
+		return fmt.Errorf("failed to batch insert into %s: %w", t.TableName, err)
+	}
+	return nil
+}
+
+// BatchUpdate updates every record in one transaction, calling onUpdate after each record
+// that changed. The diffs are returned in the order of dts.
+func (t *TableSpec) BatchUpdate(dts []interface{}, onUpdate func() error, db *configs.Database) ([]UpdateDiffs, error) {
+	allDiffs := make([]UpdateDiffs, 0, len(dts))
+	err := t.inTransaction(db, func(exec executor) error {
+		for _, dt := range dts {
+			diffs, err := t.updateRecord(db, exec, dt)
+			if err != nil {
+				return err
+			}
+			if len(diffs) > 0 && onUpdate != nil {
+				if err := onUpdate(); err != nil {
+					return err
+				}
+			}
+			allDiffs = append(allDiffs, diffs)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allDiffs, nil
+}
+
+func (t *TableSpec) BatchDelete(conditions []interface{}, db *configs.Database) error {
+	if err := t.requireTable(); err != nil {
+		return err
+	}
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	wheres := make([]queries.Condition, 0, len(conditions))
+	for _, condition := range conditions {
+		if isEmptyCondition(condition) {
+			return fmt.Errorf("delete from %s requires a condition", t.TableName)
+		}
+		where, err := t.buildCondition(condition)
+		if err != nil {
+			return err
+		}
+		wheres = append(wheres, where)
+	}
+
+	exec, err := t.executor(db)
+	if err != nil {
+		return err
+	}
+	deleteQuery, args, err := t.QGType.GenerateMultipleDeleteQuery(t.TableName, wheres)
+	if err != nil {
+		return err
+	}
+	if _, err := exec.Exec(deleteQuery, args...); err != nil {
+		return fmt.Errorf("failed to batch delete from %s: %w", t.TableName, err)
+	}
+	return nil
+}
 
-	insertQuery := t.QGType.GenerateInsertQuery(t.TableName, columns, values)
-	log.Printf("Insert Query: %s", insertQuery)
+// inTransaction runs fn in a savepoint of the running transaction, or in a new transaction.
+// Either way a failing fn leaves no trace of its own work.
+func (t *TableSpec) inTransaction(db *configs.Database, fn func(exec executor) error) error {
+	run := func(tx *configs.Tx) error {
+		return fn(tx)
+	}
+	if t.tx != nil {
+		return t.tx.WithTx(context.Background(), run)
+	}
 	if db == nil {
 		return errors.New("no active database connection")
 	}
+	return db.WithTx(context.Background(), run)
+}
+
+func (t *TableSpec) requireTable() error {
+	if t.TableName == "" {
+		return errors.New("table spec is not bound to a table")
+	}
 	return nil
 }
+
+// metaSchema returns the stored schema of the bound table, or nil when the table is unknown.
+func (t *TableSpec) metaSchema(db *configs.Database) *configs.TableSchema {
+	details := db.MetaStore().FindMetaTable(t.TableName)
+	if details == nil {
+		return nil
+	}
+	return &details.Schema
+}
+
+// primaryKeys returns the primary key columns of the bound table from its metadata, in key order.
+func (t *TableSpec) primaryKeys(db *configs.Database) ([]string, error) {
+	schema := t.metaSchema(db)
+	if schema == nil {
+		return nil, fmt.Errorf("table %s not found in metadata", t.TableName)
+	}
+	keys := schema.PrimaryKeyColumns()
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("table %s has no primary key", t.TableName)
+	}
+	return keys, nil
+}
+
+// recordValues turns a record into column/value pairs and checks the columns against the metadata.
+func (t *TableSpec) recordValues(db *configs.Database, dt interface{}) (map[string]interface{}, error) {
+	record, _, err := toRecord(dt, t.TableName, t.metaSchema(db))
+	return record, err
+}
+
+// autoIncrementKey returns the auto_increment primary key column of the bound table, or "".
+func (t *TableSpec) autoIncrementKey(db *configs.Database) string {
+	schema := t.metaSchema(db)
+	if schema == nil {
+		return ""
+	}
+	for _, key := range schema.PrimaryKeyColumns() {
+		fieldSchema, _ := schema.Column(key)
+		if strings.Contains(strings.ToLower(fieldSchema.Extra), "auto_increment") {
+			return key
+		}
+	}
+	return ""
+}
+
+// buildCondition accepts a queries.Condition, a raw SQL condition string or a map of
+// column = value pairs joined by AND. A nil condition matches every row.
+func (t *TableSpec) buildCondition(condition interface{}) (queries.Condition, error) {
+	switch c := condition.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if c == "" {
+			return nil, nil
+		}
+		return queries.Raw(c), nil
+	case map[string]interface{}:
+		return queries.EqMap(c), nil
+	case queries.Condition:
+		return c, nil
+	default:
+		return nil, fmt.Errorf("unsupported condition type %T", condition)
+	}
+}
@@ -0,0 +1,576 @@
+package table_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"sqldocify/configs"
+	"sqldocify/table"
+)
+
+// openUsersTable creates a users table on an in-memory database and returns a spec bound to it.
+func openUsersTable(t *testing.T) (*configs.Database, *table.TableSpec) {
+	t.Helper()
+	db, spec := openMemoryDatabase(t)
+	schema := configs.NewTableSchema(
+		configs.ColumnDef{Name: "id", FieldSchema: configs.FieldSchema{Type: "INTEGER", Null: "NO", Key: "PRI", Extra: "auto_increment"}},
+		column("name", "TEXT", "NO", ""),
+		column("email", "TEXT", "YES", "UNI"),
+	)
+	if err := spec.CreateTable(db, "users", schema); err != nil {
+		t.Fatal(err)
+	}
+	users, err := table.NewTableSpec("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, users
+}
+
+func TestTableSpecCRUD(t *testing.T) {
+	db, users := openUsersTable(t)
+	if !users.TableExists("users", db) || users.TableExists("missing", db) {
+		t.Error("TableExists does not match the tables of the database")
+	}
+
+	for _, record := range []map[string]interface{}{
+		{"id": 1, "name": "ada", "email": "ada@example.com"},
+		{"id": 2, "name": "bob", "email": "bob@example.com"},
+	} {
+		if err := users.Insert(record, db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := users.Insert(map[string]interface{}{"id": 3, "name": "eve", "email": "ada@example.com"}, db); err == nil {
+		t.Error("inserting a duplicate email succeeded")
+	}
+
+	var rows []map[string]interface{}
+	if err := users.Fetch(nil, &rows, db); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0]["name"] != "ada" || rows[1]["name"] != "bob" {
+		t.Fatalf("rows = %v", rows)
+	}
+
+	diffs, err := users.Update(map[string]interface{}{"id": 2, "name": "robert", "email": "bob@example.com"}, nil, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 || diffs[0].FieldName != "name" || diffs[0].OldValue != "bob" || diffs[0].NewValue != "robert" {
+		t.Errorf("diffs = %+v", diffs)
+	}
+	var row map[string]interface{}
+	if err := users.Fetch(map[string]interface{}{"id": 2}, &row, db); err != nil {
+		t.Fatal(err)
+	}
+	if row["name"] != "robert" {
+		t.Errorf("row = %v", row)
+	}
+
+	if err := users.Delete(nil, db); err == nil {
+		t.Error("a delete without a condition succeeded")
+	}
+	if err := users.Delete("name = 'ada'", db); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Fetch(map[string]interface{}{"id": 1}, &row, db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Fetch of a deleted row = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := users.Update(map[string]interface{}{"id": 1, "name": "ada"}, nil, db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Update of a deleted row = %v, want sql.ErrNoRows", err)
+	}
+	if err := users.Fetch(42, &row, db); err == nil || !strings.Contains(err.Error(), "unsupported condition") {
+		t.Errorf("Fetch with an int condition = %v", err)
+	}
+
+	unbound, err := table.AddSelectedDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := unbound.Insert(map[string]interface{}{"id": 4}, db); err == nil {
+		t.Error("Insert on a spec without a table succeeded")
+	}
+}
+
+type contact struct {
+	Email *string
+}
+
+type userRecord struct {
+	ID   int64  `sqldoc:"id"`
+	Name string `sqldoc:"name"`
+	*contact
+	Note    string `sqldoc:"-"`
+	Visible bool   // no column of that name, left out
+}
+
+func TestInsertStruct(t *testing.T) {
+	db, users := openUsersTable(t)
+
+	email := "ada@example.com"
+	ada := &userRecord{Name: "ada", contact: &contact{Email: &email}, Note: "not stored"}
+	if err := users.Insert(ada, db); err != nil {
+		t.Fatal(err)
+	}
+	bob := &userRecord{Name: "bob"}
+	if err := users.Insert(bob, db); err != nil {
+		t.Fatal(err)
+	}
+	if ada.ID != 1 || bob.ID != 2 {
+		t.Errorf("generated ids = %d, %d", ada.ID, bob.ID)
+	}
+	if err := users.Insert(userRecord{ID: 10, Name: "eve"}, db); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Insert(userRecord{Name: "dan"}, db); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM users WHERE id = 10 AND name = 'eve'"); n != 1 {
+		t.Error("an explicit id was not kept")
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM users WHERE email = 'ada@example.com' AND name = 'ada'"); n != 1 {
+		t.Error("the field of the embedded struct was not stored")
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM users WHERE email IS NULL"); n != 3 {
+		t.Errorf("%d rows without email, want 3", n)
+	}
+
+	type tagged struct {
+		Name    string `sqldoc:"name"`
+		Missing string `sqldoc:"missing"`
+	}
+	if err := users.Insert(tagged{Name: "x"}, db); err == nil || !strings.Contains(err.Error(), "column missing does not exist") {
+		t.Errorf("Insert with a tagged unknown column = %v", err)
+	}
+	if err := users.Insert(map[string]interface{}{"name": "x", "missing": 1}, db); err == nil {
+		t.Error("Insert of a map with an unknown column succeeded")
+	}
+	if err := users.Insert((*userRecord)(nil), db); err == nil {
+		t.Error("Insert of a nil pointer succeeded")
+	}
+	if err := users.Insert([]string{"x"}, db); err == nil {
+		t.Error("Insert of a slice succeeded")
+	}
+}
+
+type event struct {
+	ID       int64     `sqldoc:"id"`
+	Kind     string    `sqldoc:"kind"`
+	Happened time.Time `sqldoc:"happened"`
+	Note     *string   `sqldoc:"note"`
+	Active   bool      `sqldoc:"active"`
+	Score    float64   `sqldoc:"score"`
+	Unstored string    // no column, keeps its value
+}
+
+func TestFetchStructs(t *testing.T) {
+	db, spec := openMemoryDatabase(t)
+	schema := configs.NewTableSchema(
+		column("id", "INTEGER", "NO", "PRI"),
+		column("kind", "enum('open','closed')", "NO", ""),
+		column("happened", "DATETIME", "NO", ""),
+		column("note", "TEXT", "YES", ""),
+		column("active", "BOOLEAN", "NO", ""),
+		column("score", "DECIMAL(5,2)", "YES", ""),
+		column("secret", "TEXT", "YES", ""),
+	)
+	if err := spec.CreateTable(db, "events", schema); err != nil {
+		t.Fatal(err)
+	}
+	exec(t, db,
+		`INSERT INTO events (id, kind, happened, note, active, score, secret) VALUES (2, 'closed', '2024-03-01 10:30:00', NULL, 0, NULL, 'x')`,
+		`INSERT INTO events (id, kind, happened, note, active, score, secret) VALUES (1, 'open', '2024-02-01T08:00:00Z', 'first', 1, 12.5, 'y')`,
+	)
+	events, err := table.NewTableSpec("events")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := event{Unstored: "kept"}
+	if err := events.Fetch(nil, &first, db); err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2024, 2, 1, 8, 0, 0, 0, time.UTC)
+	if first.ID != 1 || first.Kind != "open" || !first.Happened.Equal(want) || first.Note == nil || *first.Note != "first" ||
+		!first.Active || first.Score != 12.5 || first.Unstored != "kept" {
+		t.Errorf("first = %+v", first)
+	}
+
+	var all []event
+	if err := events.Fetch(nil, &all, db); err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[1].ID != 2 || all[1].Note != nil || all[1].Active || all[1].Score != 0 ||
+		!all[1].Happened.Equal(time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("all = %+v", all)
+	}
+
+	var pointers []*event
+	if err := events.Fetch(map[string]interface{}{"kind": "closed"}, &pointers, db); err != nil {
+		t.Fatal(err)
+	}
+	if len(pointers) != 1 || pointers[0].ID != 2 {
+		t.Errorf("pointers = %+v", pointers)
+	}
+
+	var nullable struct {
+		Note sql.NullString `sqldoc:"note"`
+	}
+	if err := events.Fetch(map[string]interface{}{"id": 2}, &nullable, db); err != nil {
+		t.Fatal(err)
+	}
+	if nullable.Note.Valid {
+		t.Errorf("note = %+v, want NULL", nullable.Note)
+	}
+
+	var none []event
+	if err := events.Fetch(map[string]interface{}{"id": 3}, &none, db); err != nil || len(none) != 0 {
+		t.Errorf("Fetch of no rows into a slice = %v, %v", none, err)
+	}
+	if err := events.Fetch(map[string]interface{}{"id": 3}, &first, db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Fetch of no rows into a struct = %v, want sql.ErrNoRows", err)
+	}
+	if err := events.Fetch(nil, first, db); err == nil {
+		t.Error("Fetch into a struct value succeeded")
+	}
+	var wrongType struct {
+		Kind int `sqldoc:"kind"`
+	}
+	if err := events.Fetch(nil, &wrongType, db); err == nil || !strings.Contains(err.Error(), "column kind") {
+		t.Errorf("Fetch of text into an int = %v", err)
+	}
+}
+
+func TestUpdateDiffs(t *testing.T) {
+	db, users := openUsersTable(t)
+	email := "ada@example.com"
+	ada := &userRecord{Name: "ada", contact: &contact{Email: &email}}
+	if err := users.Insert(ada, db); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	onUpdate := func() error { calls++; return nil }
+	diffs, err := users.Update(ada, onUpdate, db)
+	if err != nil || diffs != nil || calls != 0 {
+		t.Errorf("Update without changes = %+v, %v after %d callbacks", diffs, err, calls)
+	}
+
+	newEmail := "lovelace@example.com"
+	ada.Name, ada.Email = "ada lovelace", &newEmail
+	diffs, err = users.Update(ada, onUpdate, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := table.UpdateDiffs{
+		{FieldName: "email", OldValue: "ada@example.com", NewValue: &newEmail},
+		{FieldName: "name", OldValue: "ada", NewValue: "ada lovelace"},
+	}
+	if len(diffs) != len(want) || calls != 1 {
+		t.Fatalf("diffs = %+v after %d callbacks", diffs, calls)
+	}
+	for i := range want {
+		if diffs[i].FieldName != want[i].FieldName || diffs[i].OldValue != want[i].OldValue || diffs[i].NewValue != want[i].NewValue {
+			t.Errorf("diff %d = %+v, want %+v", i, diffs[i], want[i])
+		}
+	}
+
+	// An error of the callback rolls the update back.
+	ada.Name = "countess"
+	failure := errors.New("audit log unavailable")
+	if _, err := users.Update(ada, func() error { return failure }, db); !errors.Is(err, failure) {
+		t.Fatalf("Update = %v, want the callback error", err)
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM users WHERE name = 'ada lovelace'"); n != 1 {
+		t.Error("the update of a failed callback was kept")
+	}
+
+	if _, err := users.Update(map[string]interface{}{"name": "x"}, nil, db); err == nil || !strings.Contains(err.Error(), "primary key column id") {
+		t.Errorf("Update without the primary key = %v", err)
+	}
+}
+
+func TestTableSpecBatches(t *testing.T) {
+	db, users := openUsersTable(t)
+
+	batch := []interface{}{
+		map[string]interface{}{"id": 1, "name": "ada", "email": "ada@example.com"},
+		map[string]interface{}{"id": 2, "name": "bob", "email": "bob@example.com"},
+		map[string]interface{}{"id": 3, "name": "eve", "email": "eve@example.com"},
+	}
+	if err := users.BatchInsert(batch, db); err != nil {
+		t.Fatal(err)
+	}
+	mixed := []interface{}{
+		map[string]interface{}{"id": 4, "name": "dan"},
+		map[string]interface{}{"id": 5, "name": "fay", "email": "fay@example.com"},
+	}
+	if err := users.BatchInsert(mixed, db); err == nil || !strings.Contains(err.Error(), "different columns") {
+		t.Errorf("BatchInsert with different columns = %v", err)
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM users"); n != 3 {
+		t.Fatalf("%d rows after the batch insert", n)
+	}
+
+	updates := []interface{}{
+		map[string]interface{}{"id": 1, "name": "ada", "email": "ada@example.com"},
+		map[string]interface{}{"id": 2, "name": "bobby", "email": "bob@example.com"},
+	}
+	calls := 0
+	all, err := users.BatchUpdate(updates, func() error { calls++; return nil }, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || len(all[0]) != 0 || len(all[1]) != 1 || calls != 1 {
+		t.Errorf("diffs = %+v after %d callbacks", all, calls)
+	}
+
+	// A failing record rolls back the whole batch.
+	failing := []interface{}{
+		map[string]interface{}{"id": 1, "name": "ada lovelace", "email": "ada@example.com"},
+		map[string]interface{}{"id": 3, "name": "eve", "email": "bob@example.com"},
+	}
+	if _, err := users.BatchUpdate(failing, nil, db); err == nil {
+		t.Fatal("a batch update breaking the unique email succeeded")
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM users WHERE name = 'ada'"); n != 1 {
+		t.Error("the first update of a failed batch was kept")
+	}
+
+	if err := users.BatchDelete([]interface{}{map[string]interface{}{"id": 1}, nil}, db); err == nil {
+		t.Error("a batch delete with an empty condition succeeded")
+	}
+	if err := users.BatchDelete([]interface{}{map[string]interface{}{"id": 1}, "name = 'eve'"}, db); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM users"); n != 1 {
+		t.Errorf("%d rows after the batch delete", n)
+	}
+}
+
+func TestBeginTransaction(t *testing.T) {
+	db, spec := openMemoryDatabase(t)
+	schema := configs.NewTableSchema(column("id", "INTEGER", "NO", "PRI"), column("name", "TEXT", "YES", ""))
+	if err := spec.CreateTable(db, "users", schema); err != nil {
+		t.Fatal(err)
+	}
+	users, err := table.NewTableSpec("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txUsers, err := users.BeginTransaction(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.CommitTransaction(db); err == nil {
+		t.Error("the spec BeginTransaction was called on is in the transaction")
+	}
+	if _, err := txUsers.BeginTransaction(db); err == nil {
+		t.Error("a second transaction was started on the bound copy")
+	}
+	if err := txUsers.Insert(map[string]interface{}{"id": 1, "name": "a"}, db); err != nil {
+		t.Fatal(err)
+	}
+	if err := txUsers.RollbackTransaction(db); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM users"); n != 0 {
+		t.Errorf("%d rows after the rollback", n)
+	}
+
+	txUsers, err = users.BeginTransaction(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := txUsers.Insert(map[string]interface{}{"id": 2, "name": "b"}, db); err != nil {
+		t.Fatal(err)
+	}
+	if err := txUsers.CommitTransaction(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := txUsers.CommitTransaction(db); err == nil {
+		t.Error("a committed transaction was committed again")
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM users"); n != 1 {
+		t.Errorf("%d rows after the commit", n)
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	db, users := openUsersTable(t)
+	ctx := context.Background()
+	insert := func(tx *configs.Tx, id int, name string) error {
+		return users.WithTx(tx).Insert(map[string]interface{}{"id": id, "name": name}, db)
+	}
+
+	if err := db.WithTx(ctx, func(tx *configs.Tx) error { return insert(tx, 1, "ada") }); err != nil {
+		t.Fatal(err)
+	}
+	failure := errors.New("failed")
+	err := db.WithTx(ctx, func(tx *configs.Tx) error {
+		if err := insert(tx, 2, "bob"); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("WithTx = %v, want the error of fn", err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("the panic of fn was not passed on")
+			}
+		}()
+		db.WithTx(ctx, func(tx *configs.Tx) error {
+			insert(tx, 3, "eve")
+			panic("boom")
+		})
+	}()
+	if n := count(t, db, "SELECT COUNT(*) FROM users"); n != 1 {
+		t.Fatalf("%d rows, want only the committed one", n)
+	}
+
+	// A failing savepoint only undoes its own work, at any depth.
+	err = db.WithTx(ctx, func(tx *configs.Tx) error {
+		if err := insert(tx, 4, "dan"); err != nil {
+			return err
+		}
+		if err := tx.WithTx(ctx, func(tx *configs.Tx) error {
+			if err := insert(tx, 5, "fay"); err != nil {
+				return err
+			}
+			if err := tx.WithTx(ctx, func(tx *configs.Tx) error {
+				if err := insert(tx, 6, "gus"); err != nil {
+					return err
+				}
+				return failure
+			}); !errors.Is(err, failure) {
+				return fmt.Errorf("inner savepoint = %v", err)
+			}
+			if tx.Commit() == nil || tx.Rollback() == nil {
+				return errors.New("a savepoint was committed or rolled back directly")
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		// Update runs in a savepoint of tx as well, a failing callback only undoes the update.
+		if _, err := users.WithTx(tx).Update(map[string]interface{}{"id": 4, "name": "daniel"}, func() error { return failure }, db); !errors.Is(err, failure) {
+			return fmt.Errorf("Update = %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM users WHERE id IN (4, 5) AND name <> 'daniel'"); n != 2 {
+		t.Errorf("%d rows of the outer transaction and the released savepoint, want 2", n)
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM users WHERE id = 6"); n != 0 {
+		t.Error("the row of the rolled back savepoint was kept")
+	}
+
+	// SQLite accepts every isolation level, only the options path is exercised here.
+	db.TxOptions = &sql.TxOptions{Isolation: sql.LevelSerializable}
+	if err := db.WithTxOptions(ctx, nil, func(tx *configs.Tx) error { return insert(tx, 7, "hal") }); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, db, "SELECT COUNT(*) FROM users WHERE id = 7"); n != 1 {
+		t.Error("WithTxOptions did not commit")
+	}
+}
+
+func TestCreateTablesForeignKeys(t *testing.T) {
+	db, spec := openMemoryDatabase(t)
+	id := column("id", "INTEGER", "NO", "PRI")
+	reference := func(columnName string, referenced string, onDelete string) configs.ForeignKeyDef {
+		return configs.ForeignKeyDef{Columns: []string{columnName}, ReferencedTable: referenced, ReferencedColumns: []string{"id"}, OnDelete: onDelete}
+	}
+	schemas := map[string]configs.TableSchema{
+		// items and orders sort before the tables they reference.
+		"items": {
+			Columns:     []configs.ColumnDef{id, column("order_id", "INTEGER", "NO", "")},
+			ForeignKeys: []configs.ForeignKeyDef{reference("order_id", "orders", "CASCADE")},
+		},
+		"orders": {
+			Columns:     []configs.ColumnDef{id, column("user_id", "INTEGER", "YES", "")},
+			ForeignKeys: []configs.ForeignKeyDef{reference("user_id", "users", "SET NULL")},
+		},
+		"users": {Columns: []configs.ColumnDef{id}},
+		// teams and people reference each other.
+		"people": {
+			Columns:     []configs.ColumnDef{id, column("team_id", "INTEGER", "YES", "")},
+			ForeignKeys: []configs.ForeignKeyDef{reference("team_id", "teams", "")},
+		},
+		"teams": {
+			Columns:     []configs.ColumnDef{id, column("owner_id", "INTEGER", "YES", "")},
+			ForeignKeys: []configs.ForeignKeyDef{reference("owner_id", "people", "")},
+		},
+	}
+
+	created, err := spec.CreateTables(db, schemas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	position := make(map[string]int)
+	for i, nm := range created {
+		position[nm] = i
+	}
+	if len(created) != len(schemas) {
+		t.Fatalf("created %v", created)
+	}
+	if position["users"] > position["orders"] || position["orders"] > position["items"] {
+		t.Errorf("created %v, a referenced table after the table referencing it", created)
+	}
+
+	for nm, schema := range schemas {
+		actual, err := spec.GetTableSchema(db, nm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(actual.ForeignKeys) != 1 || actual.ForeignKeys[0].ReferencedTable != schema.ForeignKeys[0].ReferencedTable {
+			if len(schema.ForeignKeys) > 0 {
+				t.Errorf("%s foreign keys = %+v", nm, actual.ForeignKeys)
+			}
+			continue
+		}
+		if actual.ForeignKeys[0].OnDelete != schema.ForeignKeys[0].OnDelete && schema.ForeignKeys[0].OnDelete != "" {
+			t.Errorf("%s ON DELETE = %q, want %q", nm, actual.ForeignKeys[0].OnDelete, schema.ForeignKeys[0].OnDelete)
+		}
+		if details := db.MetaStore().FindMetaTable(nm); details == nil || len(details.Schema.ForeignKeys) != len(schema.ForeignKeys) {
+			t.Errorf("%s metadata = %+v", nm, details)
+		}
+	}
+	diffs, err := spec.DiffTables(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("diffs after CreateTables = %+v", diffs)
+	}
+
+	exec(t, db,
+		"INSERT INTO users (id) VALUES (1)",
+		"INSERT INTO orders (id, user_id) VALUES (1, 1)",
+		"INSERT INTO items (id, order_id) VALUES (1, 1)",
+		"DELETE FROM users WHERE id = 1",
+	)
+	if n := count(t, db, "SELECT COUNT(*) FROM orders WHERE user_id IS NULL"); n != 1 {
+		t.Error("ON DELETE SET NULL was not applied")
+	}
+	exec(t, db, "DELETE FROM orders WHERE id = 1")
+	if n := count(t, db, "SELECT COUNT(*) FROM items"); n != 0 {
+		t.Error("ON DELETE CASCADE was not applied")
+	}
+	if _, err := db.DB().Exec("INSERT INTO people (id, team_id) VALUES (1, 42)"); err == nil {
+		t.Error("the deferred foreign key of people is not enforced")
+	}
+}
@@ -0,0 +1,379 @@
+package table
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sqldocify/configs"
+	"sqldocify/table/queries"
+	"strings"
+)
+
+// JoinSpec is one table joined by FetchJoined.
+type JoinSpec struct {
+	// Table is the joined table, Alias the name it is referred to by, the table name when empty.
+	Table string
+	Alias string
+	// Type is INNER when empty.
+	Type queries.JoinType
+	// On is inferred from the foreign keys in the metadata when nil: the foreign key between
+	// this table and To, or between this table and any table before it when To is empty.
+	On queries.Condition
+	To string
+	// Columns are the selected columns of the table, every column of its metadata when empty.
+	Columns []string
+}
+
+// JoinQuery describes a select from the bound table and the tables joined to it.
+type JoinQuery struct {
+	// Alias refers to the bound table, its name when empty.
+	Alias   string
+	Columns []string
+	Joins   []JoinSpec
+	// Where is a queries.Condition, a column/value map or a raw SQL string, like the condition
+	// of Fetch. Columns are qualified by alias, e.g. queries.Eq("u.status", "active").
+	Where   interface{}
+	OrderBy []queries.OrderBy
+	Limit   int
+	Offset  int
+}
+
+// joinedTable is a table of a join query with the alias its columns are read into.
+type joinedTable struct {
+	table   string
+	alias   string
+	columns []string
+}
+
+// joinedColumn is a selected column with the alias of its table.
+type joinedColumn struct {
+	alias  string
+	column string
+}
+
+// FetchJoined loads the rows of the bound table joined with query.Joins into result.
+//
+// result is a pointer to a struct, or to a slice of structs or struct pointers, with a field per
+// alias: a struct or struct pointer whose sqldoc tag, or snake cased name, is the alias. The
+// columns of the alias are scanned into that struct like in Fetch, a pointer stays nil when every
+// column is NULL, e.g. for an unmatched LEFT JOIN. Aliases without a field are skipped.
+// A *[]map[string]interface{} or *map[string]interface{} result has "alias.column" keys.
+func (t *TableSpec) FetchJoined(query JoinQuery, result interface{}, db *configs.Database) error {
+	if err := t.requireTable(); err != nil {
+		return err
+	}
+	where, err := t.buildCondition(query.Where)
+	if err != nil {
+		return err
+	}
+	tables, joins, err := t.joinPlan(db, query)
+	if err != nil {
+		return err
+	}
+	exec, err := t.executor(db)
+	if err != nil {
+		return err
+	}
+
+	var selected []joinedColumn
+	options := queries.SelectOptions{Where: where, OrderBy: query.OrderBy, Limit: query.Limit, Offset: query.Offset}
+	for _, table := range tables {
+		for _, column := range table.columns {
+			selected = append(selected, joinedColumn{alias: table.alias, column: column})
+			options.Columns = append(options.Columns, fmt.Sprintf("%s.%s AS %s__%s", table.alias, column, table.alias, column))
+		}
+	}
+	if singleResult(result) {
+		options.Limit = 1
+	}
+	selectQuery, args, err := t.QGType.GenerateJoinQuery(aliasedTable(tables[0]), joins, options)
+	if err != nil {
+		return err
+	}
+	rows, err := exec.Query(selectQuery, args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch from %s: %w", t.TableName, err)
+	}
+	defer rows.Close()
+
+	switch out := result.(type) {
+	case *[]map[string]interface{}:
+		records, err := scanJoinedMaps(rows, selected)
+		if err != nil {
+			return fmt.Errorf("failed to read rows of %s: %w", t.TableName, err)
+		}
+		*out = records
+	case *map[string]interface{}:
+		records, err := scanJoinedMaps(rows, selected)
+		if err != nil {
+			return fmt.Errorf("failed to read rows of %s: %w", t.TableName, err)
+		}
+		if len(records) == 0 {
+			return sql.ErrNoRows
+		}
+		*out = records[0]
+	default:
+		if err := scanJoinedInto(rows, selected, result); err != nil {
+			if err == sql.ErrNoRows {
+				return err
+			}
+			return fmt.Errorf("failed to read rows of %s: %w", t.TableName, err)
+		}
+	}
+	return nil
+}
+
+// joinPlan resolves the aliases, columns and ON conditions of a join query. The bound table
+// comes first.
+func (t *TableSpec) joinPlan(db *configs.Database, query JoinQuery) ([]joinedTable, []queries.Join, error) {
+	store := db.MetaStore()
+	tableColumns := func(table string, columns []string) ([]string, error) {
+		if len(columns) > 0 {
+			return columns, nil
+		}
+		details := store.FindMetaTable(table)
+		if details == nil {
+			return nil, fmt.Errorf("table %s not found in metadata, select its columns explicitly", table)
+		}
+		return details.Schema.ColumnNames(), nil
+	}
+
+	main := joinedTable{table: t.TableName, alias: query.Alias}
+	if main.alias == "" {
+		main.alias = t.TableName
+	}
+	columns, err := tableColumns(main.table, query.Columns)
+	if err != nil {
+		return nil, nil, err
+	}
+	main.columns = columns
+	tables := []joinedTable{main}
+
+	joins := make([]queries.Join, 0, len(query.Joins))
+	for _, spec := range query.Joins {
+		if spec.Table == "" {
+			return nil, nil, fmt.Errorf("join of %s without a table", t.TableName)
+		}
+		joined := joinedTable{table: spec.Table, alias: spec.Alias}
+		if joined.alias == "" {
+			joined.alias = spec.Table
+		}
+		for _, table := range tables {
+			if table.alias == joined.alias {
+				return nil, nil, fmt.Errorf("alias %s is used twice, give the joined table an Alias", joined.alias)
+			}
+		}
+		if joined.columns, err = tableColumns(spec.Table, spec.Columns); err != nil {
+			return nil, nil, err
+		}
+
+		on := spec.On
+		if on == nil && spec.Type != queries.CrossJoin {
+			if on, err = inferJoinCondition(store, tables, joined, spec.To); err != nil {
+				return nil, nil, err
+			}
+		}
+		joins = append(joins, queries.Join{Type: spec.Type, Table: aliasedTable(joined), On: on})
+		tables = append(tables, joined)
+	}
+	return tables, joins, nil
+}
+
+// inferJoinCondition finds the foreign key between joined and the earlier tables, in either
+// direction. It has to be the only one, otherwise the condition must be given.
+func inferJoinCondition(store configs.MetaStore, tables []joinedTable, joined joinedTable, to string) (queries.Condition, error) {
+	foreignKeys := func(table string) []configs.ForeignKeyDef {
+		if details := store.FindMetaTable(table); details != nil {
+			return details.Schema.ForeignKeys
+		}
+		return nil
+	}
+
+	var candidates []queries.Condition
+	var descriptions []string
+	for _, table := range tables {
+		if to != "" && table.alias != to {
+			continue
+		}
+		for _, foreignKey := range foreignKeys(joined.table) {
+			if foreignKey.ReferencedTable == table.table {
+				candidates = append(candidates, foreignKeyCondition(foreignKey, joined.alias, table.alias))
+				descriptions = append(descriptions, fmt.Sprintf("%s(%s)", joined.alias, strings.Join(foreignKey.Columns, ", ")))
+			}
+		}
+		if table.table == joined.table {
+			// A self reference was already found from the joined side.
+			continue
+		}
+		for _, foreignKey := range foreignKeys(table.table) {
+			if foreignKey.ReferencedTable == joined.table {
+				candidates = append(candidates, foreignKeyCondition(foreignKey, table.alias, joined.alias))
+				descriptions = append(descriptions, fmt.Sprintf("%s(%s)", table.alias, strings.Join(foreignKey.Columns, ", ")))
+			}
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		if to != "" {
+			return nil, fmt.Errorf("no foreign key between %s and %s in metadata, set the join condition", joined.alias, to)
+		}
+		return nil, fmt.Errorf("no foreign key to join %s on in metadata, set the join condition", joined.alias)
+	case 1:
+		return candidates[0], nil
+	}
+	return nil, fmt.Errorf("join of %s is ambiguous between the foreign keys %s, set To or the join condition", joined.alias, strings.Join(descriptions, ", "))
+}
+
+// foreignKeyCondition matches the columns of foreignKey in the table aliased from to the
+// referenced columns in the table aliased to.
+func foreignKeyCondition(foreignKey configs.ForeignKeyDef, from string, to string) queries.Condition {
+	conditions := make([]queries.Condition, 0, len(foreignKey.Columns))
+	for i, column := range foreignKey.Columns {
+		if i < len(foreignKey.ReferencedColumns) {
+			conditions = append(conditions, queries.ColumnEq(from+"."+column, to+"."+foreignKey.ReferencedColumns[i]))
+		}
+	}
+	return queries.And(conditions...)
+}
+
+// aliasedTable returns the table with its alias, as written in FROM and JOIN.
+func aliasedTable(table joinedTable) string {
+	if table.alias == table.table {
+		return table.table
+	}
+	return table.table + " " + table.alias
+}
+
+// scanJoinedMaps reads every row into a map keyed by "alias.column".
+func scanJoinedMaps(rows *sql.Rows, selected []joinedColumn) ([]map[string]interface{}, error) {
+	records, err := scanRowMaps(rows)
+	if err != nil {
+		return nil, err
+	}
+	for i, record := range records {
+		qualified := make(map[string]interface{}, len(record))
+		for _, column := range selected {
+			qualified[column.alias+"."+column.column] = record[column.alias+"__"+column.column]
+		}
+		records[i] = qualified
+	}
+	return records, nil
+}
+
+// scanJoinedInto reads the rows into dest like scanInto, splitting the columns of each row
+// into the nested structs of their aliases.
+func scanJoinedInto(rows *sql.Rows, selected []joinedColumn, dest interface{}) error {
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
+		return fmt.Errorf("fetch result must be a non-nil pointer, got %T", dest)
+	}
+	target := destValue.Elem()
+
+	switch {
+	case target.Kind() == reflect.Struct:
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return sql.ErrNoRows
+		}
+		return scanJoinedStruct(rows, selected, target)
+
+	case target.Kind() == reflect.Slice:
+		elemType := target.Type().Elem()
+		isPtr := elemType.Kind() == reflect.Ptr
+		structType := elemType
+		if isPtr {
+			structType = elemType.Elem()
+		}
+		if structType.Kind() != reflect.Struct {
+			return fmt.Errorf("unsupported fetch result type %T", dest)
+		}
+		items := reflect.MakeSlice(target.Type(), 0, 0)
+		for rows.Next() {
+			item := reflect.New(structType).Elem()
+			if err := scanJoinedStruct(rows, selected, item); err != nil {
+				return err
+			}
+			if isPtr {
+				item = item.Addr()
+			}
+			items = reflect.Append(items, item)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		target.Set(items)
+		return nil
+	}
+	return fmt.Errorf("unsupported fetch result type %T", dest)
+}
+
+// scanJoinedStruct scans the current row and assigns the columns of every alias to the fields of
+// the struct mapped to the alias.
+func scanJoinedStruct(rows *sql.Rows, selected []joinedColumn, target reflect.Value) error {
+	raw := make([]interface{}, len(selected))
+	pointers := make([]interface{}, len(selected))
+	for i := range raw {
+		pointers[i] = &raw[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return err
+	}
+
+	aliasIndex := make(map[string][]int)
+	for _, field := range structFields(target.Type()) {
+		aliasIndex[field.Column] = field.Index
+	}
+	for start := 0; start < len(selected); {
+		alias := selected[start].alias
+		end := start
+		allNull := true
+		for end < len(selected) && selected[end].alias == alias {
+			allNull = allNull && raw[end] == nil
+			end++
+		}
+		if index, ok := aliasIndex[alias]; ok {
+			if err := assignJoined(fieldByIndexAlloc(target, index), alias, selected[start:end], raw[start:end], allNull); err != nil {
+				return err
+			}
+		}
+		start = end
+	}
+	return nil
+}
+
+// assignJoined assigns the columns of one alias to field, a struct or struct pointer.
+func assignJoined(field reflect.Value, alias string, selected []joinedColumn, raw []interface{}, allNull bool) error {
+	structType := field.Type()
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct || structType == timeType {
+		return fmt.Errorf("field of alias %s must be a struct or struct pointer, got %s", alias, field.Type())
+	}
+	if field.Kind() == reflect.Ptr {
+		if allNull {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		field.Set(reflect.New(structType))
+		field = field.Elem()
+	}
+
+	fieldIndex := make(map[string][]int)
+	for _, nested := range structFields(structType) {
+		fieldIndex[nested.Column] = nested.Index
+	}
+	for i, column := range selected {
+		index, ok := fieldIndex[column.column]
+		if !ok {
+			continue
+		}
+		if err := assignValue(fieldByIndexAlloc(field, index), raw[i]); err != nil {
+			return fmt.Errorf("column %s.%s: %w", alias, column.column, err)
+		}
+	}
+	return nil
+}
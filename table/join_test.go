@@ -0,0 +1,175 @@
+package table_test
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"sqldocify/configs"
+	"sqldocify/table"
+	"sqldocify/table/queries"
+)
+
+type person struct {
+	ID        int64  `sqldoc:"id"`
+	Name      string `sqldoc:"name"`
+	ManagerID *int64 `sqldoc:"manager_id"`
+}
+
+type purchase struct {
+	ID     int64 `sqldoc:"id"`
+	UserID int64 `sqldoc:"user_id"`
+	Total  int64 `sqldoc:"total"`
+}
+
+// openShop creates people, managed by other people, and their purchases. Every foreign key is
+// in the metadata, so joins can be inferred.
+func openShop(t *testing.T) (*configs.Database, *table.TableSpec, *table.TableSpec) {
+	t.Helper()
+	db, spec := openMemoryDatabase(t)
+	id := column("id", "INTEGER", "NO", "PRI")
+	_, err := spec.CreateTables(db, map[string]configs.TableSchema{
+		"people": {
+			Columns:     []configs.ColumnDef{id, column("name", "TEXT", "NO", ""), column("manager_id", "INTEGER", "YES", "")},
+			ForeignKeys: []configs.ForeignKeyDef{{Columns: []string{"manager_id"}, ReferencedTable: "people", ReferencedColumns: []string{"id"}}},
+		},
+		"purchases": {
+			Columns:     []configs.ColumnDef{id, column("user_id", "INTEGER", "NO", ""), column("total", "INTEGER", "NO", "")},
+			ForeignKeys: []configs.ForeignKeyDef{{Columns: []string{"user_id"}, ReferencedTable: "people", ReferencedColumns: []string{"id"}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec(t, db,
+		"INSERT INTO people (id, name, manager_id) VALUES (1, 'ann', NULL), (2, 'bob', 1), (3, 'cy', 1)",
+		"INSERT INTO purchases (id, user_id, total) VALUES (1, 2, 10), (2, 2, 25), (3, 3, 7)",
+	)
+	people, err := table.NewTableSpec("people")
+	if err != nil {
+		t.Fatal(err)
+	}
+	purchases, err := table.NewTableSpec("purchases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, people, purchases
+}
+
+func TestFetchJoined(t *testing.T) {
+	db, people, purchases := openShop(t)
+
+	// The ON condition comes from the foreign key of purchases.
+	var rows []struct {
+		Purchase purchase `sqldoc:"p"`
+		Buyer    *person  `sqldoc:"u"`
+	}
+	err := purchases.FetchJoined(table.JoinQuery{
+		Alias:   "p",
+		Joins:   []table.JoinSpec{{Table: "people", Alias: "u"}},
+		Where:   queries.Gt("p.total", 8),
+		OrderBy: []queries.OrderBy{queries.Desc("p.total")},
+	}, &rows, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Purchase.Total != 25 || rows[1].Purchase.ID != 1 || rows[0].Buyer == nil || rows[0].Buyer.Name != "bob" {
+		t.Fatalf("rows = %+v", rows)
+	}
+
+	// Joined on a self reference, the joined alias holds the rows referencing the earlier table:
+	// the people reporting to person. To keeps purchases on person, a LEFT JOIN without a match
+	// leaves the pointer nil.
+	var team []*struct {
+		Person person
+		Report *person
+		Bought *purchase `sqldoc:"bought"`
+	}
+	err = people.FetchJoined(table.JoinQuery{
+		Alias: "person",
+		Joins: []table.JoinSpec{
+			{Table: "people", Alias: "report", Type: queries.LeftJoin},
+			{Table: "purchases", Alias: "bought", Type: queries.LeftJoin, To: "person"},
+		},
+		OrderBy: queries.OrderByColumns("person.id", "report.id", "bought.id"),
+	}, &team, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, member := range team {
+		entry := member.Person.Name
+		if member.Report != nil {
+			entry += ">" + member.Report.Name
+		}
+		if member.Bought != nil {
+			entry += "$" + strings.Repeat("x", int(member.Bought.ID))
+		}
+		got = append(got, entry)
+	}
+	if want := "ann>bob,ann>cy,bob$x,bob$xx,cy$xxx"; strings.Join(got, ",") != want {
+		t.Errorf("team = %s, want %s", strings.Join(got, ","), want)
+	}
+
+	// Maps are keyed by alias and column, a single struct reads the first row.
+	var records []map[string]interface{}
+	err = purchases.FetchJoined(table.JoinQuery{
+		Columns: []string{"total"},
+		Joins:   []table.JoinSpec{{Table: "people", Columns: []string{"name"}}},
+		OrderBy: queries.OrderByColumns("purchases.id"),
+	}, &records, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[2]["people.name"] != "cy" || records[2]["purchases.total"] != int64(7) {
+		t.Errorf("records = %v", records)
+	}
+	var first struct {
+		Purchases purchase
+		People    person
+	}
+	if err := purchases.FetchJoined(table.JoinQuery{Joins: []table.JoinSpec{{Table: "people"}}, Where: map[string]interface{}{"people.name": "cy"}}, &first, db); err != nil {
+		t.Fatal(err)
+	}
+	if first.Purchases.ID != 3 || first.People.ID != 3 {
+		t.Errorf("first = %+v", first)
+	}
+	if err := purchases.FetchJoined(table.JoinQuery{Joins: []table.JoinSpec{{Table: "people"}}, Where: map[string]interface{}{"people.name": "zed"}}, &first, db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("FetchJoined without a row = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestFetchJoinedErrors(t *testing.T) {
+	db, _, purchases := openShop(t)
+	var records []map[string]interface{}
+	tests := []struct {
+		name  string
+		query table.JoinQuery
+		want  string
+	}{
+		{"ambiguous", table.JoinQuery{Joins: []table.JoinSpec{{Table: "people", Alias: "u"}, {Table: "people", Alias: "v"}}}, "ambiguous"},
+		{"no foreign key to To", table.JoinQuery{Alias: "a", Joins: []table.JoinSpec{{Table: "people", Alias: "u"}, {Table: "purchases", Alias: "b", To: "a"}}}, "no foreign key between"},
+		{"alias used twice", table.JoinQuery{Joins: []table.JoinSpec{{Table: "purchases"}}}, "used twice"},
+		{"no foreign key", table.JoinQuery{Alias: "a", Joins: []table.JoinSpec{{Table: "purchases", Alias: "b"}}}, "no foreign key"},
+		{"unknown table", table.JoinQuery{Joins: []table.JoinSpec{{Table: "refunds"}}}, "not found in metadata"},
+		{"missing table", table.JoinQuery{Joins: []table.JoinSpec{{Alias: "x"}}}, "without a table"},
+	}
+	for _, tt := range tests {
+		if err := purchases.FetchJoined(tt.query, &records, db); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: FetchJoined = %v, want an error about %q", tt.name, err, tt.want)
+		}
+	}
+
+	// An explicit condition needs no foreign key.
+	err := purchases.FetchJoined(table.JoinQuery{
+		Alias: "a",
+		Joins: []table.JoinSpec{{Table: "purchases", Alias: "b", On: queries.And(queries.ColumnEq("a.user_id", "b.user_id"), queries.Raw("a.id < b.id"))}},
+	}, &records, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0]["a.id"] != int64(1) || records[0]["b.id"] != int64(2) {
+		t.Errorf("records = %v", records)
+	}
+}
@@ -0,0 +1,560 @@
+package table
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sqldocify/configs"
+	"sqldocify/table/queries"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrDestructiveMigration is returned by Migrate when a plan drops something or narrows a
+// column type and MigrateOptions.AllowDestructive is not set.
+var ErrDestructiveMigration = errors.New("migration has destructive steps")
+
+// MigrationStep is one statement of a migration plan.
+type MigrationStep struct {
+	Description string `json:"description"`
+	SQL         string `json:"sql"`
+	// Destructive steps drop a column, key, index or foreign key, or narrow a column type.
+	Destructive bool `json:"destructive"`
+	// rebuild holds the statements of a table rebuild, which Migrate runs on their own
+	// connection with foreign keys turned off instead of SQL.
+	rebuild []string
+}
+
+// MigrationPlan lists the statements that bring a table to a target schema, in the order
+// they run: foreign keys and indexes that go away are dropped first and new ones are added
+// once the columns are in place.
+type MigrationPlan struct {
+	Table string             `json:"table"`
+	Diff  configs.SchemaDiff `json:"diff"`
+	Steps []MigrationStep    `json:"steps"`
+}
+
+// Empty reports whether the table already matches the target schema.
+func (p MigrationPlan) Empty() bool {
+	return len(p.Steps) == 0
+}
+
+// DestructiveSteps returns the steps that need MigrateOptions.AllowDestructive.
+func (p MigrationPlan) DestructiveSteps() []MigrationStep {
+	var steps []MigrationStep
+	for _, step := range p.Steps {
+		if step.Destructive {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// SQL returns the statements of the plan, each preceded by a comment describing it.
+func (p MigrationPlan) SQL() string {
+	var sb strings.Builder
+	for _, step := range p.Steps {
+		sb.WriteString("-- " + step.Description)
+		if step.Destructive {
+			sb.WriteString(" (destructive)")
+		}
+		sb.WriteString("\n" + step.SQL + "\n")
+	}
+	return sb.String()
+}
+
+// MigrateOptions controls Migrate.
+type MigrateOptions struct {
+	// AllowDestructive runs plans with destructive steps, which are refused otherwise.
+	AllowDestructive bool
+	// DryRun only writes the SQL of the plan to Output, nothing is executed.
+	DryRun bool
+	// Output receives the SQL of a dry run, standard output when nil.
+	Output io.Writer
+}
+
+// PlanMigration compares a table in the database with a target schema and plans the ALTER
+// statements that bring the table to it. Nothing is executed.
+//
+// Dialects that cannot alter columns and constraints in place, SQLite, get a single step that
+// rebuilds the table with the target schema and copies the rows of the columns it keeps.
+func (t *TableSpec) PlanMigration(db *configs.Database, nm string, target configs.TableSchema) (MigrationPlan, error) {
+	if len(target.Columns) == 0 {
+		return MigrationPlan{}, fmt.Errorf("cannot migrate table %s to a schema without columns", nm)
+	}
+	actual, err := t.GetTableSchema(db, nm)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	diff := configs.Diff(t.QGType.NormalizeSchema(target), actual)
+	diff.Table = nm
+	plan := MigrationPlan{Table: nm, Diff: diff}
+	if diff.Empty() {
+		return plan, nil
+	}
+	if column := requiredColumn(diff); column != "" {
+		hasRows, err := t.hasRows(db, nm)
+		if err != nil {
+			return plan, err
+		}
+		if hasRows {
+			return plan, fmt.Errorf("cannot add column %s.%s to a table with rows: it is NOT NULL without a default, give it one", nm, column)
+		}
+	}
+
+	steps := t.alterSteps(db, nm, target, actual, diff)
+	if rebuilder, ok := t.QGType.(queries.TableRebuilder); ok && needsRebuild(diff) {
+		var copied []string
+		for _, column := range target.Columns {
+			if column.Generated == "" && actual.HasColumn(column.Name) {
+				copied = append(copied, column.Name)
+			}
+		}
+		rebuild := MigrationStep{
+			SQL:     rebuilder.GenerateRebuildTableQuery(nm, target, copied),
+			rebuild: rebuilder.GenerateRebuildTableStatements(nm, target, copied),
+		}
+		descriptions := make([]string, len(steps))
+		for i, step := range steps {
+			descriptions[i] = step.Description
+			rebuild.Destructive = rebuild.Destructive || step.Destructive
+		}
+		rebuild.Description = fmt.Sprintf("rebuild table %s to %s", nm, strings.Join(descriptions, ", "))
+		steps = []MigrationStep{rebuild}
+	}
+	plan.Steps = steps
+	return plan, nil
+}
+
+// Migrate plans the migration of a table to a target schema and runs it. Destructive plans
+// are refused with ErrDestructiveMigration unless options allow them, a dry run only writes
+// the SQL. Once every step succeeded the target schema is stored in the metadata.
+// The plan is returned in every case, a failing step stops the migration.
+func (t *TableSpec) Migrate(db *configs.Database, nm string, target configs.TableSchema, options MigrateOptions) (MigrationPlan, error) {
+	plan, err := t.PlanMigration(db, nm, target)
+	if err != nil {
+		return plan, err
+	}
+	if options.DryRun {
+		output := options.Output
+		if output == nil {
+			output = os.Stdout
+		}
+		_, err := io.WriteString(output, plan.SQL())
+		return plan, err
+	}
+	if destructive := plan.DestructiveSteps(); len(destructive) > 0 && !options.AllowDestructive {
+		descriptions := make([]string, len(destructive))
+		for i, step := range destructive {
+			descriptions[i] = step.Description
+		}
+		return plan, fmt.Errorf("%w for table %s: %s", ErrDestructiveMigration, nm, strings.Join(descriptions, "; "))
+	}
+
+	if err := t.runMigration(db, plan); err != nil {
+		return plan, err
+	}
+
+	metaTables := db.MetaStore()
+	details := configs.MetaTableDetails{Details: "Details"}
+	if existing := metaTables.FindMetaTable(nm); existing != nil {
+		details = *existing
+	}
+	details.Schema = target
+	details.Timestamp = time.Now().String()
+	if err := metaTables.UpdateMetaTable(nm, details); err != nil {
+		return plan, fmt.Errorf("table %s migrated but its metadata was not stored: %w", nm, err)
+	}
+	return plan, nil
+}
+
+// requiredColumn returns the first added column that is NOT NULL without a default, which the
+// existing rows of a table have no value for.
+func requiredColumn(diff configs.SchemaDiff) string {
+	for _, change := range diff.Columns {
+		if change.Kind != configs.ChangeAdded {
+			continue
+		}
+		fieldSchema := change.Expected
+		if fieldSchema.Null == "NO" && fieldSchema.Default == nil && fieldSchema.Generated == "" &&
+			!strings.Contains(strings.ToLower(fieldSchema.Extra), "auto_increment") {
+			return change.Name
+		}
+	}
+	return ""
+}
+
+// hasRows reports whether a table holds at least one row.
+func (t *TableSpec) hasRows(db *configs.Database, nm string) (bool, error) {
+	exec, err := t.executor(db)
+	if err != nil {
+		return false, err
+	}
+	query, args, err := t.QGType.GenerateExistsQuery(nm, queries.SelectOptions{})
+	if err != nil {
+		return false, err
+	}
+	var exists bool
+	if err := exec.QueryRow(query, args...).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check table %s for rows: %w", nm, err)
+	}
+	return exists, nil
+}
+
+// runMigration runs the steps of a plan. On dialects with transactional DDL they run in one
+// transaction, so a failing step leaves the table as it was; MySQL commits every step.
+func (t *TableSpec) runMigration(db *configs.Database, plan MigrationPlan) error {
+	if len(plan.Steps) == 1 && plan.Steps[0].rebuild != nil {
+		return t.rebuildTable(db, plan.Steps[0])
+	}
+	run := func(exec executor) error {
+		for _, step := range plan.Steps {
+			if _, err := exec.Exec(step.SQL); err != nil {
+				return fmt.Errorf("failed to %s: %w", step.Description, err)
+			}
+		}
+		return nil
+	}
+	if transactional, ok := t.QGType.(queries.TransactionalDDL); ok && transactional.TransactionalDDL() {
+		return t.inTransaction(db, run)
+	}
+	exec, err := t.executor(db)
+	if err != nil {
+		return err
+	}
+	return run(exec)
+}
+
+// rebuildTable runs the statements of a rebuild step in a transaction of a dedicated connection.
+// SQLite only turns foreign keys off outside of a transaction, and has to while the old table is
+// dropped, so they are turned off around the transaction, checked before committing and turned
+// back on whatever happens. A connection whose foreign keys could not be turned back on is
+// closed rather than returned to the pool.
+func (t *TableSpec) rebuildTable(db *configs.Database, step MigrationStep) (err error) {
+	if t.tx != nil {
+		return fmt.Errorf("cannot %s inside a transaction, foreign keys cannot be turned off in one", step.Description)
+	}
+	if db == nil || db.DB() == nil {
+		return errors.New("no active database connection")
+	}
+	ctx := context.Background()
+	conn, err := db.DB().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	discard := func() {
+		conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	}
+
+	var foreignKeys bool
+	if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		return fmt.Errorf("failed to read the foreign key setting: %w", err)
+	}
+	if foreignKeys {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return fmt.Errorf("failed to turn foreign keys off: %w", err)
+		}
+		defer func() {
+			if _, restoreErr := conn.ExecContext(ctx, "PRAGMA foreign_keys = ON"); restoreErr != nil {
+				discard()
+				err = errors.Join(err, fmt.Errorf("failed to turn foreign keys back on: %w", restoreErr))
+			}
+		}()
+	}
+
+	tx, err := conn.BeginTx(ctx, db.TxOptions)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	rollback := func(err error) error {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			discard()
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	for _, statement := range step.rebuild {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return rollback(fmt.Errorf("failed to %s: %w", step.Description, err))
+		}
+	}
+	if foreignKeys {
+		if err := foreignKeyViolation(ctx, tx); err != nil {
+			return rollback(fmt.Errorf("failed to %s: %w", step.Description, err))
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to %s: %w", step.Description, err)
+	}
+	return nil
+}
+
+// foreignKeyViolation returns an error naming the first row that violates a foreign key.
+func foreignKeyViolation(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return rows.Err()
+	}
+	var table, parent string
+	var rowid sql.NullInt64
+	var id int
+	if err := rows.Scan(&table, &rowid, &parent, &id); err != nil {
+		return err
+	}
+	return fmt.Errorf("row %d of %s violates a foreign key to %s", rowid.Int64, table, parent)
+}
+
+// alterSteps turns a diff into ALTER statements. target holds the declared definitions, the
+// diff compares them in the form the database reports.
+func (t *TableSpec) alterSteps(db *configs.Database, nm string, target configs.TableSchema, actual configs.TableSchema, diff configs.SchemaDiff) []MigrationStep {
+	var drops, columns, adds []MigrationStep
+	qg := t.generator(db)
+
+	for _, change := range diff.ForeignKeys {
+		if change.Actual != nil {
+			drops = append(drops, MigrationStep{
+				Description: fmt.Sprintf("drop foreign key on %s(%s)", nm, queries.FormatColumns(change.Actual.Columns)),
+				SQL:         qg.GenerateDropForeignKeyQuery(nm, change.Actual.Name),
+				Destructive: change.Kind == configs.ChangeRemoved,
+			})
+		}
+		if change.Expected != nil {
+			adds = append(adds, MigrationStep{
+				Description: fmt.Sprintf("add foreign key on %s(%s) to %s", nm, queries.FormatColumns(change.Expected.Columns), change.Expected.ReferencedTable),
+				SQL:         qg.GenerateAddForeignKeyConstraintQuery(nm, *change.Expected),
+			})
+		}
+	}
+	for _, change := range diff.Indexes {
+		if change.Actual != nil {
+			drops = append(drops, MigrationStep{
+				Description: fmt.Sprintf("drop index on %s(%s)", nm, queries.FormatColumns(change.Actual.Columns)),
+				SQL:         qg.GenerateDropTableIndexQuery(nm, *change.Actual),
+				Destructive: change.Kind == configs.ChangeRemoved,
+			})
+		}
+		if change.Expected != nil {
+			adds = append(adds, MigrationStep{
+				Description: fmt.Sprintf("add index on %s(%s)", nm, queries.FormatColumns(change.Expected.Columns)),
+				SQL:         qg.GenerateAddIndexQuery(nm, *change.Expected),
+			})
+		}
+	}
+	for _, change := range diff.Uniques {
+		if change.Actual != nil {
+			drops = append(drops, uniqueStep(qg, nm, *change.Actual, false))
+		}
+		if change.Expected != nil {
+			adds = append(adds, uniqueStep(qg, nm, *change.Expected, true))
+		}
+	}
+
+	var added, removed []MigrationStep
+	for _, change := range diff.Columns {
+		switch change.Kind {
+		case configs.ChangeAdded:
+			fieldSchema, _ := target.Column(change.Name)
+			if fieldSchema.Key == "PRI" {
+				fieldSchema.Key = ""
+			}
+			added = append(added, MigrationStep{
+				Description: fmt.Sprintf("add column %s.%s", nm, change.Name),
+				SQL:         qg.GenerateAddColumnDefinitionQuery(nm, configs.ColumnDef{Name: change.Name, FieldSchema: fieldSchema}),
+			})
+		case configs.ChangeRemoved:
+			removed = append(removed, MigrationStep{
+				Description: fmt.Sprintf("drop column %s.%s", nm, change.Name),
+				SQL:         qg.GenerateDropColumnQuery(nm, change.Name),
+				Destructive: true,
+			})
+		case configs.ChangeModified:
+			var attributes []string
+			for _, attribute := range change.Attributes {
+				if attribute == "Key" {
+					continue
+				}
+				attributes = append(attributes, attribute)
+			}
+			if contains(change.Attributes, "Key") {
+				unique := configs.IndexDef{Columns: []string{change.Name}}
+				switch {
+				case change.Expected.Key == "UNI" && change.Actual.Key != "UNI":
+					adds = append(adds, uniqueStep(qg, nm, unique, true))
+				case change.Actual.Key == "UNI" && change.Expected.Key != "UNI":
+					drops = append(drops, uniqueStep(qg, nm, unique, false))
+				}
+			}
+			if len(attributes) == 0 {
+				continue
+			}
+			fieldSchema, _ := target.Column(change.Name)
+			columns = append(columns, MigrationStep{
+				Description: fmt.Sprintf("modify column %s.%s (%s)", nm, change.Name, strings.Join(attributes, ", ")),
+				SQL:         qg.GenerateAlterColumnQuery(nm, configs.ColumnDef{Name: change.Name, FieldSchema: fieldSchema}),
+				Destructive: narrowsColumn(*change.Actual, *change.Expected),
+			})
+		}
+	}
+
+	steps := append(drops, added...)
+	steps = append(steps, columns...)
+	if diff.PrimaryKey != nil {
+		steps = append(steps, MigrationStep{
+			Description: fmt.Sprintf("change primary key of %s to (%s)", nm, queries.FormatColumns(diff.PrimaryKey.Expected)),
+			SQL:         qg.GenerateAlterPrimaryKeyQuery(nm, actual.PrimaryKeyColumns(), target.PrimaryKeyColumns()),
+			Destructive: len(diff.PrimaryKey.Actual) > 0,
+		})
+	}
+	steps = append(steps, removed...)
+	return append(steps, adds...)
+}
+
+func uniqueStep(qg queries.QueryGenerator, nm string, unique configs.IndexDef, add bool) MigrationStep {
+	if add {
+		return MigrationStep{
+			Description: fmt.Sprintf("add unique constraint on %s(%s)", nm, queries.FormatColumns(unique.Columns)),
+			SQL:         qg.GenerateAddUniqueQuery(nm, unique),
+		}
+	}
+	return MigrationStep{
+		Description: fmt.Sprintf("drop unique constraint on %s(%s)", nm, queries.FormatColumns(unique.Columns)),
+		SQL:         qg.GenerateDropUniqueQuery(nm, unique),
+		Destructive: true,
+	}
+}
+
+// needsRebuild reports whether a diff holds more than indexes and plain new columns, which is
+// all SQLite can change without rebuilding the table.
+func needsRebuild(diff configs.SchemaDiff) bool {
+	if diff.PrimaryKey != nil || len(diff.Uniques) > 0 || len(diff.ForeignKeys) > 0 {
+		return true
+	}
+	for _, change := range diff.Columns {
+		if change.Kind != configs.ChangeAdded {
+			return true
+		}
+		fieldSchema := change.Expected
+		if fieldSchema.Key != "" || fieldSchema.Generated != "" || (fieldSchema.Null == "NO" && fieldSchema.Default == nil) {
+			return true
+		}
+		if fieldSchema.Default != nil && !constantDefault(*fieldSchema.Default) {
+			return true
+		}
+	}
+	return false
+}
+
+var numericLiteral = regexp.MustCompile(`^[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?$`)
+
+// constantDefault reports whether a default is a literal, ADD COLUMN rejects expressions in SQLite.
+func constantDefault(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, "'") || strings.EqualFold(value, "NULL") || numericLiteral.MatchString(value)
+}
+
+// integerRanks orders the integer types by size.
+var integerRanks = map[string]int{"tinyint": 1, "smallint": 2, "mediumint": 3, "int": 4, "integer": 4, "bigint": 5}
+
+// textSizes holds the maximum length of the text and blob types.
+var textSizes = map[string]int64{
+	"tinytext": 255, "text": 65535, "mediumtext": 16777215, "longtext": 4294967295,
+	"tinyblob": 255, "blob": 65535, "mediumblob": 16777215, "longblob": 4294967295,
+}
+
+var columnTypePattern = regexp.MustCompile(`(?i)^([a-z ]+?)\s*(?:\((.*)\))?$`)
+
+// narrowsColumn reports whether changing a column from one definition to another can lose
+// values: a smaller integer, string or decimal, a signed column becoming unsigned, an enum
+// losing values or a change between unrelated types. Type names are compared in any case,
+// enum and set values as they are.
+func narrowsColumn(from configs.FieldSchema, to configs.FieldSchema) bool {
+	fromType, fromArgs := splitColumnType(from.Type)
+	toType, toArgs := splitColumnType(to.Type)
+	if fromType == toType && fromArgs == toArgs {
+		return !from.Unsigned && to.Unsigned
+	}
+
+	fromRank, fromInteger := integerRanks[fromType]
+	toRank, toInteger := integerRanks[toType]
+	switch {
+	case fromType == "tinyint" && fromArgs == "1" && toInteger:
+		return !from.Unsigned && to.Unsigned
+	case fromInteger && toInteger:
+		return toRank < fromRank || (toRank == fromRank && from.Unsigned != to.Unsigned) || (!from.Unsigned && to.Unsigned)
+	case fromInteger && (toType == "decimal" || toType == "numeric"):
+		precision, scale := decimalArgs(toArgs)
+		return precision-scale < []int{0, 3, 5, 8, 10, 20}[fromRank]
+	case fromInteger && toType == "double":
+		return fromRank == 5
+	case fromType == "float" && toType == "double":
+		return false
+	case (fromType == "decimal" || fromType == "numeric") && (toType == "decimal" || toType == "numeric"):
+		fromPrecision, fromScale := decimalArgs(fromArgs)
+		toPrecision, toScale := decimalArgs(toArgs)
+		return toScale < fromScale || toPrecision-toScale < fromPrecision-fromScale
+	case fromType == "enum" && toType == "enum", fromType == "set" && toType == "set":
+		values := strings.Split(toArgs, ",")
+		for _, value := range strings.Split(fromArgs, ",") {
+			if !contains(values, value) {
+				return true
+			}
+		}
+		return false
+	case fromType == "date" && (toType == "datetime" || toType == "timestamp"):
+		return false
+	case fromType == "timestamp" && toType == "datetime":
+		return false
+	}
+	if fromSize, ok := stringSize(fromType, fromArgs); ok {
+		toSize, ok := stringSize(toType, toArgs)
+		return !ok || toSize < fromSize
+	}
+	return true
+}
+
+// splitColumnType splits a type into its lower case name and the arguments in parentheses.
+func splitColumnType(columnType string) (string, string) {
+	match := columnTypePattern.FindStringSubmatch(strings.TrimSpace(columnType))
+	if match == nil {
+		return strings.ToLower(columnType), ""
+	}
+	return strings.ToLower(strings.TrimSpace(match[1])), strings.ReplaceAll(match[2], " ", "")
+}
+
+func decimalArgs(args string) (int, int) {
+	precisionArg, scaleArg, _ := strings.Cut(args, ",")
+	precision, err := strconv.Atoi(precisionArg)
+	if err != nil {
+		precision = 10
+	}
+	scale, _ := strconv.Atoi(scaleArg)
+	return precision, scale
+}
+
+// stringSize returns the maximum length of a character, text, binary or blob type.
+func stringSize(columnType string, args string) (int64, bool) {
+	if size, ok := textSizes[columnType]; ok {
+		return size, true
+	}
+	switch columnType {
+	case "char", "varchar", "binary", "varbinary":
+		size, err := strconv.ParseInt(args, 10, 64)
+		if err != nil {
+			// An unbounded varchar, which PostgreSQL allows.
+			return textSizes["longtext"], true
+		}
+		return size, true
+	}
+	return 0, false
+}
@@ -0,0 +1,36 @@
+package table
+
+import (
+	"testing"
+
+	"sqldocify/configs"
+)
+
+func TestNarrowsColumn(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"varchar(20)", "varchar(40)", false},
+		{"varchar(40)", "varchar(20)", true},
+		{"VARCHAR(20)", "VARCHAR(40)", false},
+		{"VARCHAR(40)", "varchar(20)", true},
+		{"Varchar(20)", "TEXT", false},
+		{"TEXT", "VARCHAR(20)", true},
+		{"INT", "BIGINT", false},
+		{"BIGINT", "int", true},
+		{"INT(11)", "INT", false},
+		{"DECIMAL(8,2)", "DECIMAL(10,2)", false},
+		{"DECIMAL(8, 2)", "decimal(8,1)", true},
+		{"ENUM('a','b')", "enum('a','b','c')", false},
+		{"ENUM('a','b')", "ENUM('a')", true},
+		{"DATE", "DATETIME", false},
+	}
+	for _, tt := range tests {
+		from := configs.FieldSchema{Type: tt.from}
+		to := configs.FieldSchema{Type: tt.to}
+		if got := narrowsColumn(from, to); got != tt.want {
+			t.Errorf("narrowsColumn(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
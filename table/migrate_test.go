@@ -0,0 +1,274 @@
+package table_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"sqldocify/configs"
+	"sqldocify/servers"
+	"sqldocify/table"
+)
+
+func column(name string, fieldType string, null string, key string) configs.ColumnDef {
+	return configs.ColumnDef{Name: name, FieldSchema: configs.FieldSchema{Type: fieldType, Null: null, Key: key}}
+}
+
+func withDefault(c configs.ColumnDef, value string) configs.ColumnDef {
+	c.Default = &value
+	return c
+}
+
+// openMemoryDatabase opens an in-memory SQLite database with its metadata kept in memory.
+func openMemoryDatabase(t *testing.T) (*configs.Database, *table.TableSpec) {
+	t.Helper()
+	db, err := servers.NewDatabase("sqlite", ":memory:", servers.Options{MetaStore: configs.NewMemoryMetaStore()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	spec, err := table.AddSelectedDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, spec
+}
+
+func exec(t *testing.T, db *configs.Database, statements ...string) {
+	t.Helper()
+	for _, statement := range statements {
+		if _, err := db.DB().Exec(statement); err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+}
+
+func count(t *testing.T, db *configs.Database, query string) int {
+	t.Helper()
+	var n int
+	if err := db.DB().QueryRow(query).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+func TestMigrateSQLiteInMemory(t *testing.T) {
+	db, spec := openMemoryDatabase(t)
+
+	users := configs.NewTableSchema(
+		column("id", "INTEGER", "NO", "PRI"),
+		column("email", "VARCHAR(255)", "NO", "UNI"),
+		withDefault(column("status", "VARCHAR(20)", "YES", ""), "'active'"),
+	)
+	orders := configs.TableSchema{
+		Columns: []configs.ColumnDef{column("id", "INTEGER", "NO", "PRI"), column("user_id", "INTEGER", "NO", "")},
+		ForeignKeys: []configs.ForeignKeyDef{
+			{Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}, OnDelete: "CASCADE"},
+		},
+	}
+	if err := spec.CreateTable(db, "users", users); err != nil {
+		t.Fatal(err)
+	}
+	if err := spec.CreateTable(db, "orders", orders); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := spec.GetTableSchema(db, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(schema.ColumnNames(), ","); got != "id,email,status" {
+		t.Errorf("columns = %s", got)
+	}
+	if email, _ := schema.Column("email"); email.Key != "UNI" || email.Null != "NO" {
+		t.Errorf("email = %+v", email)
+	}
+	schema, err = spec.GetTableSchema(db, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schema.ForeignKeys) != 1 || schema.ForeignKeys[0].ReferencedTable != "users" {
+		t.Errorf("foreign keys = %+v", schema.ForeignKeys)
+	}
+	for _, nm := range []string{"users", "orders"} {
+		if diff, err := spec.DiffTable(db, nm); err != nil || !diff.Empty() {
+			t.Errorf("DiffTable(%s) = %+v, %v", nm, diff, err)
+		}
+	}
+
+	exec(t, db,
+		"INSERT INTO users (id, email) VALUES (1, 'a@example.com'), (2, 'b@example.com')",
+		"INSERT INTO orders (id, user_id) VALUES (10, 1), (11, 2)",
+	)
+
+	t.Run("required column", func(t *testing.T) {
+		target := users.Copy()
+		target.Columns = append(target.Columns, column("age", "INTEGER", "NO", ""))
+		if _, err := spec.PlanMigration(db, "users", target); err == nil || !strings.Contains(err.Error(), "NOT NULL without a default") {
+			t.Errorf("PlanMigration = %v, want the required column rejected", err)
+		}
+		target.Columns[len(target.Columns)-1] = withDefault(column("age", "INTEGER", "NO", ""), "0")
+		if _, err := spec.PlanMigration(db, "users", target); err != nil {
+			t.Errorf("PlanMigration with a default: %v", err)
+		}
+	})
+
+	t.Run("add column", func(t *testing.T) {
+		target := users.Copy()
+		target.Columns = append(target.Columns, column("nickname", "TEXT", "YES", ""))
+		var out bytes.Buffer
+		plan, err := spec.Migrate(db, "users", target, table.MigrateOptions{DryRun: true, Output: &out})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(plan.Steps) != 1 || plan.Steps[0].Destructive || !strings.Contains(out.String(), "ADD COLUMN") {
+			t.Fatalf("plan = %+v\n%s", plan, out.String())
+		}
+		if diff, _ := spec.DiffTable(db, "users"); !diff.Empty() {
+			t.Fatalf("a dry run changed the table: %+v", diff)
+		}
+
+		if _, err := spec.Migrate(db, "users", target, table.MigrateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		users = target
+		if diff, err := spec.DiffTable(db, "users"); err != nil || !diff.Empty() {
+			t.Errorf("DiffTable after Migrate = %+v, %v", diff, err)
+		}
+		if plan, err := spec.PlanMigration(db, "users", target); err != nil || !plan.Empty() {
+			t.Errorf("PlanMigration after Migrate = %+v, %v", plan, err)
+		}
+	})
+
+	t.Run("rebuild", func(t *testing.T) {
+		target := configs.NewTableSchema(
+			column("id", "INTEGER", "NO", "PRI"),
+			column("email", "TEXT", "NO", "UNI"),
+			withDefault(column("status", "VARCHAR(20)", "NO", ""), "'active'"),
+		)
+		plan, err := spec.Migrate(db, "users", target, table.MigrateOptions{})
+		if !errors.Is(err, table.ErrDestructiveMigration) {
+			t.Fatalf("Migrate = %v, want ErrDestructiveMigration", err)
+		}
+		if len(plan.Steps) != 1 || !strings.HasPrefix(plan.Steps[0].Description, "rebuild table users") {
+			t.Fatalf("plan = %+v", plan)
+		}
+
+		if _, err := spec.Migrate(db, "users", target, table.MigrateOptions{AllowDestructive: true}); err != nil {
+			t.Fatal(err)
+		}
+		if diff, err := spec.DiffTable(db, "users"); err != nil || !diff.Empty() {
+			t.Errorf("DiffTable after the rebuild = %+v, %v", diff, err)
+		}
+		schema, err := spec.GetTableSchema(db, "users")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(schema.ColumnNames(), ","); got != "id,email,status" {
+			t.Errorf("columns = %s", got)
+		}
+		if n := count(t, db, "SELECT COUNT(*) FROM users WHERE status = 'active'"); n != 2 {
+			t.Errorf("%d users kept", n)
+		}
+		if n := count(t, db, "SELECT COUNT(*) FROM orders"); n != 2 {
+			t.Errorf("%d orders kept", n)
+		}
+		if n := count(t, db, "PRAGMA foreign_keys"); n != 1 {
+			t.Errorf("foreign_keys = %d after the rebuild", n)
+		}
+		exec(t, db, "DELETE FROM users WHERE id = 1")
+		if n := count(t, db, "SELECT COUNT(*) FROM orders"); n != 1 {
+			t.Errorf("the foreign key of orders no longer cascades, %d orders left", n)
+		}
+	})
+
+	t.Run("failed rebuild", func(t *testing.T) {
+		exec(t, db, "INSERT INTO users (id, email) VALUES (3, 'b@EXAMPLE.com')")
+		target := configs.NewTableSchema(
+			column("id", "INTEGER", "NO", "PRI"),
+			configs.ColumnDef{Name: "email", FieldSchema: configs.FieldSchema{Type: "TEXT", Null: "NO", Key: "UNI", Collation: "NOCASE"}},
+			withDefault(column("status", "VARCHAR(20)", "NO", ""), "'active'"),
+		)
+		if _, err := spec.Migrate(db, "users", target, table.MigrateOptions{AllowDestructive: true}); err == nil {
+			t.Fatal("rebuilding with duplicate emails succeeded")
+		}
+		if diff, err := spec.DiffTable(db, "users"); err != nil || !diff.Empty() {
+			t.Errorf("a failed rebuild changed the table: %+v, %v", diff, err)
+		}
+		if n := count(t, db, "SELECT COUNT(*) FROM users"); n != 2 {
+			t.Errorf("%d users after a failed rebuild", n)
+		}
+		if n := count(t, db, "PRAGMA foreign_keys"); n != 1 {
+			t.Errorf("foreign_keys = %d after a failed rebuild", n)
+		}
+	})
+}
+
+func TestReservedWordColumns(t *testing.T) {
+	db, spec := openMemoryDatabase(t)
+
+	schema := configs.NewTableSchema(
+		column("id", "INTEGER", "NO", "PRI"),
+		column("order", "INTEGER", "NO", ""),
+		column("userId", "INTEGER", "YES", ""),
+	)
+	schema.Indexes = []configs.IndexDef{{Columns: []string{"order"}}}
+	if err := spec.CreateTable(db, "group", schema); err != nil {
+		t.Fatal(err)
+	}
+	if diff, err := spec.DiffTable(db, "group"); err != nil || !diff.Empty() {
+		t.Errorf("DiffTable = %+v, %v", diff, err)
+	}
+
+	groups, err := table.NewTableSpec("group")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := groups.Insert(map[string]interface{}{"id": 1, "order": 5, "userId": 7}, db); err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := groups.Update(map[string]interface{}{"id": 1, "order": 6, "userId": 7}, nil, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 || diffs[0].FieldName != "order" {
+		t.Errorf("diffs = %+v", diffs)
+	}
+	var row map[string]interface{}
+	if err := groups.Fetch(map[string]interface{}{"userId": 7}, &row, db); err != nil {
+		t.Fatal(err)
+	}
+	if row["order"] != int64(6) {
+		t.Errorf("row = %v", row)
+	}
+	if err := groups.Delete(map[string]interface{}{"order": 6}, db); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, db, `SELECT COUNT(*) FROM "group"`); n != 0 {
+		t.Errorf("%d rows left", n)
+	}
+}
+
+func TestColumnOrder(t *testing.T) {
+	db, spec := openMemoryDatabase(t)
+	schema := configs.NewTableSchema(
+		column("zeta", "INTEGER", "NO", "PRI"),
+		column("alpha", "TEXT", "YES", ""),
+		column("mid", "TEXT", "YES", ""),
+	)
+	if err := spec.CreateTable(db, "ordered", schema); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := spec.GetTableSchema(db, "ordered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(actual.ColumnNames(), ","); got != "zeta,alpha,mid" {
+		t.Errorf("database columns = %s", got)
+	}
+	stored := db.MetaStore().FindMetaTable("ordered")
+	if stored == nil || strings.Join(stored.Schema.ColumnNames(), ",") != "zeta,alpha,mid" {
+		t.Errorf("stored schema = %+v", stored)
+	}
+}
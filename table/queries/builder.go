@@ -0,0 +1,451 @@
+package queries
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Builder starts queries that render through the escaping and bind parameter markers of one
+// dialect. Conditions are written with "?" markers, Build numbers them for the dialect and
+// returns the arguments in the order of their markers.
+//
+//	query, args, err := queries.NewBuilder(qg).Select("id", "name").From("users").
+//		Where("age > ?", 18).OrderBy("name DESC").Limit(10).Build()
+//
+// Plain and qualified names like users.id are escaped, anything else such as COUNT(*) is
+// written as given.
+type Builder struct {
+	qg QueryGenerator
+}
+
+// NewBuilder returns a builder rendering for qg.
+func NewBuilder(qg QueryGenerator) *Builder {
+	return &Builder{qg: qg}
+}
+
+// Select starts a SELECT of columns, all columns when none are given.
+func (b *Builder) Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{qg: b.qg, columns: columns}
+}
+
+// Insert starts an INSERT into table.
+func (b *Builder) Insert(table string) *InsertBuilder {
+	return &InsertBuilder{qg: b.qg, table: table}
+}
+
+// Update starts an UPDATE of table.
+func (b *Builder) Update(table string) *UpdateBuilder {
+	return &UpdateBuilder{qg: b.qg, table: table}
+}
+
+// Delete starts a DELETE from table.
+func (b *Builder) Delete(table string) *DeleteBuilder {
+	return &DeleteBuilder{qg: b.qg, table: table}
+}
+
+// fragment is a piece of SQL with "?" markers and their arguments.
+type fragment struct {
+	sql  string
+	args []interface{}
+}
+
+type joinClause struct {
+	kind  string
+	table string
+	on    fragment
+}
+
+// SelectBuilder builds a SELECT, every clause is optional.
+type SelectBuilder struct {
+	qg       QueryGenerator
+	err      error
+	with     []CTE
+	distinct bool
+	columns  []string
+	from     string
+	joins    []joinClause
+	where    []Condition
+	groupBy  []string
+	having   []Condition
+	unions   []Union
+	orderBy  []string
+	limit    int
+	offset   int
+}
+
+// Distinct selects distinct rows only.
+func (s *SelectBuilder) Distinct() *SelectBuilder {
+	s.distinct = true
+	return s
+}
+
+// From sets the table, "users u" or "users AS u" gives it an alias.
+func (s *SelectBuilder) From(table string) *SelectBuilder {
+	s.from = table
+	return s
+}
+
+// Join adds an INNER JOIN.
+func (s *SelectBuilder) Join(table string, on string, args ...interface{}) *SelectBuilder {
+	return s.join("INNER JOIN", table, on, args)
+}
+
+// LeftJoin adds a LEFT JOIN.
+func (s *SelectBuilder) LeftJoin(table string, on string, args ...interface{}) *SelectBuilder {
+	return s.join("LEFT JOIN", table, on, args)
+}
+
+// RightJoin adds a RIGHT JOIN, which SQLite supports from 3.39 on.
+func (s *SelectBuilder) RightJoin(table string, on string, args ...interface{}) *SelectBuilder {
+	return s.join("RIGHT JOIN", table, on, args)
+}
+
+// CrossJoin adds a CROSS JOIN.
+func (s *SelectBuilder) CrossJoin(table string) *SelectBuilder {
+	return s.join("CROSS JOIN", table, "", nil)
+}
+
+func (s *SelectBuilder) join(kind string, table string, on string, args []interface{}) *SelectBuilder {
+	s.joins = append(s.joins, joinClause{kind: kind, table: table, on: fragment{sql: on, args: args}})
+	return s
+}
+
+// Where adds a condition, several conditions are joined with AND. condition is a Condition, a
+// map of column = value pairs or a SQL string with "?" markers for args; anything else is
+// reported by Err.
+func (s *SelectBuilder) Where(condition interface{}, args ...interface{}) *SelectBuilder {
+	s.where = addCondition(s.where, &s.err, condition, args)
+	return s
+}
+
+// GroupBy adds grouping columns.
+func (s *SelectBuilder) GroupBy(columns ...string) *SelectBuilder {
+	s.groupBy = append(s.groupBy, columns...)
+	return s
+}
+
+// Having adds a condition on the groups like Where, several conditions are joined with AND.
+func (s *SelectBuilder) Having(condition interface{}, args ...interface{}) *SelectBuilder {
+	s.having = addCondition(s.having, &s.err, condition, args)
+	return s
+}
+
+// OrderBy adds sort columns, each optionally followed by ASC or DESC.
+func (s *SelectBuilder) OrderBy(columns ...string) *SelectBuilder {
+	s.orderBy = append(s.orderBy, columns...)
+	return s
+}
+
+// Limit caps the number of rows, 0 removes the cap.
+func (s *SelectBuilder) Limit(limit int) *SelectBuilder {
+	s.limit = limit
+	return s
+}
+
+// Offset skips rows.
+func (s *SelectBuilder) Offset(offset int) *SelectBuilder {
+	s.offset = offset
+	return s
+}
+
+// Err returns the first invalid condition given to Where or Having, of this select or of the
+// selects nested in it.
+func (s *SelectBuilder) Err() error {
+	if err := buildersErr(s.err, s.where, s.having); err != nil {
+		return err
+	}
+	for _, cte := range s.with {
+		if cte.Query == nil {
+			return fmt.Errorf("common table expression %s has no query", cte.Name)
+		}
+		if err := cte.Query.Err(); err != nil {
+			return err
+		}
+	}
+	for _, union := range s.unions {
+		if union.Query == nil {
+			return errors.New("union without a query")
+		}
+		if err := union.Query.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Build returns the statement and its arguments, or the error reported by Err.
+func (s *SelectBuilder) Build() (string, []interface{}, error) {
+	if err := s.Err(); err != nil {
+		return "", nil, err
+	}
+	query := s.render()
+	return bindPlaceholders(query.sql+";", s.qg.Placeholder), query.args, nil
+}
+
+// render returns the statement with "?" markers and without a terminating semicolon.
+func (s *SelectBuilder) render() fragment {
+	with := s.renderWith()
+	query := s.renderSelect()
+	query.sql = with.sql + query.sql
+	query.args = append(with.args, query.args...)
+	return query
+}
+
+// renderSelect returns the statement without its WITH clause.
+func (s *SelectBuilder) renderSelect() fragment {
+	var query fragment
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	if s.distinct {
+		sb.WriteString("DISTINCT ")
+	}
+	sb.WriteString(escapeColumns(s.qg, s.columns))
+	if s.from != "" {
+		sb.WriteString(" FROM " + escapeAliased(s.qg, s.from))
+	}
+	for _, join := range s.joins {
+		sb.WriteString(" " + join.kind + " " + escapeAliased(s.qg, join.table))
+		if join.on.sql != "" {
+			sb.WriteString(" ON " + join.on.sql)
+			query.args = append(query.args, join.on.args...)
+		}
+	}
+	if len(s.where) > 0 {
+		where, args := And(s.where...).Build(s.qg)
+		sb.WriteString(" WHERE " + where)
+		query.args = append(query.args, args...)
+	}
+	if len(s.groupBy) > 0 {
+		sb.WriteString(" GROUP BY " + escapeList(s.qg, s.groupBy, escapeName))
+	}
+	if len(s.having) > 0 {
+		having, args := And(s.having...).Build(s.qg)
+		sb.WriteString(" HAVING " + having)
+		query.args = append(query.args, args...)
+	}
+	for i, union := range s.unions {
+		operand := union.operand(i + 1)
+		sb.WriteString(" " + union.keyword() + " " + operand.sql)
+		query.args = append(query.args, operand.args...)
+	}
+	if len(s.orderBy) > 0 {
+		sb.WriteString(" ORDER BY " + escapeList(s.qg, s.orderBy, escapeOrder))
+	}
+	if limit := s.qg.GenerateLimitClause(s.limit, s.offset); limit != "" {
+		sb.WriteString(" " + limit)
+	}
+	query.sql = sb.String()
+	return query
+}
+
+// InsertBuilder builds an INSERT of one or more rows.
+type InsertBuilder struct {
+	qg      QueryGenerator
+	table   string
+	columns []string
+	rows    [][]interface{}
+}
+
+// Columns sets the inserted columns.
+func (i *InsertBuilder) Columns(columns ...string) *InsertBuilder {
+	i.columns = columns
+	return i
+}
+
+// Values adds a row with one value per column.
+func (i *InsertBuilder) Values(values ...interface{}) *InsertBuilder {
+	i.rows = append(i.rows, values)
+	return i
+}
+
+// Record adds a row from a column/value map. The first record sets the columns when Columns
+// was not called, in sorted order; later records are read in the same order.
+func (i *InsertBuilder) Record(record map[string]interface{}) *InsertBuilder {
+	if len(i.columns) == 0 {
+		i.columns = sortedKeys(record)
+	}
+	values := make([]interface{}, len(i.columns))
+	for n, column := range i.columns {
+		values[n] = record[column]
+	}
+	return i.Values(values...)
+}
+
+// Build returns the statement and its arguments. A statement without rows, or with a row
+// whose values do not match the columns, is an error.
+func (i *InsertBuilder) Build() (string, []interface{}, error) {
+	if len(i.rows) == 0 {
+		return "", nil, fmt.Errorf("insert into %s has no values", i.table)
+	}
+	var args []interface{}
+	rows := make([]string, 0, len(i.rows))
+	for n, row := range i.rows {
+		if len(row) != len(i.columns) {
+			return "", nil, fmt.Errorf("row %d of the insert into %s has %d values for %d columns", n+1, i.table, len(row), len(i.columns))
+		}
+		rows = append(rows, "("+placeholders(len(row))+")")
+		args = append(args, row...)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s;", escapeName(i.qg, i.table),
+		escapeList(i.qg, i.columns, escapeName), strings.Join(rows, ", "))
+	return bindPlaceholders(query, i.qg.Placeholder), args, nil
+}
+
+// UpdateBuilder builds an UPDATE.
+type UpdateBuilder struct {
+	qg    QueryGenerator
+	err   error
+	table string
+	sets  []fragment
+	where []Condition
+}
+
+// Set assigns value to column.
+func (u *UpdateBuilder) Set(column string, value interface{}) *UpdateBuilder {
+	u.sets = append(u.sets, fragment{sql: escapeName(u.qg, column) + " = ?", args: []interface{}{value}})
+	return u
+}
+
+// SetExpr assigns a SQL expression to column, e.g. SetExpr("hits", "hits + ?", 1).
+func (u *UpdateBuilder) SetExpr(column string, expression string, args ...interface{}) *UpdateBuilder {
+	u.sets = append(u.sets, fragment{sql: escapeName(u.qg, column) + " = " + expression, args: args})
+	return u
+}
+
+// SetMap assigns the values of a column/value map in sorted column order.
+func (u *UpdateBuilder) SetMap(values map[string]interface{}) *UpdateBuilder {
+	for _, column := range sortedKeys(values) {
+		u.Set(column, values[column])
+	}
+	return u
+}
+
+// Where adds a condition like SelectBuilder.Where. Without one every row is updated.
+func (u *UpdateBuilder) Where(condition interface{}, args ...interface{}) *UpdateBuilder {
+	u.where = addCondition(u.where, &u.err, condition, args)
+	return u
+}
+
+// Err returns the first invalid condition given to Where.
+func (u *UpdateBuilder) Err() error {
+	return buildersErr(u.err, u.where)
+}
+
+// Build returns the statement and its arguments, or the error reported by Err. An update
+// setting no column is an error too.
+func (u *UpdateBuilder) Build() (string, []interface{}, error) {
+	if err := u.Err(); err != nil {
+		return "", nil, err
+	}
+	if len(u.sets) == 0 {
+		return "", nil, fmt.Errorf("update of %s sets no column", u.table)
+	}
+	var args []interface{}
+	sets := make([]string, 0, len(u.sets))
+	for _, set := range u.sets {
+		sets = append(sets, set.sql)
+		args = append(args, set.args...)
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s", escapeName(u.qg, u.table), strings.Join(sets, ", "))
+	if len(u.where) > 0 {
+		where, whereArgs := And(u.where...).Build(u.qg)
+		query += " WHERE " + where
+		args = append(args, whereArgs...)
+	}
+	return bindPlaceholders(query+";", u.qg.Placeholder), args, nil
+}
+
+// DeleteBuilder builds a DELETE.
+type DeleteBuilder struct {
+	qg    QueryGenerator
+	err   error
+	table string
+	where []Condition
+}
+
+// Where adds a condition like SelectBuilder.Where. Without one every row is deleted.
+func (d *DeleteBuilder) Where(condition interface{}, args ...interface{}) *DeleteBuilder {
+	d.where = addCondition(d.where, &d.err, condition, args)
+	return d
+}
+
+// Err returns the first invalid condition given to Where. Build returns it rather than a
+// DELETE without the condition.
+func (d *DeleteBuilder) Err() error {
+	return buildersErr(d.err, d.where)
+}
+
+// Build returns the statement and its arguments, or the error reported by Err.
+func (d *DeleteBuilder) Build() (string, []interface{}, error) {
+	if err := d.Err(); err != nil {
+		return "", nil, err
+	}
+	query := "DELETE FROM " + escapeName(d.qg, d.table)
+	var args []interface{}
+	if len(d.where) > 0 {
+		var where string
+		where, args = And(d.where...).Build(d.qg)
+		query += " WHERE " + where
+	}
+	return bindPlaceholders(query+";", d.qg.Placeholder), args, nil
+}
+
+var (
+	plainIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+	aliasedName     = regexp.MustCompile(`(?i)^(\S+)\s+(?:AS\s+)?([A-Za-z_][A-Za-z0-9_$]*)$`)
+	orderedName     = regexp.MustCompile(`(?i)^(.+?)\s+(ASC|DESC)$`)
+)
+
+// escapeName escapes a plain or qualified name like users.id or u.*, anything else is an
+// expression and returned as is.
+func escapeName(qg QueryGenerator, name string) string {
+	name = strings.TrimSpace(name)
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if !plainIdentifier.MatchString(part) && !(part == "*" && i == len(parts)-1) {
+			return name
+		}
+	}
+	for i, part := range parts {
+		if part != "*" {
+			parts[i] = qg.EscapeIdentifier(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// escapeAliased escapes a name followed by an alias, "users u" or "users AS u".
+func escapeAliased(qg QueryGenerator, name string) string {
+	name = strings.TrimSpace(name)
+	if match := aliasedName.FindStringSubmatch(name); match != nil && escapeName(qg, match[1]) != match[1] {
+		return escapeName(qg, match[1]) + " AS " + qg.EscapeIdentifier(match[2])
+	}
+	return escapeName(qg, name)
+}
+
+// escapeOrder escapes a sort column followed by ASC or DESC.
+func escapeOrder(qg QueryGenerator, column string) string {
+	column = strings.TrimSpace(column)
+	if match := orderedName.FindStringSubmatch(column); match != nil {
+		return escapeName(qg, match[1]) + " " + strings.ToUpper(match[2])
+	}
+	return escapeName(qg, column)
+}
+
+// escapeColumns escapes a select list, * when it is empty.
+func escapeColumns(qg QueryGenerator, columns []string) string {
+	if len(columns) == 0 {
+		return "*"
+	}
+	return escapeList(qg, columns, escapeAliased)
+}
+
+func escapeList(qg QueryGenerator, names []string, escape func(QueryGenerator, string) string) string {
+	escaped := make([]string, len(names))
+	for i, name := range names {
+		escaped[i] = escape(qg, name)
+	}
+	return strings.Join(escaped, ", ")
+}
@@ -0,0 +1,535 @@
+package queries
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sqldocify/configs"
+)
+
+// dialects are the generators every builder test renders for.
+var dialects = []struct {
+	name string
+	qg   QueryGenerator
+}{
+	{"mysql", &MySQLQueryGenerator{}},
+	{"sqlite", &SQLiteQueryGenerator{}},
+	{"postgres", &PostgreSQLQueryGenerator{}},
+}
+
+// dialectSQL is the statement expected from each dialect.
+type dialectSQL struct {
+	mysql, sqlite, postgres string
+}
+
+func (d dialectSQL) of(dialect string) string {
+	switch dialect {
+	case "mysql":
+		return d.mysql
+	case "sqlite":
+		return d.sqlite
+	}
+	return d.postgres
+}
+
+// sameSQL is the statement of a query that only differs in its quoting and bind markers. It is
+// written for SQLite, MySQL quotes with backticks and PostgreSQL numbers the markers.
+func sameSQL(query string) dialectSQL {
+	return dialectSQL{
+		mysql:    strings.ReplaceAll(query, `"`, "`"),
+		sqlite:   query,
+		postgres: bindPlaceholders(query, (&PostgreSQLQueryGenerator{}).Placeholder),
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(b *Builder) (string, []interface{}, error)
+		want  dialectSQL
+		args  []interface{}
+	}{
+		{
+			name: "select all",
+			build: func(b *Builder) (string, []interface{}, error) {
+				return b.Select().From("users").Build()
+			},
+			want: sameSQL(`SELECT * FROM "users";`),
+		},
+		{
+			name: "select with every clause",
+			build: func(b *Builder) (string, []interface{}, error) {
+				return b.Select("u.id", "u.name AS n", "COUNT(*) total").Distinct().From("users u").
+					LeftJoin("orders o", "o.user_id = u.id AND o.status = ?", "paid").
+					Where("u.age > ?", 18).Where(map[string]interface{}{"u.active": true}).
+					GroupBy("u.id", "u.name").Having("COUNT(*) > ?", 2).
+					OrderBy("n desc", "u.id").Limit(10).Offset(20).Build()
+			},
+			want: sameSQL(`SELECT DISTINCT "u"."id", "u"."name" AS "n", COUNT(*) total FROM "users" AS "u" ` +
+				`LEFT JOIN "orders" AS "o" ON o.user_id = u.id AND o.status = ? WHERE (u.age > ?) AND "u"."active" = ? ` +
+				`GROUP BY "u"."id", "u"."name" HAVING COUNT(*) > ? ORDER BY "n" DESC, "u"."id" LIMIT 10 OFFSET 20;`),
+			args: []interface{}{"paid", 18, true, 2},
+		},
+		{
+			name: "offset without limit",
+			build: func(b *Builder) (string, []interface{}, error) {
+				return b.Select("id").From("users").Offset(5).Build()
+			},
+			want: dialectSQL{
+				mysql:    "SELECT `id` FROM `users` LIMIT 18446744073709551615 OFFSET 5;",
+				sqlite:   `SELECT "id" FROM "users" LIMIT -1 OFFSET 5;`,
+				postgres: `SELECT "id" FROM "users" OFFSET 5;`,
+			},
+		},
+		{
+			name: "cross join",
+			build: func(b *Builder) (string, []interface{}, error) {
+				return b.Select("a.x", "b.y").From("a").CrossJoin("b").Build()
+			},
+			want: sameSQL(`SELECT "a"."x", "b"."y" FROM "a" CROSS JOIN "b";`),
+		},
+		{
+			name: "question marks in literals are no markers",
+			build: func(b *Builder) (string, []interface{}, error) {
+				return b.Select("id").From("faq").Where("question = 'why?' AND id > ?", 1).Where(Eq("lang", "en")).Build()
+			},
+			want: sameSQL(`SELECT "id" FROM "faq" WHERE (question = 'why?' AND id > ?) AND "lang" = ?;`),
+			args: []interface{}{1, "en"},
+		},
+		{
+			name: "insert rows",
+			build: func(b *Builder) (string, []interface{}, error) {
+				return b.Insert("users").Columns("name", "age").Values("ann", 30).Values("bob", 40).Build()
+			},
+			want: sameSQL(`INSERT INTO "users" ("name", "age") VALUES (?, ?), (?, ?);`),
+			args: []interface{}{"ann", 30, "bob", 40},
+		},
+		{
+			name: "insert records",
+			build: func(b *Builder) (string, []interface{}, error) {
+				return b.Insert("users").Record(map[string]interface{}{"name": "ann", "age": 30}).
+					Record(map[string]interface{}{"age": 40, "name": "bob"}).Build()
+			},
+			want: sameSQL(`INSERT INTO "users" ("age", "name") VALUES (?, ?), (?, ?);`),
+			args: []interface{}{30, "ann", 40, "bob"},
+		},
+		{
+			name: "update",
+			build: func(b *Builder) (string, []interface{}, error) {
+				return b.Update("users").Set("name", "ann").SetExpr("hits", "hits + ?", 1).
+					Where(Eq("id", 7)).Build()
+			},
+			want: sameSQL(`UPDATE "users" SET "name" = ?, "hits" = hits + ? WHERE "id" = ?;`),
+			args: []interface{}{"ann", 1, 7},
+		},
+		{
+			name: "update map",
+			build: func(b *Builder) (string, []interface{}, error) {
+				return b.Update("users").SetMap(map[string]interface{}{"b": 2, "a": 1}).Build()
+			},
+			want: sameSQL(`UPDATE "users" SET "a" = ?, "b" = ?;`),
+			args: []interface{}{1, 2},
+		},
+		{
+			name: "delete",
+			build: func(b *Builder) (string, []interface{}, error) {
+				return b.Delete("sessions").Where(Lt("expires_at", 100)).Where("user_id = ?", 3).Build()
+			},
+			want: sameSQL(`DELETE FROM "sessions" WHERE "expires_at" < ? AND (user_id = ?);`),
+			args: []interface{}{100, 3},
+		},
+		{
+			name: "identifiers that need quoting",
+			build: func(b *Builder) (string, []interface{}, error) {
+				return b.Select("order", "group.by").From("select").Build()
+			},
+			want: sameSQL(`SELECT "order", "group"."by" FROM "select";`),
+		},
+	}
+	for _, tt := range tests {
+		for _, dialect := range dialects {
+			t.Run(tt.name+"/"+dialect.name, func(t *testing.T) {
+				query, args, err := tt.build(NewBuilder(dialect.qg))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := tt.want.of(dialect.name); query != want {
+					t.Errorf("query\n got %s\nwant %s", query, want)
+				}
+				if !reflect.DeepEqual(args, tt.args) {
+					t.Errorf("args = %#v, want %#v", args, tt.args)
+				}
+			})
+		}
+	}
+}
+
+func TestEscapeIdentifier(t *testing.T) {
+	tests := []struct {
+		identifier string
+		want       dialectSQL
+	}{
+		{"users", dialectSQL{"`users`", `"users"`, `"users"`}},
+		{"weird`name", dialectSQL{"`weird``name`", "\"weird`name\"", "\"weird`name\""}},
+		{`weird"name`, dialectSQL{"`weird\"name`", `"weird""name"`, `"weird""name"`}},
+	}
+	for _, tt := range tests {
+		for _, dialect := range dialects {
+			if got, want := dialect.qg.EscapeIdentifier(tt.identifier), tt.want.of(dialect.name); got != want {
+				t.Errorf("%s EscapeIdentifier(%q) = %s, want %s", dialect.name, tt.identifier, got, want)
+			}
+		}
+	}
+}
+
+func TestQuoteLiteral(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  dialectSQL
+	}{
+		{"active", dialectSQL{`'active'`, `'active'`, `'active'`}},
+		{"it's", dialectSQL{`'it''s'`, `'it''s'`, `'it''s'`}},
+		{`a\' OR 1=1 -- `, dialectSQL{`'a\\'' OR 1=1 -- '`, `'a\'' OR 1=1 -- '`, `E'a\\'' OR 1=1 -- '`}},
+		{42, dialectSQL{"42", "42", "42"}},
+		{uint8(7), dialectSQL{"7", "7", "7"}},
+		{1.5, dialectSQL{"1.5", "1.5", "1.5"}},
+		{true, dialectSQL{"1", "1", "TRUE"}},
+		{false, dialectSQL{"0", "0", "FALSE"}},
+		{nil, dialectSQL{"NULL", "NULL", "NULL"}},
+	}
+	for _, tt := range tests {
+		for _, dialect := range dialects {
+			if got, want := dialect.qg.QuoteLiteral(tt.value), tt.want.of(dialect.name); got != want {
+				t.Errorf("%s QuoteLiteral(%#v) = %s, want %s", dialect.name, tt.value, got, want)
+			}
+		}
+	}
+	noEscapes := &MySQLQueryGenerator{NoBackslashEscapes: true}
+	if got := noEscapes.QuoteLiteral(`a\'b`); got != `'a\''b'` {
+		t.Errorf("QuoteLiteral with NO_BACKSLASH_ESCAPES = %s", got)
+	}
+}
+
+func TestTableExistsQuery(t *testing.T) {
+	want := dialectSQL{
+		mysql:    "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?;",
+		sqlite:   "SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?;",
+		postgres: "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1;",
+	}
+	for _, dialect := range dialects {
+		query, args := dialect.qg.GenerateTableExistsQuery("user_roles")
+		if query != want.of(dialect.name) || !reflect.DeepEqual(args, []interface{}{"user_roles"}) {
+			t.Errorf("%s GenerateTableExistsQuery = %s %v", dialect.name, query, args)
+		}
+	}
+}
+
+func TestReservedWordIdentifiers(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(qg QueryGenerator) (string, error)
+		want  dialectSQL
+	}{
+		{
+			"insert",
+			func(qg QueryGenerator) (string, error) {
+				query, _ := qg.GenerateInsertQuery("order", []string{"userId", "group"}, []interface{}{1, "a"})
+				return query, nil
+			},
+			sameSQL(`INSERT INTO "order" ("userId", "group") VALUES (?, ?);`),
+		},
+		{
+			"upsert",
+			func(qg QueryGenerator) (string, error) {
+				query, _ := qg.GenerateUpsertQuery("order", []string{"userId", "group"}, []interface{}{1, "a"}, []string{"userId"}, map[string]interface{}{"group": "a"})
+				return query, nil
+			},
+			dialectSQL{
+				mysql:    "INSERT INTO `order` (`userId`, `group`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `group` = ?;",
+				sqlite:   `INSERT INTO "order" ("userId", "group") VALUES (?, ?) ON CONFLICT ("userId") DO UPDATE SET "group" = ?;`,
+				postgres: `INSERT INTO "order" ("userId", "group") VALUES ($1, $2) ON CONFLICT ("userId") DO UPDATE SET "group" = $3;`,
+			},
+		},
+		{
+			"update",
+			func(qg QueryGenerator) (string, error) {
+				query, _, err := qg.GenerateUpdateQuery("order", map[string]interface{}{"group": "b"}, Eq("userId", 1))
+				return query, err
+			},
+			sameSQL(`UPDATE "order" SET "group" = ? WHERE "userId" = ?;`),
+		},
+		{
+			"delete",
+			func(qg QueryGenerator) (string, error) {
+				query, _, err := qg.GenerateDeleteQuery("order", Eq("userId", 1))
+				return query, err
+			},
+			sameSQL(`DELETE FROM "order" WHERE "userId" = ?;`),
+		},
+		{
+			"create index",
+			func(qg QueryGenerator) (string, error) {
+				return qg.GenerateCreateIndexQuery("idx_order_userId", "order", []string{"userId"}, false), nil
+			},
+			sameSQL(`CREATE INDEX "idx_order_userId" ON "order" ("userId");`),
+		},
+		{
+			"drop column",
+			func(qg QueryGenerator) (string, error) {
+				return qg.GenerateDropColumnQuery("order", "group"), nil
+			},
+			sameSQL(`ALTER TABLE "order" DROP COLUMN "group";`),
+		},
+	}
+	for _, tt := range tests {
+		for _, dialect := range dialects {
+			got, err := tt.build(dialect.qg)
+			if err != nil {
+				t.Errorf("%s %s: %v", dialect.name, tt.name, err)
+				continue
+			}
+			if want := tt.want.of(dialect.name); got != want {
+				t.Errorf("%s %s:\n got %s\nwant %s", dialect.name, tt.name, got, want)
+			}
+		}
+	}
+}
+
+// membersSchema has a composite primary key, a composite unique constraint and two indexes.
+func membersSchema() configs.TableSchema {
+	return configs.TableSchema{
+		Columns: []configs.ColumnDef{
+			{Name: "user_id", FieldSchema: configs.FieldSchema{Type: "int", Null: "NO"}},
+			{Name: "group_id", FieldSchema: configs.FieldSchema{Type: "int", Null: "NO"}},
+			{Name: "slug", FieldSchema: configs.FieldSchema{Type: "varchar(50)", Null: "NO"}},
+			{Name: "body", FieldSchema: configs.FieldSchema{Type: "text", Null: "YES"}},
+		},
+		PrimaryKey: []string{"user_id", "group_id"},
+		Uniques:    []configs.IndexDef{{Columns: []string{"group_id", "slug"}}},
+		Indexes:    []configs.IndexDef{{Name: "idx_slug", Columns: []string{"slug"}}, {Type: "FULLTEXT", Columns: []string{"body"}}},
+	}
+}
+
+func TestCreateTableConstraints(t *testing.T) {
+	want := dialectSQL{
+		mysql: "CREATE TABLE `members` (`user_id` int NOT NULL, `group_id` int NOT NULL, `slug` varchar(50) NOT NULL, `body` text, " +
+			"PRIMARY KEY (`user_id`, `group_id`), UNIQUE (`group_id`, `slug`), INDEX `idx_slug` (`slug`), FULLTEXT INDEX `idx_members_body` (`body`));",
+		sqlite: `CREATE TABLE "members" ("user_id" INTEGER NOT NULL, "group_id" INTEGER NOT NULL, "slug" varchar(50) NOT NULL, "body" text, ` +
+			`PRIMARY KEY ("user_id", "group_id"), UNIQUE ("group_id", "slug"));` + "\n" +
+			`CREATE INDEX "idx_slug" ON "members" ("slug");` + "\n" +
+			`CREATE INDEX "idx_members_body" ON "members" ("body");`,
+		postgres: `CREATE TABLE "members" ("user_id" integer NOT NULL, "group_id" integer NOT NULL, "slug" varchar(50) NOT NULL, "body" text, ` +
+			`PRIMARY KEY ("user_id", "group_id"), UNIQUE ("group_id", "slug"));` + "\n" +
+			`CREATE INDEX "idx_slug" ON "members" ("slug");` + "\n" +
+			`CREATE INDEX "idx_members_body" ON "members" USING GIN (to_tsvector('simple', coalesce("body", '')));`,
+	}
+	for _, dialect := range dialects {
+		if got := dialect.qg.GenerateCreateTableQuery("members", membersSchema()); got != want.of(dialect.name) {
+			t.Errorf("%s:\n got %s\nwant %s", dialect.name, got, want.of(dialect.name))
+		}
+	}
+}
+
+func TestConditions(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition Condition
+		want      string
+		args      []interface{}
+	}{
+		{"eq", Eq("status", "active"), `"status" = ?`, []interface{}{"active"}},
+		{"eq nil", Eq("deleted_at", nil), `"deleted_at" IS NULL`, nil},
+		{"neq nil", Neq("deleted_at", nil), `"deleted_at" IS NOT NULL`, nil},
+		{"comparisons", And(Gt("a", 1), Gte("b", 2), Lt("c", 3), Lte("d", 4), Neq("e", 5)),
+			`"a" > ? AND "b" >= ? AND "c" < ? AND "d" <= ? AND "e" <> ?`, []interface{}{1, 2, 3, 4, 5}},
+		{"like", Or(Like("name", "a%"), NotLike("name", "%z")), `"name" LIKE ? OR "name" NOT LIKE ?`, []interface{}{"a%", "%z"}},
+		{"in", In("id", 1, 2, 3), `"id" IN (?, ?, ?)`, []interface{}{1, 2, 3}},
+		{"in slice", In("id", []int{4, 5}), `"id" IN (?, ?)`, []interface{}{4, 5}},
+		{"in bytes", In("hash", []byte("ab")), `"hash" IN (?)`, []interface{}{[]byte("ab")}},
+		{"in nothing", In("id"), `1 = 0`, nil},
+		{"not in nothing", NotIn("id", []string{}), `1 = 1`, nil},
+		{"not in", NotIn("role", "guest"), `"role" NOT IN (?)`, []interface{}{"guest"}},
+		{"between", Between("age", 18, 65), `"age" BETWEEN ? AND ?`, []interface{}{18, 65}},
+		{"column eq", ColumnEq("o.user_id", "u.id"), `"o"."user_id" = "u"."id"`, nil},
+		{"nested groups", And(Eq("a", 1), Or(Eq("b", 2), Eq("c", 3)), Not(Eq("d", 4))),
+			`"a" = ? AND ("b" = ? OR "c" = ?) AND NOT ("d" = ?)`, []interface{}{1, 2, 3, 4}},
+		{"raw is parenthesized", Or(Raw("a = ? AND b = ?", 1, 2), IsNull("c")), `(a = ? AND b = ?) OR "c" IS NULL`, []interface{}{1, 2}},
+		{"nil and empty groups are skipped", And(nil, Eq("a", 1), Or(), And(nil)), `"a" = ?`, []interface{}{1}},
+		{"single condition group", And(Or(Eq("a", 1))), `"a" = ?`, []interface{}{1}},
+		{"empty and", And(), `1 = 1`, nil},
+		{"empty or", Or(nil), `1 = 0`, nil},
+		{"eq map", EqMap(map[string]interface{}{"b": 2, "a": 1}), `"a" = ? AND "b" = ?`, []interface{}{1, 2}},
+		{"expression column", Gt("LOWER(name)", "m"), `LOWER(name) > ?`, []interface{}{"m"}},
+	}
+	qg := &SQLiteQueryGenerator{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args := tt.condition.Build(qg)
+			if sql != tt.want {
+				t.Errorf("condition\n got %s\nwant %s", sql, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+	if sql, args := buildCondition(qg, nil); sql != "1 = 1" || args != nil {
+		t.Errorf("buildCondition(nil) = %s %v", sql, args)
+	}
+}
+
+func TestBuilderErr(t *testing.T) {
+	b := NewBuilder(&PostgreSQLQueryGenerator{})
+	tests := []struct {
+		name    string
+		err     func() error
+		build   func() (string, []interface{}, error)
+		message string
+	}{
+		{
+			name:    "unsupported where",
+			err:     func() error { return b.Select().From("users").Where(42).Err() },
+			build:   func() (string, []interface{}, error) { return b.Select().From("users").Where(42).Build() },
+			message: "unsupported condition type int",
+		},
+		{
+			name: "arguments with a condition",
+			err:  func() error { return b.Update("users").Set("a", 1).Where(Eq("id", 1), 2).Err() },
+			build: func() (string, []interface{}, error) {
+				return b.Update("users").Set("a", 1).Where(Eq("id", 1), 2).Build()
+			},
+			message: "arguments are only used with a SQL string condition",
+		},
+		{
+			name: "delete keeps no partial condition",
+			err:  func() error { return b.Delete("users").Where(Eq("a", 1)).Where([]string{"x"}).Err() },
+			build: func() (string, []interface{}, error) {
+				return b.Delete("users").Where(Eq("a", 1)).Where([]string{"x"}).Build()
+			},
+			message: "unsupported condition type []string",
+		},
+		{
+			name:    "having",
+			err:     func() error { return b.Select().From("users").Having(3.5).Err() },
+			build:   func() (string, []interface{}, error) { return b.Select().From("users").Having(3.5).Build() },
+			message: "unsupported condition type float64",
+		},
+		{
+			name: "nested select",
+			err: func() error {
+				return b.Delete("users").Where(Not(InSelect("id", b.Select("user_id").From("bans").Where(true)))).Err()
+			},
+			build: func() (string, []interface{}, error) {
+				return b.Delete("users").Where(Not(InSelect("id", b.Select("user_id").From("bans").Where(true)))).Build()
+			},
+			message: "unsupported condition type bool",
+		},
+		{
+			name: "not of nil",
+			err:  func() error { return b.Delete("users").Where(Or(Eq("a", 1), Not(nil))).Err() },
+			build: func() (string, []interface{}, error) {
+				return b.Delete("users").Where(Or(Eq("a", 1), Not(nil))).Build()
+			},
+			message: "Not of a nil condition",
+		},
+		{
+			name: "nil subquery",
+			err:  func() error { return b.Select().From("users").Where(InSelect("id", nil)).Err() },
+			build: func() (string, []interface{}, error) {
+				return b.Select().From("users").Where(InSelect("id", nil)).Build()
+			},
+			message: "subquery of id is nil",
+		},
+		{
+			name:    "union without a query",
+			err:     func() error { return b.Select().From("users").Union(nil).Err() },
+			build:   func() (string, []interface{}, error) { return b.Select().From("users").Union(nil).Build() },
+			message: "union without a query",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.err(); err == nil || err.Error() != tt.message {
+				t.Errorf("Err() = %v, want %s", err, tt.message)
+			}
+			query, args, err := tt.build()
+			if err == nil || err.Error() != tt.message {
+				t.Errorf("Build() error = %v, want %s", err, tt.message)
+			}
+			if query != "" || args != nil {
+				t.Errorf("Build() = %q %v, want no statement", query, args)
+			}
+		})
+	}
+}
+
+func TestInvalidStatements(t *testing.T) {
+	qg := &SQLiteQueryGenerator{}
+	b := NewBuilder(qg)
+	tests := []struct {
+		name    string
+		build   func() (string, []interface{}, error)
+		message string
+	}{
+		{
+			name:    "insert without values",
+			build:   func() (string, []interface{}, error) { return b.Insert("users").Columns("a").Build() },
+			message: "insert into users has no values",
+		},
+		{
+			name: "insert with missing values",
+			build: func() (string, []interface{}, error) {
+				return b.Insert("users").Columns("a", "b").Values(1, 2).Values(3).Build()
+			},
+			message: "row 2 of the insert into users has 1 values for 2 columns",
+		},
+		{
+			name:    "update without columns",
+			build:   func() (string, []interface{}, error) { return b.Update("users").Where(Eq("id", 1)).Build() },
+			message: "update of users sets no column",
+		},
+		{
+			name: "generated update of not nil",
+			build: func() (string, []interface{}, error) {
+				return qg.GenerateUpdateQuery("users", map[string]interface{}{"a": 1}, Not(nil))
+			},
+			message: "Not of a nil condition",
+		},
+		{
+			name:    "generated delete of a nil subquery",
+			build:   func() (string, []interface{}, error) { return qg.GenerateDeleteQuery("users", NotExists(nil)) },
+			message: "subquery of EXISTS is nil",
+		},
+		{
+			name: "generated select with an invalid join",
+			build: func() (string, []interface{}, error) {
+				return qg.GenerateJoinQuery("users u", []Join{{Table: "orders o", On: Not(nil)}}, SelectOptions{})
+			},
+			message: "Not of a nil condition",
+		},
+		{
+			name: "generated count with an invalid union",
+			build: func() (string, []interface{}, error) {
+				return qg.GenerateCountQuery("users", SelectOptions{Unions: []Union{{Query: b.Select().From("admins").Where(1)}}})
+			},
+			message: "unsupported condition type int",
+		},
+		{
+			name:    "rendered condition",
+			build:   func() (string, []interface{}, error) { return qg.BuildConditionQuery(And(Eq("a", 1), Not(nil))) },
+			message: "Not of a nil condition",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := tt.build()
+			if err == nil || err.Error() != tt.message {
+				t.Errorf("error = %v, want %s", err, tt.message)
+			}
+			if query != "" || args != nil {
+				t.Errorf("statement = %q %v, want none", query, args)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package queries
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Condition is a WHERE condition built from Eq, In, And and the other constructors below.
+// Values are always bound as arguments, column names are escaped like in the Builder.
+//
+//	queries.And(
+//		queries.Eq("status", "active"),
+//		queries.Or(queries.Gt("age", 18), queries.IsNull("age")),
+//		queries.In("role", "admin", "editor"),
+//	)
+type Condition interface {
+	// Build returns the condition with "?" markers and its arguments. The generator running
+	// the statement binds the markers for its dialect.
+	Build(qg QueryGenerator) (string, []interface{})
+}
+
+type comparison struct {
+	column   string
+	operator string
+	value    interface{}
+}
+
+func (c comparison) Build(qg QueryGenerator) (string, []interface{}) {
+	return escapeName(qg, c.column) + " " + c.operator + " ?", []interface{}{c.value}
+}
+
+// Eq matches column = value, a nil value matches column IS NULL.
+func Eq(column string, value interface{}) Condition {
+	if value == nil {
+		return IsNull(column)
+	}
+	return comparison{column: column, operator: "=", value: value}
+}
+
+// Neq matches column <> value, a nil value matches column IS NOT NULL.
+func Neq(column string, value interface{}) Condition {
+	if value == nil {
+		return IsNotNull(column)
+	}
+	return comparison{column: column, operator: "<>", value: value}
+}
+
+// Gt matches column > value.
+func Gt(column string, value interface{}) Condition {
+	return comparison{column: column, operator: ">", value: value}
+}
+
+// Gte matches column >= value.
+func Gte(column string, value interface{}) Condition {
+	return comparison{column: column, operator: ">=", value: value}
+}
+
+// Lt matches column < value.
+func Lt(column string, value interface{}) Condition {
+	return comparison{column: column, operator: "<", value: value}
+}
+
+// Lte matches column <= value.
+func Lte(column string, value interface{}) Condition {
+	return comparison{column: column, operator: "<=", value: value}
+}
+
+// Like matches column LIKE pattern.
+func Like(column string, pattern string) Condition {
+	return comparison{column: column, operator: "LIKE", value: pattern}
+}
+
+// NotLike matches column NOT LIKE pattern.
+func NotLike(column string, pattern string) Condition {
+	return comparison{column: column, operator: "NOT LIKE", value: pattern}
+}
+
+type columnComparison struct {
+	left  string
+	right string
+}
+
+func (c columnComparison) Build(qg QueryGenerator) (string, []interface{}) {
+	return escapeName(qg, c.left) + " = " + escapeName(qg, c.right), nil
+}
+
+// ColumnEq matches left = right for two columns, e.g. the ON condition of a join
+// ColumnEq("o.user_id", "u.id").
+func ColumnEq(left string, right string) Condition {
+	return columnComparison{left: left, right: right}
+}
+
+type inList struct {
+	column string
+	not    bool
+	values []interface{}
+}
+
+func (c inList) Build(qg QueryGenerator) (string, []interface{}) {
+	if len(c.values) == 0 {
+		// Nothing is in an empty list, which SQL cannot write as IN ().
+		if c.not {
+			return "1 = 1", nil
+		}
+		return "1 = 0", nil
+	}
+	operator := " IN "
+	if c.not {
+		operator = " NOT IN "
+	}
+	return escapeName(qg, c.column) + operator + "(" + placeholders(len(c.values)) + ")", c.values
+}
+
+// In matches column IN (values...). A single slice is expanded, so In("id", ids) and
+// In("id", 1, 2, 3) are the same; an empty list matches nothing.
+func In(column string, values ...interface{}) Condition {
+	return inList{column: column, values: expandValues(values)}
+}
+
+// NotIn matches column NOT IN (values...), an empty list matches everything.
+func NotIn(column string, values ...interface{}) Condition {
+	return inList{column: column, not: true, values: expandValues(values)}
+}
+
+// expandValues turns a single slice argument into its elements, []byte stays one value.
+func expandValues(values []interface{}) []interface{} {
+	if len(values) != 1 {
+		return values
+	}
+	if _, ok := values[0].([]byte); ok {
+		return values
+	}
+	v := reflect.ValueOf(values[0])
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return values
+	}
+	expanded := make([]interface{}, v.Len())
+	for i := range expanded {
+		expanded[i] = v.Index(i).Interface()
+	}
+	return expanded
+}
+
+type between struct {
+	column string
+	low    interface{}
+	high   interface{}
+}
+
+func (c between) Build(qg QueryGenerator) (string, []interface{}) {
+	return escapeName(qg, c.column) + " BETWEEN ? AND ?", []interface{}{c.low, c.high}
+}
+
+// Between matches low <= column <= high.
+func Between(column string, low interface{}, high interface{}) Condition {
+	return between{column: column, low: low, high: high}
+}
+
+type nullCheck struct {
+	column string
+	not    bool
+}
+
+func (c nullCheck) Build(qg QueryGenerator) (string, []interface{}) {
+	if c.not {
+		return escapeName(qg, c.column) + " IS NOT NULL", nil
+	}
+	return escapeName(qg, c.column) + " IS NULL", nil
+}
+
+// IsNull matches column IS NULL.
+func IsNull(column string) Condition {
+	return nullCheck{column: column}
+}
+
+// IsNotNull matches column IS NOT NULL.
+func IsNotNull(column string) Condition {
+	return nullCheck{column: column, not: true}
+}
+
+type logical struct {
+	operator   string
+	conditions []Condition
+}
+
+func (c logical) Build(qg QueryGenerator) (string, []interface{}) {
+	conditions := c.present()
+	if len(conditions) == 0 {
+		// The identities of AND and OR.
+		if c.operator == "OR" {
+			return "1 = 0", nil
+		}
+		return "1 = 1", nil
+	}
+	if len(conditions) == 1 {
+		return conditions[0].Build(qg)
+	}
+	parts := make([]string, 0, len(conditions))
+	var args []interface{}
+	for _, condition := range conditions {
+		sql, conditionArgs := condition.Build(qg)
+		if needsParens(condition) {
+			sql = "(" + sql + ")"
+		}
+		parts = append(parts, sql)
+		args = append(args, conditionArgs...)
+	}
+	return strings.Join(parts, " "+c.operator+" "), args
+}
+
+// present returns the conditions of the group without nil conditions and empty groups, so
+// filters can be collected conditionally.
+func (c logical) present() []Condition {
+	var conditions []Condition
+	for _, condition := range c.conditions {
+		if group, ok := condition.(logical); condition == nil || ok && len(group.present()) == 0 {
+			continue
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions
+}
+
+// needsParens reports whether a condition has to be parenthesized inside AND or OR: groups of
+// several conditions and raw fragments, whose operators are unknown.
+func needsParens(condition Condition) bool {
+	switch c := condition.(type) {
+	case raw:
+		return true
+	case logical:
+		conditions := c.present()
+		return len(conditions) > 1 || len(conditions) == 1 && needsParens(conditions[0])
+	}
+	return false
+}
+
+// And matches when every condition matches. nil conditions and empty groups are skipped, an
+// And of nothing matches every row.
+func And(conditions ...Condition) Condition {
+	return logical{operator: "AND", conditions: conditions}
+}
+
+// Or matches when any condition matches. nil conditions and empty groups are skipped, an Or of
+// nothing matches no row.
+func Or(conditions ...Condition) Condition {
+	return logical{operator: "OR", conditions: conditions}
+}
+
+// EqMap matches every column = value pair of values, in sorted column order.
+func EqMap(values map[string]interface{}) Condition {
+	conditions := make([]Condition, 0, len(values))
+	for _, column := range sortedKeys(values) {
+		conditions = append(conditions, Eq(column, values[column]))
+	}
+	return And(conditions...)
+}
+
+type not struct {
+	condition Condition
+}
+
+func (c not) Build(qg QueryGenerator) (string, []interface{}) {
+	sql, args := buildCondition(qg, c.condition)
+	return "NOT (" + sql + ")", args
+}
+
+// Not matches when condition does not. A nil condition is reported as an error by the builders
+// and generators.
+func Not(condition Condition) Condition {
+	return not{condition: condition}
+}
+
+type raw struct {
+	sql  string
+	args []interface{}
+}
+
+func (c raw) Build(qg QueryGenerator) (string, []interface{}) {
+	return c.sql, c.args
+}
+
+// Raw is a SQL fragment with "?" markers for args, written as given.
+func Raw(sql string, args ...interface{}) Condition {
+	return raw{sql: sql, args: args}
+}
+
+// toCondition turns the condition of a Where into a Condition: a Condition, a column/value map
+// or a SQL string with "?" markers for args.
+func toCondition(condition interface{}, args []interface{}) (Condition, error) {
+	switch c := condition.(type) {
+	case Condition:
+		if len(args) > 0 {
+			return nil, errors.New("arguments are only used with a SQL string condition")
+		}
+		return c, nil
+	case string:
+		return Raw(c, args...), nil
+	case map[string]interface{}:
+		return EqMap(c), nil
+	default:
+		return nil, fmt.Errorf("unsupported condition type %T", condition)
+	}
+}
+
+// addCondition appends a condition given to a Where or Having, an invalid one is kept in err,
+// the first one only.
+func addCondition(conditions []Condition, err *error, condition interface{}, args []interface{}) []Condition {
+	c, invalid := toCondition(condition, args)
+	if invalid != nil {
+		if *err == nil {
+			*err = invalid
+		}
+		return conditions
+	}
+	return append(conditions, c)
+}
+
+// buildersErr returns err or the first error of a select nested in the condition groups.
+func buildersErr(err error, groups ...[]Condition) error {
+	if err != nil {
+		return err
+	}
+	for _, conditions := range groups {
+		for _, condition := range conditions {
+			if err := conditionErr(condition); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// conditionErr returns the error of a select nested in a condition, e.g. by InSelect, or of a
+// condition missing its operand.
+func conditionErr(condition Condition) error {
+	switch c := condition.(type) {
+	case inSelect:
+		if c.query == nil {
+			return fmt.Errorf("subquery of %s is nil", c.column)
+		}
+		return c.query.Err()
+	case exists:
+		if c.query == nil {
+			return errors.New("subquery of EXISTS is nil")
+		}
+		return c.query.Err()
+	case not:
+		if c.condition == nil {
+			return errors.New("Not of a nil condition")
+		}
+		return conditionErr(c.condition)
+	case logical:
+		return buildersErr(nil, c.conditions)
+	}
+	return nil
+}
+
+// buildCondition renders a condition for a dialect, nil matches every row.
+func buildCondition(qg QueryGenerator, condition Condition) (string, []interface{}) {
+	if condition == nil {
+		return "1 = 1", nil
+	}
+	return condition.Build(qg)
+}
@@ -10,9 +10,11 @@ func GetQueryGenerator() (QueryGenerator, error) {
 	switch dbType {
 	case "mysql":
 		return &MySQLQueryGenerator{}, nil
+	case "sqlite":
+		return &SQLiteQueryGenerator{}, nil
+	case "postgres":
+		return &PostgreSQLQueryGenerator{}, nil
 	// Add more cases for other database types if needed
-	// case "postgresql":
-	//     return &queries.PostgreSQLQueryGenerator{}, nil
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", dbType)
 	}
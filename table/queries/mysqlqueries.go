@@ -3,51 +3,272 @@ package queries
 import (
 	"database/sql"
 	"fmt"
+	"regexp"
 	"sqldocify/configs"
 	"strings"
 )
 
-type MySQLQueryGenerator struct{}
+type MySQLQueryGenerator struct {
+	// NoBackslashEscapes is set for servers running with NO_BACKSLASH_ESCAPES in their
+	// sql_mode, where a backslash in a string literal stands for itself.
+	NoBackslashEscapes bool
+}
 
-func (m *MySQLQueryGenerator) GenerateGetSchemaQuery(db *sql.DB, tablename string) (map[string]configs.FieldSchema, error) {
-	query := "DESC " + tablename + ";"
-	rows, err := db.Query(query)
+func (m *MySQLQueryGenerator) GenerateGetSchemaQuery(db *sql.DB, tablename string) (configs.TableSchema, error) {
+	// Character sets and collations are only kept when they differ from the table default,
+	// otherwise every text column would carry the server settings along.
+	query := `SELECT c.COLUMN_NAME, c.COLUMN_TYPE, c.DATA_TYPE, c.IS_NULLABLE, c.COLUMN_KEY, c.COLUMN_DEFAULT, c.EXTRA,
+			c.COLUMN_COMMENT, c.COLLATION_NAME, c.CHARACTER_SET_NAME, c.GENERATION_EXPRESSION, t.TABLE_COLLATION, ccsa.CHARACTER_SET_NAME
+		FROM information_schema.COLUMNS c
+		JOIN information_schema.TABLES t ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME
+		LEFT JOIN information_schema.COLLATION_CHARACTER_SET_APPLICABILITY ccsa ON ccsa.COLLATION_NAME = t.TABLE_COLLATION
+		WHERE c.TABLE_SCHEMA = DATABASE() AND c.TABLE_NAME = ?
+		ORDER BY c.ORDINAL_POSITION;`
+	rows, err := db.Query(query, tablename)
 	if err != nil {
-		return nil, err
+		return configs.TableSchema{}, err
 	}
 	defer rows.Close()
 
-	schema := make(map[string]configs.FieldSchema)
+	var schema configs.TableSchema
 
 	for rows.Next() {
 		var field string
-		var fieldType string
+		var columnType string
+		var dataType string
 		var isNull string
 		var key string
 		var defaultValue sql.NullString
 		var extra string
+		var comment string
+		var collation sql.NullString
+		var charset sql.NullString
+		var generated sql.NullString
+		var tableCollation sql.NullString
+		var tableCharset sql.NullString
 
-		err := rows.Scan(&field, &fieldType, &isNull, &key, &defaultValue, &extra)
+		err := rows.Scan(&field, &columnType, &dataType, &isNull, &key, &defaultValue, &extra,
+			&comment, &collation, &charset, &generated, &tableCollation, &tableCharset)
 		if err != nil {
-			return nil, err
+			return configs.TableSchema{}, err
+		}
+
+		fieldSchema := configs.FieldSchema{
+			Type:    columnType,
+			Null:    isNull,
+			Key:     key,
+			Comment: comment,
+		}
+		if strings.Contains(strings.ToLower(columnType), " unsigned") {
+			fieldSchema.Type = strings.TrimSpace(strings.Replace(strings.ToLower(columnType), " unsigned", "", 1))
+			fieldSchema.Unsigned = true
+		}
+		if collation.Valid && collation.String != tableCollation.String {
+			fieldSchema.Collation = collation.String
+		}
+		if charset.Valid && charset.String != tableCharset.String {
+			fieldSchema.Charset = charset.String
 		}
 
-		schema[field] = configs.FieldSchema{
-			Type: fieldType,
-			Null: isNull,
-			Key:  key,
-			// Default: nilIfEmpty(defaultValue),
-			Extra: extra,
+		var defaultGenerated bool
+		fieldSchema.Extra, fieldSchema.OnUpdate, fieldSchema.GeneratedType, defaultGenerated = splitMySQLExtra(extra)
+		if generated.String != "" {
+			fieldSchema.Generated = generated.String
+		} else if defaultValue.Valid {
+			value := m.columnDefault(defaultValue.String, dataType, defaultGenerated)
+			fieldSchema.Default = &value
 		}
+		schema.Columns = append(schema.Columns, configs.ColumnDef{Name: field, FieldSchema: fieldSchema})
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return configs.TableSchema{}, err
+	}
+	if len(schema.Columns) == 0 {
+		return configs.TableSchema{}, fmt.Errorf("table %s does not exist", tablename)
 	}
 
+	if err := m.applyForeignKeys(db, tablename, &schema); err != nil {
+		return configs.TableSchema{}, err
+	}
+	if err := m.applyIndexes(db, tablename, &schema); err != nil {
+		return configs.TableSchema{}, err
+	}
 	return schema, nil
 }
 
+// NormalizeSchema returns a schema the way GenerateGetSchemaQuery reports it once the table
+// is created, so it can be compared with configs.Diff.
+func (m *MySQLQueryGenerator) NormalizeSchema(schema configs.TableSchema) configs.TableSchema {
+	normalized := schema.Copy()
+	for i, column := range normalized.Columns {
+		fieldSchema := column.FieldSchema
+		if lower := strings.ToLower(fieldSchema.Type); strings.HasSuffix(lower, " unsigned") {
+			fieldSchema.Type = strings.TrimSpace(strings.TrimSuffix(lower, " unsigned"))
+			fieldSchema.Unsigned = true
+		}
+		if fieldSchema.Generated != "" {
+			fieldSchema.Default = nil
+			if fieldSchema.GeneratedType == "" {
+				fieldSchema.GeneratedType = "VIRTUAL"
+			}
+		}
+		normalized.Columns[i].FieldSchema = fieldSchema
+	}
+	return normalized
+}
+
+// applyForeignKeys reads the foreign keys of a table with their referential actions.
+func (m *MySQLQueryGenerator) applyForeignKeys(db *sql.DB, tablename string, schema *configs.TableSchema) error {
+	query := `SELECT k.CONSTRAINT_NAME, k.COLUMN_NAME, k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME, r.DELETE_RULE, r.UPDATE_RULE
+		FROM information_schema.KEY_COLUMN_USAGE k
+		JOIN information_schema.REFERENTIAL_CONSTRAINTS r
+			ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME AND r.TABLE_NAME = k.TABLE_NAME
+		WHERE k.TABLE_SCHEMA = DATABASE() AND k.TABLE_NAME = ? AND k.REFERENCED_TABLE_NAME IS NOT NULL
+		ORDER BY k.CONSTRAINT_NAME, k.ORDINAL_POSITION;`
+	rows, err := db.Query(query, tablename)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, column, referencedTable, referencedColumn, deleteRule, updateRule string
+		if err := rows.Scan(&name, &column, &referencedTable, &referencedColumn, &deleteRule, &updateRule); err != nil {
+			return err
+		}
+		last := len(schema.ForeignKeys) - 1
+		if last >= 0 && schema.ForeignKeys[last].Name == name {
+			schema.ForeignKeys[last].Columns = append(schema.ForeignKeys[last].Columns, column)
+			schema.ForeignKeys[last].ReferencedColumns = append(schema.ForeignKeys[last].ReferencedColumns, referencedColumn)
+			continue
+		}
+		schema.ForeignKeys = append(schema.ForeignKeys, configs.ForeignKeyDef{
+			Name:              name,
+			Columns:           []string{column},
+			ReferencedTable:   referencedTable,
+			ReferencedColumns: []string{referencedColumn},
+			OnDelete:          referentialAction(deleteRule),
+			OnUpdate:          referentialAction(updateRule),
+		})
+	}
+	return rows.Err()
+}
+
+// applyIndexes reads the composite primary key, multi-column unique keys and secondary indexes.
+// Single column keys are already reported by COLUMN_KEY, and the indexes MySQL adds for
+// foreign keys are left out, they come back with the foreign key.
+func (m *MySQLQueryGenerator) applyIndexes(db *sql.DB, tablename string, schema *configs.TableSchema) error {
+	query := `SELECT INDEX_NAME, NON_UNIQUE, COLUMN_NAME, INDEX_TYPE
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+		ORDER BY INDEX_NAME = 'PRIMARY' DESC, INDEX_NAME, SEQ_IN_INDEX;`
+	rows, err := db.Query(query, tablename)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var indexes indexList
+	unique := make(map[string]bool)
+	indexTypes := make(map[string]string)
+	functional := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		var nonUnique int
+		var column sql.NullString
+		var indexType string
+		if err := rows.Scan(&name, &nonUnique, &column, &indexType); err != nil {
+			return err
+		}
+		if !column.Valid {
+			// Functional key parts have no column and cannot be described by the schema.
+			functional[name] = true
+			continue
+		}
+		indexes.add(name, column.String)
+		unique[name] = nonUnique == 0
+		indexTypes[name] = indexType
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range indexes.names {
+		columns := indexes.columns[name]
+		switch {
+		case functional[name]:
+		case name == "PRIMARY":
+			if len(columns) > 1 {
+				schema.PrimaryKey = columns
+			}
+		case unique[name]:
+			if len(columns) > 1 {
+				schema.Uniques = append(schema.Uniques, configs.IndexDef{Name: name, Columns: columns})
+			}
+		case foreignKeyIndex(*schema, name):
+		default:
+			index := configs.IndexDef{Name: name, Columns: columns}
+			if indexTypes[name] == "FULLTEXT" {
+				index.Type = "FULLTEXT"
+			}
+			schema.Indexes = append(schema.Indexes, index)
+		}
+	}
+	return nil
+}
+
+func foreignKeyIndex(schema configs.TableSchema, name string) bool {
+	for _, foreignKey := range schema.ForeignKeys {
+		if foreignKey.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	mysqlOnUpdate         = regexp.MustCompile(`(?i)\bon update (\S+)`)
+	mysqlGeneratedType    = regexp.MustCompile(`(?i)\b(VIRTUAL|STORED) GENERATED\b`)
+	mysqlDefaultGenerated = regexp.MustCompile(`(?i)\bDEFAULT_GENERATED\b`)
+	mysqlNumericType      = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|integer|bigint|decimal|numeric|float|double|real|bit)$`)
+)
+
+// splitMySQLExtra separates the attributes MySQL reports in the EXTRA column. What remains,
+// e.g. auto_increment, is kept as Extra.
+func splitMySQLExtra(extra string) (rest string, onUpdate string, generatedType string, defaultGenerated bool) {
+	if match := mysqlOnUpdate.FindStringSubmatch(extra); match != nil {
+		onUpdate = match[1]
+		extra = strings.Replace(extra, match[0], "", 1)
+	}
+	if match := mysqlGeneratedType.FindStringSubmatch(extra); match != nil {
+		generatedType = strings.ToUpper(match[1])
+		extra = strings.Replace(extra, match[0], "", 1)
+	}
+	if mysqlDefaultGenerated.MatchString(extra) {
+		defaultGenerated = true
+		extra = mysqlDefaultGenerated.ReplaceAllString(extra, "")
+	}
+	return strings.Join(strings.Fields(extra), " "), onUpdate, generatedType, defaultGenerated
+}
+
+// columnDefault turns COLUMN_DEFAULT into the DDL form stored in FieldSchema.Default.
+// MySQL reports literals without quotes and expressions without their parentheses.
+func (m *MySQLQueryGenerator) columnDefault(value string, dataType string, defaultGenerated bool) string {
+	switch {
+	case isCurrentTimeDefault(value):
+		return value
+	case defaultGenerated:
+		return "(" + value + ")"
+	case strings.HasPrefix(value, "'"):
+		// MariaDB already quotes literals.
+		return value
+	case mysqlNumericType.MatchString(strings.ToLower(dataType)) && isNumericDefault(value):
+		return value
+	}
+	return m.QuoteLiteral(value)
+}
+
 func (m *MySQLQueryGenerator) GenerateGetAllTablesQuery(db *sql.DB) ([]string, error) {
 	query := "SHOW TABLES;"
 	rows, err := db.Query(query)
@@ -69,128 +290,172 @@ func (m *MySQLQueryGenerator) GenerateGetAllTablesQuery(db *sql.DB) ([]string, e
 	return tables, nil
 }
 
-func (m *MySQLQueryGenerator) GenerateCreateTableQuery(nm string, schema map[string]configs.FieldSchema) string {
+func (m *MySQLQueryGenerator) GenerateCreateTableQuery(nm string, schema configs.TableSchema) string {
 	var columnStrings []string
-	for column, fieldSchema := range schema {
-		if column == "created_at" || column == "updated_at" {
-			continue
+	for _, column := range schema.Columns {
+		columnStrings = append(columnStrings, m.columnDefinition(column.Name, column.FieldSchema, inlinePrimaryKey(schema, column.Name)))
+	}
+	columnStrings = append(columnStrings, tableConstraints(m, nm, schema)...)
+	for _, index := range schema.Indexes {
+		indexType := ""
+		if index.Type != "" {
+			indexType = strings.ToUpper(index.Type) + " "
 		}
-		colDef := fmt.Sprintf("%s %s", column, fieldSchema.Type)
+		columnStrings = append(columnStrings, fmt.Sprintf("%sINDEX %s (%s)", indexType, m.EscapeIdentifier(indexName(nm, index)), escapeIdentifiers(m, index.Columns)))
+	}
 
-		if fieldSchema.Null != "" {
-			colDef += " " + "NOT NULL"
-		}
-		if fieldSchema.Key == "UNI" {
-			colDef += " " + "UNIQUE"
-		}
-		if fieldSchema.Key == "PRI" {
-			colDef += " " + "PRIMARY KEY"
-		}
-		if fieldSchema.Extra != "" {
-			colDef += " " + fieldSchema.Extra
+	return fmt.Sprintf("CREATE TABLE %s (%s);", m.EscapeIdentifier(nm), strings.Join(columnStrings, ", "))
+}
+
+func (m *MySQLQueryGenerator) columnDefinition(column string, fieldSchema configs.FieldSchema, primary bool) string {
+	colDef := fmt.Sprintf("%s %s", m.EscapeIdentifier(column), fieldSchema.Type)
+	if fieldSchema.Unsigned && !strings.Contains(strings.ToLower(fieldSchema.Type), "unsigned") {
+		colDef += " unsigned"
+	}
+	if fieldSchema.Charset != "" {
+		colDef += " CHARACTER SET " + fieldSchema.Charset
+	}
+	if fieldSchema.Collation != "" {
+		colDef += " COLLATE " + fieldSchema.Collation
+	}
+	if fieldSchema.Generated != "" {
+		colDef += fmt.Sprintf(" GENERATED ALWAYS AS (%s)", fieldSchema.Generated)
+		if fieldSchema.GeneratedType != "" {
+			colDef += " " + strings.ToUpper(fieldSchema.GeneratedType)
 		}
-		columnStrings = append(columnStrings, colDef)
 	}
 
-	return fmt.Sprintf("CREATE TABLE %s (%s);", nm, strings.Join(columnStrings, ", "))
+	if fieldSchema.Null == "NO" {
+		colDef += " " + "NOT NULL"
+	}
+	if fieldSchema.Default != nil && fieldSchema.Generated == "" {
+		colDef += " DEFAULT " + *fieldSchema.Default
+	}
+	if fieldSchema.OnUpdate != "" {
+		colDef += " ON UPDATE " + fieldSchema.OnUpdate
+	}
+	if fieldSchema.Key == "UNI" {
+		colDef += " " + "UNIQUE"
+	}
+	if primary {
+		colDef += " " + "PRIMARY KEY"
+	}
+	if fieldSchema.Extra != "" {
+		colDef += " " + fieldSchema.Extra
+	}
+	if fieldSchema.Comment != "" {
+		colDef += " COMMENT " + m.QuoteLiteral(fieldSchema.Comment)
+	}
+	return colDef
 }
 
-func (m *MySQLQueryGenerator) GenerateTableExistsQuery(table string) string {
-	return fmt.Sprintf("SHOW TABLES LIKE '%s';", table)
+// GenerateTableExistsQuery looks the table up in the current database by its exact name, LIKE
+// would treat _ and % in it as wildcards.
+func (m *MySQLQueryGenerator) GenerateTableExistsQuery(table string) (string, []interface{}) {
+	return "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?;", []interface{}{table}
 }
 
-func (m *MySQLQueryGenerator) GenerateInsertQuery(table string, columns []string, values []interface{}) string {
-	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", table, FormatColumns(columns), SanitizeValues(values))
+func (m *MySQLQueryGenerator) GenerateInsertQuery(table string, columns []string, values []interface{}) (string, []interface{}) {
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", m.EscapeIdentifier(table), escapeIdentifiers(m, columns), placeholders(len(values)))
+	return bindPlaceholders(query, m.Placeholder), values
 }
 
-func (m *MySQLQueryGenerator) GenerateMultipleInsertQuery(table string, columns []string, values [][]interface{}) string {
+func (m *MySQLQueryGenerator) GenerateMultipleInsertQuery(table string, columns []string, values [][]interface{}) (string, []interface{}) {
 	var valueStrings []string
+	var args []interface{}
 	for _, val := range values {
-		valueStrings = append(valueStrings, fmt.Sprintf("(%s)", SanitizeValues(val)))
+		valueStrings = append(valueStrings, fmt.Sprintf("(%s)", placeholders(len(val))))
+		args = append(args, val...)
 	}
-	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s;", table, FormatColumns(columns), strings.Join(valueStrings, ", "))
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s;", m.EscapeIdentifier(table), escapeIdentifiers(m, columns), strings.Join(valueStrings, ", "))
+	return bindPlaceholders(query, m.Placeholder), args
 }
 
-func (m *MySQLQueryGenerator) GenerateUpdateQuery(table string, updates map[string]interface{}, condition string) string {
-	var setClauses []string
-	for column, value := range updates {
-		setClauses = append(setClauses, fmt.Sprintf("%s = %s", column, SanitizeValue(value)))
-	}
-	return fmt.Sprintf("UPDATE %s SET %s WHERE %s;", table, strings.Join(setClauses, ", "), condition)
+func (m *MySQLQueryGenerator) GenerateUpdateQuery(table string, updates map[string]interface{}, condition Condition) (string, []interface{}, error) {
+	return generateUpdateQuery(m, table, updates, condition)
 }
-func (m *MySQLQueryGenerator) GenerateDeleteQuery(table string, condition string) string {
-	return fmt.Sprintf("DELETE FROM %s WHERE %s;", table, condition)
+
+func (m *MySQLQueryGenerator) GenerateDeleteQuery(table string, condition Condition) (string, []interface{}, error) {
+	return generateDeleteQuery(m, table, condition)
 }
 
-func (m *MySQLQueryGenerator) GenerateMultipleDeleteQuery(table string, conditions []string) string {
-	return fmt.Sprintf("DELETE FROM %s WHERE %s;", table, strings.Join(conditions, " OR "))
+// GenerateMultipleDeleteQuery deletes the rows matching any of conditions, none deletes nothing.
+func (m *MySQLQueryGenerator) GenerateMultipleDeleteQuery(table string, conditions []Condition) (string, []interface{}, error) {
+	return generateDeleteQuery(m, table, Or(conditions...))
 }
-func (m *MySQLQueryGenerator) GenerateSelectQuery(table string, columns []string, condition string, orderBy string, limit int, offset int) string {
-	return fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s LIMIT %d OFFSET %d;", FormatColumns(columns), table, condition, orderBy, limit, offset)
+
+func (m *MySQLQueryGenerator) GenerateSelectQuery(table string, options SelectOptions) (string, []interface{}, error) {
+	return generateSelectQuery(m, table, options)
 }
 
-func (m *MySQLQueryGenerator) GenerateBatchInsertQuery(table string, columns []string, batchValues [][]interface{}) string {
-	var valueStrings []string
-	for _, val := range batchValues {
-		valueStrings = append(valueStrings, fmt.Sprintf("(%s)", SanitizeValues(val)))
-	}
-	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s;", table, FormatColumns(columns), strings.Join(valueStrings, ", "))
+func (m *MySQLQueryGenerator) GenerateBatchInsertQuery(table string, columns []string, batchValues [][]interface{}) (string, []interface{}) {
+	return m.GenerateMultipleInsertQuery(table, columns, batchValues)
 }
 
-func (m *MySQLQueryGenerator) GenerateUpsertQuery(table string, columns []string, values []interface{}, conflictColumns []string, updates map[string]interface{}) string {
+// GenerateUpsertQuery relies on ON DUPLICATE KEY UPDATE, which picks up every unique key,
+// so conflictColumns are not part of the statement.
+func (m *MySQLQueryGenerator) GenerateUpsertQuery(table string, columns []string, values []interface{}, conflictColumns []string, updates map[string]interface{}) (string, []interface{}) {
 	var setClauses []string
-	for column, value := range updates {
-		setClauses = append(setClauses, fmt.Sprintf("%s = %s", column, SanitizeValue(value)))
+	args := append([]interface{}{}, values...)
+	for _, column := range sortedKeys(updates) {
+		setClauses = append(setClauses, m.EscapeIdentifier(column)+" = ?")
+		args = append(args, updates[column])
+	}
+	if len(setClauses) == 0 && len(columns) > 0 {
+		// Nothing to update still needs a valid clause, keep the existing row as it is.
+		column := m.EscapeIdentifier(columns[0])
+		setClauses = append(setClauses, column+" = "+column)
 	}
-	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s;", table, FormatColumns(columns), SanitizeValues(values), strings.Join(setClauses, ", "))
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s;", m.EscapeIdentifier(table), escapeIdentifiers(m, columns), placeholders(len(values)), strings.Join(setClauses, ", "))
+	return bindPlaceholders(query, m.Placeholder), args
 }
 
-func (m *MySQLQueryGenerator) GenerateJoinQuery(mainTable string, joinType string, joinTable string, onCondition string, columns []string, condition string, orderBy string, limit int) string {
-	return fmt.Sprintf("SELECT %s FROM %s %s JOIN %s ON %s WHERE %s ORDER BY %s LIMIT %d;", FormatColumns(columns), mainTable, joinType, joinTable, onCondition, condition, orderBy, limit)
+func (m *MySQLQueryGenerator) GenerateJoinQuery(table string, joins []Join, options SelectOptions) (string, []interface{}, error) {
+	return generateJoinQuery(m, table, joins, options)
 }
 
-func (m *MySQLQueryGenerator) GenerateCountQuery(table string, condition string) string {
-	return fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s;", table, condition)
+func (m *MySQLQueryGenerator) GenerateCountQuery(table string, options SelectOptions) (string, []interface{}, error) {
+	return generateCountQuery(m, table, options)
 }
 
-func (m *MySQLQueryGenerator) GenerateExistsQuery(table string, condition string) string {
-	return fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE %s);", table, condition)
+func (m *MySQLQueryGenerator) GenerateExistsQuery(table string, options SelectOptions) (string, []interface{}, error) {
+	return generateExistsQuery(m, table, options)
 }
 
 func (m *MySQLQueryGenerator) GenerateTransactionQuery(queries []string) string {
 	return fmt.Sprintf("START TRANSACTION;\n%s;\nCOMMIT;", strings.Join(queries, ";\n"))
 }
-func (m *MySQLQueryGenerator) GenerateAggregationQuery(table string, columns []string, aggregations map[string]string, condition string, groupBy []string, orderBy string) string {
-	var aggClauses []string
-	for column, aggFunc := range aggregations {
-		aggClauses = append(aggClauses, fmt.Sprintf("%s(%s)", aggFunc, column))
-	}
-	return fmt.Sprintf("SELECT %s FROM %s WHERE %s GROUP BY %s ORDER BY %s;", strings.Join(aggClauses, ", "), table, condition, strings.Join(groupBy, ", "), orderBy)
+
+func (m *MySQLQueryGenerator) GenerateAggregationQuery(table string, aggregations map[string]string, options SelectOptions) (string, []interface{}, error) {
+	return generateAggregationQuery(m, table, aggregations, options)
 }
-func (m *MySQLQueryGenerator) BuildConditionQuery(conditions map[string]interface{}, logicalOperator string) string {
-	var conditionClauses []string
-	for column, value := range conditions {
-		conditionClauses = append(conditionClauses, fmt.Sprintf("%s = %s", column, SanitizeValue(value)))
+
+// BuildConditionQuery returns the condition with "?" markers, the generator that consumes it binds them,
+// or the error of a condition that cannot be rendered.
+func (m *MySQLQueryGenerator) BuildConditionQuery(condition Condition) (string, []interface{}, error) {
+	if err := conditionErr(condition); err != nil {
+		return "", nil, err
 	}
-	return strings.Join(conditionClauses, fmt.Sprintf(" %s ", logicalOperator))
+	sql, args := buildCondition(m, condition)
+	return sql, args, nil
 }
 
-func (m *MySQLQueryGenerator) GeneratePaginationQuery(table string, columns []string, condition string, orderBy string, page int, pageSize int) string {
-	offset := (page - 1) * pageSize
-	return fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s LIMIT %d OFFSET %d;", FormatColumns(columns), table, condition, orderBy, pageSize, offset)
+func (m *MySQLQueryGenerator) GeneratePaginationQuery(table string, options SelectOptions, page int, pageSize int) (string, []interface{}, error) {
+	return generatePaginationQuery(m, table, options, page, pageSize)
 }
+
 func (m *MySQLQueryGenerator) GenerateCreateIndexQuery(indexName string, table string, columns []string, unique bool) string {
 	uniqueClause := ""
 	if unique {
 		uniqueClause = "UNIQUE "
 	}
-	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s);", uniqueClause, indexName, table, FormatColumns(columns))
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s);", uniqueClause, m.EscapeIdentifier(indexName), m.EscapeIdentifier(table), escapeIdentifiers(m, columns))
 }
 func (m *MySQLQueryGenerator) GenerateDropIndexQuery(indexName string) string {
-	return fmt.Sprintf("DROP INDEX %s;", indexName)
+	return fmt.Sprintf("DROP INDEX %s;", m.EscapeIdentifier(indexName))
 }
 func (m *MySQLQueryGenerator) GenerateAddColumnQuery(table string, columnName string, columnType string, defaultValue interface{}) string {
-	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s DEFAULT %s;", table, columnName, columnType, SanitizeValue(defaultValue))
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s DEFAULT %s;", m.EscapeIdentifier(table), m.EscapeIdentifier(columnName), columnType, m.QuoteLiteral(defaultValue))
 }
 func (m *MySQLQueryGenerator) GenerateModifyColumnQuery(table string, columnName string, columnType string, nullable bool) string {
 	nullableClause := ""
@@ -199,21 +464,83 @@ func (m *MySQLQueryGenerator) GenerateModifyColumnQuery(table string, columnName
 	} else {
 		nullableClause = "NOT NULL"
 	}
-	return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s %s;", table, columnName, columnType, nullableClause)
+	return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s %s;", m.EscapeIdentifier(table), m.EscapeIdentifier(columnName), columnType, nullableClause)
 }
 func (m *MySQLQueryGenerator) GenerateDropColumnQuery(table string, columnName string) string {
-	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, columnName)
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", m.EscapeIdentifier(table), m.EscapeIdentifier(columnName))
+}
+
+func (m *MySQLQueryGenerator) GenerateAddColumnDefinitionQuery(table string, column configs.ColumnDef) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", m.EscapeIdentifier(table), m.columnDefinition(column.Name, column.FieldSchema, false))
+}
+
+// GenerateAlterColumnQuery redefines a column, its keys are changed on their own.
+func (m *MySQLQueryGenerator) GenerateAlterColumnQuery(table string, column configs.ColumnDef) string {
+	fieldSchema := column.FieldSchema
+	fieldSchema.Key = ""
+	return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s;", m.EscapeIdentifier(table), m.columnDefinition(column.Name, fieldSchema, false))
+}
+
+func (m *MySQLQueryGenerator) GenerateAlterPrimaryKeyQuery(table string, current []string, columns []string) string {
+	var clauses []string
+	if len(current) > 0 {
+		clauses = append(clauses, "DROP PRIMARY KEY")
+	}
+	if len(columns) > 0 {
+		clauses = append(clauses, fmt.Sprintf("ADD PRIMARY KEY (%s)", escapeIdentifiers(m, columns)))
+	}
+	return fmt.Sprintf("ALTER TABLE %s %s;", m.EscapeIdentifier(table), strings.Join(clauses, ", "))
+}
+
+func (m *MySQLQueryGenerator) GenerateAddUniqueQuery(table string, unique configs.IndexDef) string {
+	if unique.Name == "" {
+		return fmt.Sprintf("ALTER TABLE %s ADD UNIQUE (%s);", m.EscapeIdentifier(table), escapeIdentifiers(m, unique.Columns))
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE (%s);", m.EscapeIdentifier(table), m.EscapeIdentifier(unique.Name), escapeIdentifiers(m, unique.Columns))
+}
+
+// GenerateDropUniqueQuery drops the index behind a unique constraint, MySQL names an unnamed
+// one after its first column.
+func (m *MySQLQueryGenerator) GenerateDropUniqueQuery(table string, unique configs.IndexDef) string {
+	name := unique.Name
+	if name == "" && len(unique.Columns) > 0 {
+		name = unique.Columns[0]
+	}
+	return fmt.Sprintf("ALTER TABLE %s DROP INDEX %s;", m.EscapeIdentifier(table), m.EscapeIdentifier(name))
+}
+
+func (m *MySQLQueryGenerator) GenerateAddIndexQuery(table string, index configs.IndexDef) string {
+	indexType := ""
+	if index.Type != "" {
+		indexType = strings.ToUpper(index.Type) + " "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s);", indexType, m.EscapeIdentifier(indexName(table, index)), m.EscapeIdentifier(table), escapeIdentifiers(m, index.Columns))
+}
+
+func (m *MySQLQueryGenerator) GenerateDropTableIndexQuery(table string, index configs.IndexDef) string {
+	return fmt.Sprintf("DROP INDEX %s ON %s;", m.EscapeIdentifier(indexName(table, index)), m.EscapeIdentifier(table))
 }
 
 func (m *MySQLQueryGenerator) GenerateAddForeignKeyQuery(table string, columnName string, referencedTable string, referencedColumn string, onDelete string, onUpdate string) string {
-	return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT FK_%s FOREIGN KEY (%s) REFERENCES %s(%s) ON DELETE %s ON UPDATE %s;", table, columnName, columnName, referencedTable, referencedColumn, onDelete, onUpdate)
+	return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s(%s) ON DELETE %s ON UPDATE %s;", m.EscapeIdentifier(table),
+		m.EscapeIdentifier("FK_"+columnName), m.EscapeIdentifier(columnName), m.EscapeIdentifier(referencedTable), m.EscapeIdentifier(referencedColumn), onDelete, onUpdate)
+}
+
+func (m *MySQLQueryGenerator) GenerateAddForeignKeyConstraintQuery(table string, foreignKey configs.ForeignKeyDef) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s;", m.EscapeIdentifier(table), foreignKeyConstraint(m, table, foreignKey))
 }
 func (m *MySQLQueryGenerator) GenerateDropForeignKeyQuery(table string, foreignKeyName string) string {
-	return fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s;", table, foreignKeyName)
+	return fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s;", m.EscapeIdentifier(table), m.EscapeIdentifier(foreignKeyName))
 }
 
-func (m *MySQLQueryGenerator) SanitizeValue(value interface{}) string {
-	return SanitizeValue(value)
+// QuoteLiteral escapes backslashes as well as quotes, unless NoBackslashEscapes is set.
+func (m *MySQLQueryGenerator) QuoteLiteral(value interface{}) string {
+	return formatLiteral(value, func(s string) string {
+		if !m.NoBackslashEscapes {
+			s = strings.ReplaceAll(s, `\`, `\\`)
+		}
+		return quoteString(s)
+	})
 }
 
 func (m *MySQLQueryGenerator) FormatColumns(columns []string) string {
@@ -221,5 +548,24 @@ func (m *MySQLQueryGenerator) FormatColumns(columns []string) string {
 }
 
 func (m *MySQLQueryGenerator) EscapeIdentifier(identifier string) string {
-	return fmt.Sprintf("`%s`", identifier)
+	return fmt.Sprintf("`%s`", strings.ReplaceAll(identifier, "`", "``"))
+}
+
+// GenerateLimitClause returns the LIMIT clause, limit and offset 0 are left out. An offset
+// needs a limit here, the largest row count stands in for none.
+func (m *MySQLQueryGenerator) GenerateLimitClause(limit int, offset int) string {
+	switch {
+	case limit > 0 && offset > 0:
+		return fmt.Sprintf("LIMIT %d OFFSET %d", limit, offset)
+	case limit > 0:
+		return fmt.Sprintf("LIMIT %d", limit)
+	case offset > 0:
+		return fmt.Sprintf("LIMIT 18446744073709551615 OFFSET %d", offset)
+	}
+	return ""
+}
+
+// Placeholder returns the bind parameter marker, which is positionless for this dialect.
+func (m *MySQLQueryGenerator) Placeholder(index int) string {
+	return "?"
 }
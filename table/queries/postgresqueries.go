@@ -0,0 +1,736 @@
+package queries
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sqldocify/configs"
+	"strings"
+)
+
+type PostgreSQLQueryGenerator struct{}
+
+func (p *PostgreSQLQueryGenerator) GenerateGetSchemaQuery(db *sql.DB, tablename string) (configs.TableSchema, error) {
+	query := `SELECT column_name, data_type, character_maximum_length, numeric_precision, numeric_scale, is_nullable, column_default, is_identity,
+			collation_name, is_generated, generation_expression, col_description(format('%I.%I', table_schema, table_name)::regclass, ordinal_position)
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1
+		ORDER BY ordinal_position;`
+	rows, err := db.Query(query, tablename)
+	if err != nil {
+		return configs.TableSchema{}, err
+	}
+	defer rows.Close()
+
+	var schema configs.TableSchema
+
+	for rows.Next() {
+		var field string
+		var dataType string
+		var maxLength sql.NullInt64
+		var precision sql.NullInt64
+		var scale sql.NullInt64
+		var isNull string
+		var defaultValue sql.NullString
+		var isIdentity string
+		var collation sql.NullString
+		var isGenerated string
+		var generated sql.NullString
+		var comment sql.NullString
+
+		if err := rows.Scan(&field, &dataType, &maxLength, &precision, &scale, &isNull, &defaultValue, &isIdentity,
+			&collation, &isGenerated, &generated, &comment); err != nil {
+			return configs.TableSchema{}, err
+		}
+
+		fieldSchema := configs.FieldSchema{
+			Type:      fieldTypeFromPostgres(dataType, maxLength, precision, scale),
+			Null:      isNull,
+			Collation: collation.String,
+			Comment:   comment.String,
+		}
+		switch {
+		case isIdentity == "YES" || strings.HasPrefix(defaultValue.String, "nextval("):
+			fieldSchema.Extra = "auto_increment"
+		case isGenerated == "ALWAYS":
+			fieldSchema.Generated = generated.String
+			fieldSchema.GeneratedType = "STORED"
+		case defaultValue.Valid:
+			value := postgresDefault(defaultValue.String)
+			fieldSchema.Default = &value
+		}
+		schema.Columns = append(schema.Columns, configs.ColumnDef{Name: field, FieldSchema: fieldSchema})
+	}
+	if err = rows.Err(); err != nil {
+		return configs.TableSchema{}, err
+	}
+	if len(schema.Columns) == 0 {
+		return configs.TableSchema{}, fmt.Errorf("table %s does not exist", tablename)
+	}
+
+	if err := p.applyKeys(db, tablename, &schema); err != nil {
+		return configs.TableSchema{}, err
+	}
+	return schema, nil
+}
+
+// applyKeys marks primary key columns with PRI and single-column unique constraints with UNI,
+// composite keys and unique constraints are stored on the table.
+func (p *PostgreSQLQueryGenerator) applyKeys(db *sql.DB, tablename string, schema *configs.TableSchema) error {
+	query := `SELECT tc.constraint_name, tc.constraint_type, kcu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON tc.constraint_name = kcu.constraint_name AND tc.table_schema = kcu.table_schema AND tc.table_name = kcu.table_name
+		WHERE tc.table_schema = current_schema() AND tc.table_name = $1 AND tc.constraint_type IN ('PRIMARY KEY', 'UNIQUE')
+		ORDER BY tc.constraint_type, tc.constraint_name, kcu.ordinal_position;`
+	rows, err := db.Query(query, tablename)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var constraints indexList
+	constraintTypes := make(map[string]string)
+	for rows.Next() {
+		var name, constraintType, column string
+		if err := rows.Scan(&name, &constraintType, &column); err != nil {
+			return err
+		}
+		constraintTypes[name] = constraintType
+		constraints.add(name, column)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range constraints.names {
+		columns := constraints.columns[name]
+		if constraintTypes[name] == "UNIQUE" && len(columns) > 1 {
+			schema.Uniques = append(schema.Uniques, configs.IndexDef{Name: name, Columns: columns})
+			continue
+		}
+		if constraintTypes[name] == "PRIMARY KEY" && len(columns) > 1 {
+			schema.PrimaryKey = columns
+		}
+		for _, column := range columns {
+			fieldSchema, ok := schema.Column(column)
+			if !ok {
+				continue
+			}
+			switch {
+			case constraintTypes[name] == "PRIMARY KEY":
+				fieldSchema.Key = "PRI"
+			case fieldSchema.Key == "":
+				fieldSchema.Key = "UNI"
+			}
+			schema.SetColumn(column, fieldSchema)
+		}
+	}
+	if err := p.applyForeignKeys(db, tablename, schema); err != nil {
+		return err
+	}
+	return p.applyIndexes(db, tablename, schema)
+}
+
+// postgresActions maps the action codes of pg_constraint onto their SQL names.
+var postgresActions = map[string]string{"a": "", "r": "RESTRICT", "c": "CASCADE", "n": "SET NULL", "d": "SET DEFAULT"}
+
+// applyForeignKeys reads the foreign keys of a table from pg_constraint, which keeps the
+// column pairs of composite keys in order.
+func (p *PostgreSQLQueryGenerator) applyForeignKeys(db *sql.DB, tablename string, schema *configs.TableSchema) error {
+	query := `SELECT c.conname, a.attname, rt.relname, ra.attname, c.confdeltype, c.confupdtype
+		FROM pg_constraint c
+		JOIN pg_class t ON t.oid = c.conrelid
+		JOIN pg_class rt ON rt.oid = c.confrelid
+		CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, refattnum, n)
+		JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+		JOIN pg_attribute ra ON ra.attrelid = c.confrelid AND ra.attnum = k.refattnum
+		WHERE c.contype = 'f' AND t.relname = $1 AND t.relnamespace = current_schema()::regnamespace
+		ORDER BY c.conname, k.n;`
+	rows, err := db.Query(query, tablename)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, column, referencedTable, referencedColumn, deleteAction, updateAction string
+		if err := rows.Scan(&name, &column, &referencedTable, &referencedColumn, &deleteAction, &updateAction); err != nil {
+			return err
+		}
+		last := len(schema.ForeignKeys) - 1
+		if last >= 0 && schema.ForeignKeys[last].Name == name {
+			schema.ForeignKeys[last].Columns = append(schema.ForeignKeys[last].Columns, column)
+			schema.ForeignKeys[last].ReferencedColumns = append(schema.ForeignKeys[last].ReferencedColumns, referencedColumn)
+			continue
+		}
+		schema.ForeignKeys = append(schema.ForeignKeys, configs.ForeignKeyDef{
+			Name:              name,
+			Columns:           []string{column},
+			ReferencedTable:   referencedTable,
+			ReferencedColumns: []string{referencedColumn},
+			OnDelete:          postgresActions[deleteAction],
+			OnUpdate:          postgresActions[updateAction],
+		})
+	}
+	return rows.Err()
+}
+
+var postgresFullTextColumn = regexp.MustCompile(`(?i)coalesce\(\(?"?([A-Za-z_][A-Za-z0-9_]*)"?\)?`)
+
+// applyIndexes reads the secondary indexes, those backing a constraint are covered by applyKeys.
+// A GIN index over to_tsvector is read back as a FULLTEXT index.
+func (p *PostgreSQLQueryGenerator) applyIndexes(db *sql.DB, tablename string, schema *configs.TableSchema) error {
+	query := `SELECT i.relname, ix.indisunique, am.amname, pg_get_indexdef(ix.indexrelid),
+			array_to_string(ARRAY(
+				SELECT a.attname FROM unnest(ix.indkey) WITH ORDINALITY AS k(attnum, n)
+				JOIN pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = k.attnum
+				ORDER BY k.n), ',')
+		FROM pg_index ix
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_am am ON am.oid = i.relam
+		WHERE t.relname = $1 AND t.relnamespace = current_schema()::regnamespace
+			AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = ix.indexrelid)
+		ORDER BY i.relname;`
+	rows, err := db.Query(query, tablename)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, method, definition, columnList string
+		var unique bool
+		if err := rows.Scan(&name, &unique, &method, &definition, &columnList); err != nil {
+			return err
+		}
+		index := configs.IndexDef{Name: name}
+		if columnList != "" {
+			index.Columns = strings.Split(columnList, ",")
+		}
+		switch {
+		case method == "gin" && strings.Contains(definition, "to_tsvector"):
+			index.Type = "FULLTEXT"
+			index.Columns = nil
+			for _, match := range postgresFullTextColumn.FindAllStringSubmatch(definition, -1) {
+				index.Columns = append(index.Columns, match[1])
+			}
+		case unique:
+			index.Type = "UNIQUE"
+		}
+		if len(index.Columns) == 0 {
+			// Expression indexes cannot be described by the schema.
+			continue
+		}
+		schema.Indexes = append(schema.Indexes, index)
+	}
+	return rows.Err()
+}
+
+var postgresDefaultCast = regexp.MustCompile(`^(.*?)(::[a-z_ ]+(\(\d+(,\d+)?\))?(\[\])?)+$`)
+
+// postgresDefault strips the type casts PostgreSQL adds to a column default,
+// 'active'::character varying becomes 'active'.
+func postgresDefault(value string) string {
+	if match := postgresDefaultCast.FindStringSubmatch(value); match != nil {
+		return match[1]
+	}
+	return value
+}
+
+// NormalizeSchema returns a schema the way GenerateGetSchemaQuery reports it once the table
+// is created. Types go through the PostgreSQL mapping and back, the attributes PostgreSQL
+// has no place for are dropped.
+func (p *PostgreSQLQueryGenerator) NormalizeSchema(schema configs.TableSchema) configs.TableSchema {
+	normalized := schema.Copy()
+	for i, column := range normalized.Columns {
+		fieldSchema := column.FieldSchema
+		columnType := postgresColumnType(fieldSchema.Type)
+		fieldSchema.Type = postgresReportedType(columnType)
+		fieldSchema.Charset = ""
+		fieldSchema.Unsigned = false
+		fieldSchema.OnUpdate = ""
+		switch {
+		case strings.Contains(strings.ToLower(fieldSchema.Extra), "auto_increment"):
+			fieldSchema.Extra = "auto_increment"
+			fieldSchema.Default = nil
+		case fieldSchema.Generated != "":
+			fieldSchema.GeneratedType = "STORED"
+			fieldSchema.Default = nil
+		case fieldSchema.Default != nil:
+			value := postgresDefaultValue(*fieldSchema.Default, columnType)
+			fieldSchema.Default = &value
+		}
+		if !strings.Contains(strings.ToLower(fieldSchema.Extra), "auto_increment") {
+			fieldSchema.Extra = ""
+		}
+		normalized.Columns[i].FieldSchema = fieldSchema
+	}
+	return normalized
+}
+
+// postgresReportedType names a PostgreSQL column type the way fieldTypeFromPostgres reports it.
+func postgresReportedType(columnType string) string {
+	lower := strings.ToLower(columnType)
+	switch {
+	case lower == "integer":
+		return "int"
+	case lower == "double precision":
+		return "double"
+	case lower == "real":
+		return "float"
+	case lower == "bytea":
+		return "blob"
+	case strings.HasPrefix(lower, "numeric"):
+		return "decimal" + strings.TrimPrefix(lower, "numeric")
+	}
+	return lower
+}
+
+// fieldTypeFromPostgres turns information_schema type details into the MySQL style type names
+// used by configs.FieldSchema, so schemas stay comparable across dialects.
+func fieldTypeFromPostgres(dataType string, maxLength, precision, scale sql.NullInt64) string {
+	switch dataType {
+	case "character varying":
+		if maxLength.Valid {
+			return fmt.Sprintf("varchar(%d)", maxLength.Int64)
+		}
+		return "varchar"
+	case "character":
+		if maxLength.Valid {
+			return fmt.Sprintf("char(%d)", maxLength.Int64)
+		}
+		return "char"
+	case "integer":
+		return "int"
+	case "numeric":
+		if precision.Valid {
+			return fmt.Sprintf("decimal(%d,%d)", precision.Int64, scale.Int64)
+		}
+		return "decimal"
+	case "double precision":
+		return "double"
+	case "real":
+		return "float"
+	case "timestamp without time zone":
+		return "timestamp"
+	case "timestamp with time zone":
+		return "timestamptz"
+	case "time without time zone":
+		return "time"
+	case "bytea":
+		return "blob"
+	}
+	return dataType
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateGetAllTablesQuery(db *sql.DB) ([]string, error) {
+	query := "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' ORDER BY table_name;"
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+	tables := []string{}
+	var tableName string
+	for rows.Next() {
+		if err := rows.Scan(&tableName); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		tables = append(tables, tableName)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return tables, nil
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateCreateTableQuery(nm string, schema configs.TableSchema) string {
+	var columnStrings []string
+	var comments []string
+	for _, column := range schema.Columns {
+		columnStrings = append(columnStrings, p.columnDefinition(column.Name, column.FieldSchema, inlinePrimaryKey(schema, column.Name)))
+		if column.Comment != "" {
+			comments = append(comments, fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s;", p.EscapeIdentifier(nm), p.EscapeIdentifier(column.Name), p.QuoteLiteral(column.Comment)))
+		}
+	}
+	columnStrings = append(columnStrings, tableConstraints(p, nm, schema)...)
+
+	// PostgreSQL has no inline column comments or indexes, they follow as separate statements.
+	statements := []string{fmt.Sprintf("CREATE TABLE %s (%s);", p.EscapeIdentifier(nm), strings.Join(columnStrings, ", "))}
+	statements = append(statements, comments...)
+	for _, index := range schema.Indexes {
+		statements = append(statements, p.createIndexStatement(nm, index))
+	}
+	return strings.Join(statements, "\n")
+}
+
+// createIndexStatement renders an index of the schema. FULLTEXT becomes a GIN index over the
+// text search vector of the columns.
+func (p *PostgreSQLQueryGenerator) createIndexStatement(table string, index configs.IndexDef) string {
+	name := indexName(table, index)
+	switch strings.ToUpper(index.Type) {
+	case "FULLTEXT":
+		parts := make([]string, len(index.Columns))
+		for i, column := range index.Columns {
+			parts[i] = fmt.Sprintf("coalesce(%s, '')", p.EscapeIdentifier(column))
+		}
+		return fmt.Sprintf("CREATE INDEX %s ON %s USING GIN (to_tsvector('simple', %s));", p.EscapeIdentifier(name), p.EscapeIdentifier(table), strings.Join(parts, " || ' ' || "))
+	case "UNIQUE":
+		return p.GenerateCreateIndexQuery(name, table, index.Columns, true)
+	}
+	return p.GenerateCreateIndexQuery(name, table, index.Columns, false)
+}
+
+func (p *PostgreSQLQueryGenerator) columnDefinition(column string, fieldSchema configs.FieldSchema, primary bool) string {
+	columnType := postgresColumnType(fieldSchema.Type)
+	colDef := fmt.Sprintf("%s %s", p.EscapeIdentifier(column), columnType)
+	if fieldSchema.Collation != "" {
+		colDef += " COLLATE " + p.EscapeIdentifier(fieldSchema.Collation)
+	}
+	switch {
+	case strings.Contains(strings.ToLower(fieldSchema.Extra), "auto_increment"):
+		colDef += " GENERATED BY DEFAULT AS IDENTITY"
+	case fieldSchema.Generated != "":
+		// Only stored generated columns are supported.
+		colDef += fmt.Sprintf(" GENERATED ALWAYS AS (%s) STORED", fieldSchema.Generated)
+	case fieldSchema.Default != nil:
+		colDef += " DEFAULT " + postgresDefaultValue(*fieldSchema.Default, columnType)
+	}
+	if fieldSchema.Null == "NO" {
+		colDef += " NOT NULL"
+	}
+	if fieldSchema.Key == "UNI" {
+		colDef += " UNIQUE"
+	}
+	if primary {
+		colDef += " PRIMARY KEY"
+	}
+	if values := enumValues(fieldSchema.Type); values != "" {
+		colDef += fmt.Sprintf(" CHECK (%s IN (%s))", p.EscapeIdentifier(column), values)
+	}
+	return colDef
+}
+
+// postgresDefaultValue adapts a MySQL style default, numeric booleans of a tinyint(1) column
+// become TRUE and FALSE.
+func postgresDefaultValue(value string, columnType string) string {
+	if columnType == "boolean" {
+		switch strings.Trim(value, "'") {
+		case "0":
+			return "FALSE"
+		case "1":
+			return "TRUE"
+		}
+	}
+	return value
+}
+
+var postgresTypeLength = regexp.MustCompile(`\(\d+\)`)
+
+// postgresColumnType maps a MySQL style column type onto its PostgreSQL equivalent.
+func postgresColumnType(columnType string) string {
+	columnType = strings.TrimSpace(columnType)
+	if strings.HasSuffix(strings.ToLower(columnType), "unsigned") {
+		columnType = strings.TrimSpace(columnType[:len(columnType)-len("unsigned")])
+	}
+	lower := strings.ToLower(columnType)
+	if strings.HasPrefix(lower, "enum(") || strings.HasPrefix(lower, "set(") {
+		return "text"
+	}
+	if lower == "tinyint(1)" {
+		return "boolean"
+	}
+
+	base := lower
+	if !strings.HasPrefix(lower, "varchar") && !strings.HasPrefix(lower, "char") &&
+		!strings.HasPrefix(lower, "decimal") && !strings.HasPrefix(lower, "numeric") {
+		// Display widths like int(11) have no meaning in PostgreSQL.
+		base = postgresTypeLength.ReplaceAllString(lower, "")
+	}
+	switch base {
+	case "int", "integer", "mediumint":
+		return "integer"
+	case "tinyint", "smallint":
+		return "smallint"
+	case "bigint":
+		return "bigint"
+	case "double":
+		return "double precision"
+	case "float":
+		return "real"
+	case "datetime":
+		return "timestamp"
+	case "tinytext", "mediumtext", "longtext":
+		return "text"
+	case "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary":
+		return "bytea"
+	}
+	return columnType
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateTableExistsQuery(table string) (string, []interface{}) {
+	query := "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?;"
+	return bindPlaceholders(query, p.Placeholder), []interface{}{table}
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateInsertQuery(table string, columns []string, values []interface{}) (string, []interface{}) {
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", p.EscapeIdentifier(table), escapeIdentifiers(p, columns), placeholders(len(values)))
+	return bindPlaceholders(query, p.Placeholder), values
+}
+
+// GenerateInsertReturningQuery is used instead of LastInsertId, which lib/pq does not support.
+func (p *PostgreSQLQueryGenerator) GenerateInsertReturningQuery(table string, columns []string, values []interface{}, returning string) (string, []interface{}) {
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING %s;", p.EscapeIdentifier(table), escapeIdentifiers(p, columns), placeholders(len(values)), p.EscapeIdentifier(returning))
+	return bindPlaceholders(query, p.Placeholder), values
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateMultipleInsertQuery(table string, columns []string, values [][]interface{}) (string, []interface{}) {
+	var valueStrings []string
+	var args []interface{}
+	for _, val := range values {
+		valueStrings = append(valueStrings, fmt.Sprintf("(%s)", placeholders(len(val))))
+		args = append(args, val...)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s;", p.EscapeIdentifier(table), escapeIdentifiers(p, columns), strings.Join(valueStrings, ", "))
+	return bindPlaceholders(query, p.Placeholder), args
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateUpdateQuery(table string, updates map[string]interface{}, condition Condition) (string, []interface{}, error) {
+	return generateUpdateQuery(p, table, updates, condition)
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateDeleteQuery(table string, condition Condition) (string, []interface{}, error) {
+	return generateDeleteQuery(p, table, condition)
+}
+
+// GenerateMultipleDeleteQuery deletes the rows matching any of conditions, none deletes nothing.
+func (p *PostgreSQLQueryGenerator) GenerateMultipleDeleteQuery(table string, conditions []Condition) (string, []interface{}, error) {
+	return generateDeleteQuery(p, table, Or(conditions...))
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateSelectQuery(table string, options SelectOptions) (string, []interface{}, error) {
+	return generateSelectQuery(p, table, options)
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateBatchInsertQuery(table string, columns []string, batchValues [][]interface{}) (string, []interface{}) {
+	return p.GenerateMultipleInsertQuery(table, columns, batchValues)
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateUpsertQuery(table string, columns []string, values []interface{}, conflictColumns []string, updates map[string]interface{}) (string, []interface{}) {
+	var setClauses []string
+	args := append([]interface{}{}, values...)
+	for _, column := range sortedKeys(updates) {
+		setClauses = append(setClauses, p.EscapeIdentifier(column)+" = ?")
+		args = append(args, updates[column])
+	}
+	if len(setClauses) == 0 {
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO NOTHING;", p.EscapeIdentifier(table), escapeIdentifiers(p, columns), placeholders(len(values)), escapeIdentifiers(p, conflictColumns))
+		return bindPlaceholders(query, p.Placeholder), args
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s;", p.EscapeIdentifier(table), escapeIdentifiers(p, columns), placeholders(len(values)), escapeIdentifiers(p, conflictColumns), strings.Join(setClauses, ", "))
+	return bindPlaceholders(query, p.Placeholder), args
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateJoinQuery(table string, joins []Join, options SelectOptions) (string, []interface{}, error) {
+	return generateJoinQuery(p, table, joins, options)
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateCountQuery(table string, options SelectOptions) (string, []interface{}, error) {
+	return generateCountQuery(p, table, options)
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateExistsQuery(table string, options SelectOptions) (string, []interface{}, error) {
+	return generateExistsQuery(p, table, options)
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateTransactionQuery(queries []string) string {
+	return fmt.Sprintf("BEGIN;\n%s;\nCOMMIT;", strings.Join(queries, ";\n"))
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateAggregationQuery(table string, aggregations map[string]string, options SelectOptions) (string, []interface{}, error) {
+	return generateAggregationQuery(p, table, aggregations, options)
+}
+
+// BuildConditionQuery returns the condition with "?" markers, the generator that consumes it binds them,
+// or the error of a condition that cannot be rendered.
+func (p *PostgreSQLQueryGenerator) BuildConditionQuery(condition Condition) (string, []interface{}, error) {
+	if err := conditionErr(condition); err != nil {
+		return "", nil, err
+	}
+	sql, args := buildCondition(p, condition)
+	return sql, args, nil
+}
+
+func (p *PostgreSQLQueryGenerator) GeneratePaginationQuery(table string, options SelectOptions, page int, pageSize int) (string, []interface{}, error) {
+	return generatePaginationQuery(p, table, options, page, pageSize)
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateCreateIndexQuery(indexName string, table string, columns []string, unique bool) string {
+	uniqueClause := ""
+	if unique {
+		uniqueClause = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s);", uniqueClause, p.EscapeIdentifier(indexName), p.EscapeIdentifier(table), escapeIdentifiers(p, columns))
+}
+
+// GenerateDropIndexQuery needs no table name, PostgreSQL index names are unique per schema.
+func (p *PostgreSQLQueryGenerator) GenerateDropIndexQuery(indexName string) string {
+	return fmt.Sprintf("DROP INDEX IF EXISTS %s;", p.EscapeIdentifier(indexName))
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateAddColumnQuery(table string, columnName string, columnType string, defaultValue interface{}) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s DEFAULT %s;", p.EscapeIdentifier(table), p.EscapeIdentifier(columnName), postgresColumnType(columnType), p.QuoteLiteral(defaultValue))
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateModifyColumnQuery(table string, columnName string, columnType string, nullable bool) string {
+	nullableClause := "SET NOT NULL"
+	if nullable {
+		nullableClause = "DROP NOT NULL"
+	}
+	pgType := postgresColumnType(columnType)
+	column := p.EscapeIdentifier(columnName)
+	return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s, ALTER COLUMN %s %s;", p.EscapeIdentifier(table), column, pgType, column, pgType, column, nullableClause)
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateDropColumnQuery(table string, columnName string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", p.EscapeIdentifier(table), p.EscapeIdentifier(columnName))
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateAddColumnDefinitionQuery(table string, column configs.ColumnDef) string {
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", p.EscapeIdentifier(table), p.columnDefinition(column.Name, column.FieldSchema, false))
+	if column.Comment != "" {
+		query += fmt.Sprintf("\nCOMMENT ON COLUMN %s.%s IS %s;", p.EscapeIdentifier(table), p.EscapeIdentifier(column.Name), p.QuoteLiteral(column.Comment))
+	}
+	return query
+}
+
+// GenerateAlterColumnQuery changes the type, collation, nullability, default and comment of a
+// column. Identity and generated expressions are left as they are, keys change on their own.
+func (p *PostgreSQLQueryGenerator) GenerateAlterColumnQuery(table string, column configs.ColumnDef) string {
+	pgType := postgresColumnType(column.Type)
+	name := p.EscapeIdentifier(column.Name)
+	typeClause := fmt.Sprintf("ALTER COLUMN %s TYPE %s", name, pgType)
+	if column.Collation != "" {
+		typeClause += " COLLATE " + p.EscapeIdentifier(column.Collation)
+	}
+	clauses := []string{fmt.Sprintf("%s USING %s::%s", typeClause, name, pgType)}
+	if column.Null == "NO" {
+		clauses = append(clauses, fmt.Sprintf("ALTER COLUMN %s SET NOT NULL", name))
+	} else {
+		clauses = append(clauses, fmt.Sprintf("ALTER COLUMN %s DROP NOT NULL", name))
+	}
+	autoIncrement := strings.Contains(strings.ToLower(column.Extra), "auto_increment")
+	switch {
+	case autoIncrement || column.Generated != "":
+	case column.Default != nil:
+		clauses = append(clauses, fmt.Sprintf("ALTER COLUMN %s SET DEFAULT %s", name, postgresDefaultValue(*column.Default, pgType)))
+	default:
+		clauses = append(clauses, fmt.Sprintf("ALTER COLUMN %s DROP DEFAULT", name))
+	}
+	query := fmt.Sprintf("ALTER TABLE %s %s;", p.EscapeIdentifier(table), strings.Join(clauses, ", "))
+	comment := "NULL"
+	if column.Comment != "" {
+		comment = p.QuoteLiteral(column.Comment)
+	}
+	return query + fmt.Sprintf("\nCOMMENT ON COLUMN %s.%s IS %s;", p.EscapeIdentifier(table), name, comment)
+}
+
+// GenerateAlterPrimaryKeyQuery relies on the default constraint name <table>_pkey.
+func (p *PostgreSQLQueryGenerator) GenerateAlterPrimaryKeyQuery(table string, current []string, columns []string) string {
+	var clauses []string
+	if len(current) > 0 {
+		clauses = append(clauses, "DROP CONSTRAINT "+p.EscapeIdentifier(table+"_pkey"))
+	}
+	if len(columns) > 0 {
+		clauses = append(clauses, fmt.Sprintf("ADD PRIMARY KEY (%s)", escapeIdentifiers(p, columns)))
+	}
+	return fmt.Sprintf("ALTER TABLE %s %s;", p.EscapeIdentifier(table), strings.Join(clauses, ", "))
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateAddUniqueQuery(table string, unique configs.IndexDef) string {
+	if unique.Name == "" {
+		return fmt.Sprintf("ALTER TABLE %s ADD UNIQUE (%s);", p.EscapeIdentifier(table), escapeIdentifiers(p, unique.Columns))
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE (%s);", p.EscapeIdentifier(table), p.EscapeIdentifier(unique.Name), escapeIdentifiers(p, unique.Columns))
+}
+
+// GenerateDropUniqueQuery drops a unique constraint, an unnamed one has the default name
+// <table>_<columns>_key.
+func (p *PostgreSQLQueryGenerator) GenerateDropUniqueQuery(table string, unique configs.IndexDef) string {
+	name := unique.Name
+	if name == "" {
+		name = fmt.Sprintf("%s_%s_key", table, strings.Join(unique.Columns, "_"))
+	}
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", p.EscapeIdentifier(table), p.EscapeIdentifier(name))
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateAddIndexQuery(table string, index configs.IndexDef) string {
+	return p.createIndexStatement(table, index)
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateDropTableIndexQuery(table string, index configs.IndexDef) string {
+	return p.GenerateDropIndexQuery(indexName(table, index))
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateAddForeignKeyQuery(table string, columnName string, referencedTable string, referencedColumn string, onDelete string, onUpdate string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s(%s) ON DELETE %s ON UPDATE %s;", p.EscapeIdentifier(table),
+		p.EscapeIdentifier("FK_"+columnName), p.EscapeIdentifier(columnName), p.EscapeIdentifier(referencedTable), p.EscapeIdentifier(referencedColumn), onDelete, onUpdate)
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateAddForeignKeyConstraintQuery(table string, foreignKey configs.ForeignKeyDef) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s;", p.EscapeIdentifier(table), foreignKeyConstraint(p, table, foreignKey))
+}
+
+func (p *PostgreSQLQueryGenerator) GenerateDropForeignKeyQuery(table string, foreignKeyName string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", p.EscapeIdentifier(table), p.EscapeIdentifier(foreignKeyName))
+}
+
+// QuoteLiteral writes booleans as TRUE and FALSE, PostgreSQL does not cast 1/0 to boolean. A
+// string with backslashes becomes an escape string, E'...', which reads the same whether
+// standard_conforming_strings is on or off.
+func (p *PostgreSQLQueryGenerator) QuoteLiteral(value interface{}) string {
+	if v, ok := value.(bool); ok {
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	return formatLiteral(value, func(s string) string {
+		if strings.Contains(s, `\`) {
+			return "E" + quoteString(strings.ReplaceAll(s, `\`, `\\`))
+		}
+		return quoteString(s)
+	})
+}
+
+func (p *PostgreSQLQueryGenerator) FormatColumns(columns []string) string {
+	return FormatColumns(columns)
+}
+
+func (p *PostgreSQLQueryGenerator) EscapeIdentifier(identifier string) string {
+	return fmt.Sprintf(`"%s"`, strings.ReplaceAll(identifier, `"`, `""`))
+}
+
+// TransactionalDDL reports that schema changes can be rolled back.
+func (p *PostgreSQLQueryGenerator) TransactionalDDL() bool {
+	return true
+}
+
+// GenerateLimitClause returns the LIMIT and OFFSET clauses, limit and offset 0 are left out.
+func (p *PostgreSQLQueryGenerator) GenerateLimitClause(limit int, offset int) string {
+	var clauses []string
+	if limit > 0 {
+		clauses = append(clauses, fmt.Sprintf("LIMIT %d", limit))
+	}
+	if offset > 0 {
+		clauses = append(clauses, fmt.Sprintf("OFFSET %d", offset))
+	}
+	return strings.Join(clauses, " ")
+}
+
+// Placeholder returns the positional bind parameter PostgreSQL expects, $1 for the first argument.
+func (p *PostgreSQLQueryGenerator) Placeholder(index int) string {
+	return fmt.Sprintf("$%d", index)
+}
@@ -0,0 +1,198 @@
+package queries
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SelectOptions are the clauses of the SELECT family of generators. Every field is optional:
+// no Columns select *, a nil Where matches every row and a zero Limit or Offset is left out.
+// With adds common table expressions before the select, Unions are appended to it and OrderBy,
+// Limit and Offset then apply to the whole union.
+type SelectOptions struct {
+	With     []CTE
+	Columns  []string
+	Distinct bool
+	Joins    []Join
+	Where    Condition
+	GroupBy  []string
+	Having   Condition
+	Unions   []Union
+	OrderBy  []OrderBy
+	Limit    int
+	Offset   int
+}
+
+// JoinType is the kind of a join.
+type JoinType string
+
+const (
+	InnerJoin JoinType = "INNER"
+	LeftJoin  JoinType = "LEFT"
+	RightJoin JoinType = "RIGHT"
+	CrossJoin JoinType = "CROSS"
+)
+
+// Join joins Table, which may carry an alias like "orders o", on a condition. An empty Type is
+// an INNER join, the On condition of a CROSS join is ignored.
+type Join struct {
+	Type  JoinType
+	Table string
+	On    Condition
+}
+
+// clause returns the join as a clause of a SelectBuilder.
+func (j Join) clause(qg QueryGenerator) joinClause {
+	kind := JoinType(strings.ToUpper(strings.TrimSpace(string(j.Type))))
+	if kind == "" {
+		kind = InnerJoin
+	}
+	on := fragment{}
+	if j.On != nil && kind != CrossJoin {
+		on.sql, on.args = j.On.Build(qg)
+	}
+	return joinClause{kind: string(kind) + " JOIN", table: j.Table, on: on}
+}
+
+// OrderBy is one sort column of a SELECT.
+type OrderBy struct {
+	Column string
+	Desc   bool
+}
+
+// Asc sorts by column in ascending order.
+func Asc(column string) OrderBy {
+	return OrderBy{Column: column}
+}
+
+// Desc sorts by column in descending order.
+func Desc(column string) OrderBy {
+	return OrderBy{Column: column, Desc: true}
+}
+
+func (o OrderBy) String() string {
+	if o.Desc {
+		return o.Column + " DESC"
+	}
+	return o.Column
+}
+
+// OrderByColumns sorts by every column in ascending order, e.g. by a primary key.
+func OrderByColumns(columns ...string) []OrderBy {
+	orderBy := make([]OrderBy, len(columns))
+	for i, column := range columns {
+		orderBy[i] = Asc(column)
+	}
+	return orderBy
+}
+
+// selectBuilder returns a SelectBuilder with the clauses of the options.
+func (o SelectOptions) selectBuilder(qg QueryGenerator, table string) *SelectBuilder {
+	s := NewBuilder(qg).Select(o.Columns...).From(table).Limit(o.Limit).Offset(o.Offset)
+	s.distinct = o.Distinct
+	s.with = append(s.with, o.With...)
+	s.unions = append(s.unions, o.Unions...)
+	for _, join := range o.Joins {
+		if err := conditionErr(join.On); err != nil {
+			if s.err == nil {
+				s.err = err
+			}
+			continue
+		}
+		s.joins = append(s.joins, join.clause(qg))
+	}
+	s.GroupBy(o.GroupBy...)
+	if o.Where != nil {
+		s.Where(o.Where)
+	}
+	if o.Having != nil {
+		s.Having(o.Having)
+	}
+	for _, orderBy := range o.OrderBy {
+		s.OrderBy(orderBy.String())
+	}
+	return s
+}
+
+// generateSelectQuery is GenerateSelectQuery for every dialect.
+func generateSelectQuery(qg QueryGenerator, table string, options SelectOptions) (string, []interface{}, error) {
+	return options.selectBuilder(qg, table).Build()
+}
+
+// generatePaginationQuery selects page, counted from 1, of pageSize rows. A pageSize of 0
+// selects every row.
+func generatePaginationQuery(qg QueryGenerator, table string, options SelectOptions, page int, pageSize int) (string, []interface{}, error) {
+	if page < 1 {
+		page = 1
+	}
+	options.Limit, options.Offset = 0, 0
+	if pageSize > 0 {
+		options.Limit, options.Offset = pageSize, (page-1)*pageSize
+	}
+	return generateSelectQuery(qg, table, options)
+}
+
+// generateCountQuery counts the rows matching options. Grouped, distinct, limited or union
+// selects are counted as a subquery, so the count is the number of rows the select returns.
+func generateCountQuery(qg QueryGenerator, table string, options SelectOptions) (string, []interface{}, error) {
+	if len(options.GroupBy) == 0 && options.Having == nil && !options.Distinct && len(options.Unions) == 0 && options.Limit == 0 && options.Offset == 0 {
+		options.Columns = []string{"COUNT(*)"}
+		options.OrderBy = nil
+		return generateSelectQuery(qg, table, options)
+	}
+	if options.Limit == 0 && options.Offset == 0 {
+		options.OrderBy = nil
+	}
+	return wrapSelect(qg, table, options, "SELECT COUNT(*) FROM (%s) AS counted")
+}
+
+// generateExistsQuery checks whether the select of options returns a row.
+func generateExistsQuery(qg QueryGenerator, table string, options SelectOptions) (string, []interface{}, error) {
+	if len(options.Columns) == 0 && len(options.Unions) == 0 {
+		options.Columns = []string{"1"}
+	}
+	if options.Limit == 0 && options.Offset == 0 {
+		options.OrderBy = nil
+	}
+	return wrapSelect(qg, table, options, "SELECT EXISTS(%s)")
+}
+
+// wrapSelect writes the select of options into format as a subquery. Its WITH clause is moved
+// before the outer select, so the common table expressions stay at the top of the statement.
+func wrapSelect(qg QueryGenerator, table string, options SelectOptions, format string) (string, []interface{}, error) {
+	s := options.selectBuilder(qg, table)
+	if err := s.Err(); err != nil {
+		return "", nil, err
+	}
+	with := s.renderWith()
+	inner := s.renderSelect()
+	query := with.sql + fmt.Sprintf(format, inner.sql) + ";"
+	return bindPlaceholders(query, qg.Placeholder), append(with.args, inner.args...), nil
+}
+
+// generateJoinQuery selects from table followed by joins, after the joins of options.
+func generateJoinQuery(qg QueryGenerator, table string, joins []Join, options SelectOptions) (string, []interface{}, error) {
+	options.Joins = append(append([]Join{}, options.Joins...), joins...)
+	return generateSelectQuery(qg, table, options)
+}
+
+// generateAggregationQuery selects options.Columns followed by aggregations, a column/function
+// map like {"amount": "SUM"}, in column order.
+func generateAggregationQuery(qg QueryGenerator, table string, aggregations map[string]string, options SelectOptions) (string, []interface{}, error) {
+	columns := make([]string, 0, len(aggregations))
+	for column := range aggregations {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	selected := append([]string{}, options.Columns...)
+	for _, column := range columns {
+		argument := column
+		if argument != "*" {
+			argument = escapeName(qg, column)
+		}
+		selected = append(selected, fmt.Sprintf("%s(%s)", strings.ToUpper(aggregations[column]), argument))
+	}
+	options.Columns = selected
+	return generateSelectQuery(qg, table, options)
+}
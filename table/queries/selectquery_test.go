@@ -0,0 +1,150 @@
+package queries
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectOptionClauses(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(qg QueryGenerator) (string, []interface{}, error)
+		want  dialectSQL
+		args  []interface{}
+	}{
+		{
+			name: "no options",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GenerateSelectQuery("users", SelectOptions{})
+			},
+			want: sameSQL(`SELECT * FROM "users";`),
+		},
+		{
+			name: "order by several columns",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GenerateSelectQuery("users", SelectOptions{OrderBy: []OrderBy{Desc("created_at"), Asc("name"), {Column: "id"}}})
+			},
+			want: sameSQL(`SELECT * FROM "users" ORDER BY "created_at" DESC, "name", "id";`),
+		},
+		{
+			name: "distinct with grouping",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GenerateSelectQuery("orders", SelectOptions{
+					Columns: []string{"user_id", "COUNT(*)"}, Distinct: true, GroupBy: []string{"user_id"}, Having: Gt("COUNT(*)", 2),
+				})
+			},
+			want: sameSQL(`SELECT DISTINCT "user_id", COUNT(*) FROM "orders" GROUP BY "user_id" HAVING COUNT(*) > ?;`),
+			args: []interface{}{2},
+		},
+		{
+			name: "first page",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GeneratePaginationQuery("users", SelectOptions{OrderBy: OrderByColumns("id"), Limit: 99, Offset: 99}, 0, 20)
+			},
+			want: sameSQL(`SELECT * FROM "users" ORDER BY "id" LIMIT 20;`),
+		},
+		{
+			name: "third page",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GeneratePaginationQuery("users", SelectOptions{Where: Eq("active", true)}, 3, 20)
+			},
+			want: sameSQL(`SELECT * FROM "users" WHERE "active" = ? LIMIT 20 OFFSET 40;`),
+			args: []interface{}{true},
+		},
+		{
+			name: "page without a size",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GeneratePaginationQuery("users", SelectOptions{Offset: 5}, 2, 0)
+			},
+			want: sameSQL(`SELECT * FROM "users";`),
+		},
+		{
+			name: "count without options",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GenerateCountQuery("users", SelectOptions{})
+			},
+			want: sameSQL(`SELECT COUNT(*) FROM "users";`),
+		},
+		{
+			name: "count of groups",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GenerateCountQuery("orders", SelectOptions{Columns: []string{"user_id"}, GroupBy: []string{"user_id"}, OrderBy: OrderByColumns("user_id")})
+			},
+			want: sameSQL(`SELECT COUNT(*) FROM (SELECT "user_id" FROM "orders" GROUP BY "user_id") AS counted;`),
+		},
+		{
+			name: "aggregation",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GenerateAggregationQuery("orders", map[string]string{"total": "sum", "*": "count"}, SelectOptions{
+					Columns: []string{"user_id"}, GroupBy: []string{"user_id"}, OrderBy: []OrderBy{Desc("user_id")},
+				})
+			},
+			want: sameSQL(`SELECT "user_id", COUNT(*), SUM("total") FROM "orders" GROUP BY "user_id" ORDER BY "user_id" DESC;`),
+		},
+	}
+	for _, tt := range tests {
+		for _, dialect := range dialects {
+			t.Run(tt.name+"/"+dialect.name, func(t *testing.T) {
+				query, args, err := tt.build(dialect.qg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := tt.want.of(dialect.name); query != want {
+					t.Errorf("query\n got %s\nwant %s", query, want)
+				}
+				if !reflect.DeepEqual(args, tt.args) {
+					t.Errorf("args = %#v, want %#v", args, tt.args)
+				}
+			})
+		}
+	}
+}
+
+func TestSelectOptionsOnSQLite(t *testing.T) {
+	db := openMemoryDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, team TEXT)",
+		"INSERT INTO users VALUES (1, 'ann', 'b'), (2, 'bob', 'a'), (3, 'cy', 'b'), (4, 'dee', 'a'), (5, 'eve', NULL)",
+	)
+	qg := &SQLiteQueryGenerator{}
+	tests := []struct {
+		name  string
+		build func() (string, []interface{}, error)
+		want  string
+	}{
+		{"order by several columns", func() (string, []interface{}, error) {
+			return qg.GenerateSelectQuery("users", SelectOptions{Columns: []string{"id"}, Where: IsNotNull("team"), OrderBy: []OrderBy{Asc("team"), Desc("id")}})
+		}, "4,2,3,1"},
+		{"second page", func() (string, []interface{}, error) {
+			return qg.GeneratePaginationQuery("users", SelectOptions{Columns: []string{"id"}, OrderBy: OrderByColumns("id")}, 2, 2)
+		}, "3,4"},
+		{"count", func() (string, []interface{}, error) {
+			return qg.GenerateCountQuery("users", SelectOptions{Where: Eq("team", "a")})
+		}, "2"},
+		{"count of groups", func() (string, []interface{}, error) {
+			return qg.GenerateCountQuery("users", SelectOptions{Columns: []string{"team"}, GroupBy: []string{"team"}})
+		}, "3"},
+		{"exists", func() (string, []interface{}, error) {
+			return qg.GenerateExistsQuery("users", SelectOptions{Where: Eq("name", "zed")})
+		}, "0"},
+	}
+	for _, tt := range tests {
+		query, args, err := tt.build()
+		if got := strings.Join(queryStrings(t, db, query, args, err), ","); got != tt.want {
+			t.Errorf("%s: %s = %s, want %s", tt.name, query, got, tt.want)
+		}
+	}
+
+	query, _, err := qg.GenerateSelectQuery("users", SelectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	defer rows.Close()
+	if columns, err := rows.Columns(); err != nil || strings.Join(columns, ",") != "id,name,team" {
+		t.Errorf("%s selected %v, %v", query, columns, err)
+	}
+}
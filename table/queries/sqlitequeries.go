@@ -0,0 +1,807 @@
+package queries
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sqldocify/configs"
+	"strings"
+)
+
+type SQLiteQueryGenerator struct {
+	// MetaStore holds the schemas tables are rebuilt from, nil uses configs.DefaultMetaStore.
+	MetaStore configs.MetaStore
+}
+
+// WithMetaStore returns a copy of the generator rebuilding tables from the schemas in store.
+func (s *SQLiteQueryGenerator) WithMetaStore(store configs.MetaStore) QueryGenerator {
+	bound := *s
+	bound.MetaStore = store
+	return &bound
+}
+
+func (s *SQLiteQueryGenerator) GenerateGetSchemaQuery(db *sql.DB, tablename string) (configs.TableSchema, error) {
+	// table_xinfo also lists generated columns, which table_info leaves out.
+	// The column clauses are read first: an in-memory database has a single connection, which
+	// the open rows below hold until they are read.
+	clauses, err := s.columnClauses(db, tablename)
+	if err != nil {
+		return configs.TableSchema{}, err
+	}
+
+	query := fmt.Sprintf("PRAGMA table_xinfo(%s);", s.EscapeIdentifier(tablename))
+	rows, err := db.Query(query)
+	if err != nil {
+		return configs.TableSchema{}, err
+	}
+	defer rows.Close()
+
+	var schema configs.TableSchema
+	// pk is the 1-based position of a column in the primary key.
+	pkColumns := make(map[int]string)
+
+	for rows.Next() {
+		var cid int
+		var field string
+		var fieldType string
+		var notNull int
+		var defaultValue sql.NullString
+		var pk int
+		var hidden int
+
+		if err := rows.Scan(&cid, &field, &fieldType, &notNull, &defaultValue, &pk, &hidden); err != nil {
+			return configs.TableSchema{}, err
+		}
+
+		fieldSchema := configs.FieldSchema{
+			Type: strings.TrimSpace(strings.TrimSuffix(strings.ToLower(fieldType), "generated always")),
+			Null: "YES",
+		}
+		if notNull == 1 || pk > 0 {
+			fieldSchema.Null = "NO"
+		}
+		if pk > 0 {
+			fieldSchema.Key = "PRI"
+			pkColumns[pk] = field
+		}
+
+		clause := clauses[strings.ToLower(field)]
+		if match := sqliteCollate.FindStringSubmatch(clause); match != nil {
+			fieldSchema.Collation = match[1]
+		}
+		switch {
+		case hidden == 2 || hidden == 3:
+			fieldSchema.Generated = generatedExpression(clause)
+			fieldSchema.GeneratedType = "VIRTUAL"
+			if hidden == 3 {
+				fieldSchema.GeneratedType = "STORED"
+			}
+		case defaultValue.Valid:
+			value := defaultValue.String
+			fieldSchema.Default = &value
+		}
+		schema.Columns = append(schema.Columns, configs.ColumnDef{Name: field, FieldSchema: fieldSchema})
+	}
+	if err = rows.Err(); err != nil {
+		return configs.TableSchema{}, err
+	}
+	rows.Close()
+	if len(schema.Columns) == 0 {
+		return configs.TableSchema{}, fmt.Errorf("table %s does not exist", tablename)
+	}
+
+	// An INTEGER PRIMARY KEY is an alias for the rowid and is filled in automatically.
+	if len(pkColumns) == 1 {
+		fieldSchema, _ := schema.Column(pkColumns[1])
+		if fieldSchema.Type == "integer" {
+			fieldSchema.Extra = "auto_increment"
+			schema.SetColumn(pkColumns[1], fieldSchema)
+		}
+	}
+	if len(pkColumns) > 1 {
+		for position := 1; position <= len(pkColumns); position++ {
+			schema.PrimaryKey = append(schema.PrimaryKey, pkColumns[position])
+		}
+	}
+
+	if err := s.applyIndexes(db, tablename, &schema); err != nil {
+		return configs.TableSchema{}, err
+	}
+	if err := s.applyForeignKeys(db, tablename, &schema); err != nil {
+		return configs.TableSchema{}, err
+	}
+
+	return schema, nil
+}
+
+// NormalizeSchema returns a schema the way GenerateGetSchemaQuery reports it once the table
+// is created. SQLite keeps no comments, character sets, unsigned flags or ON UPDATE clauses,
+// and only an INTEGER PRIMARY KEY counts up by itself, which an auto_increment key becomes.
+func (s *SQLiteQueryGenerator) NormalizeSchema(schema configs.TableSchema) configs.TableSchema {
+	normalized := schema.Copy()
+	for i, column := range normalized.Columns {
+		fieldSchema := column.FieldSchema
+		fieldSchema.Type = strings.ToLower(sqliteColumnType(fieldSchema.Type))
+		if inlinePrimaryKey(schema, column.Name) && strings.Contains(strings.ToLower(fieldSchema.Extra), "auto_increment") {
+			fieldSchema.Type = "integer"
+		}
+		fieldSchema.Comment = ""
+		fieldSchema.Charset = ""
+		fieldSchema.Unsigned = false
+		fieldSchema.OnUpdate = ""
+		fieldSchema.Extra = ""
+		if fieldSchema.Generated != "" {
+			fieldSchema.Default = nil
+		} else if fieldSchema.Default != nil {
+			value := sqliteDefault(*fieldSchema.Default)
+			fieldSchema.Default = &value
+		}
+		normalized.Columns[i].FieldSchema = fieldSchema
+	}
+	if keys := normalized.PrimaryKeyColumns(); len(keys) == 1 {
+		for i, column := range normalized.Columns {
+			if column.Name == keys[0] && column.Type == "integer" {
+				normalized.Columns[i].Extra = "auto_increment"
+			}
+		}
+	}
+	return normalized
+}
+
+var (
+	sqliteCollate       = regexp.MustCompile(`(?i)\bCOLLATE\s+["'\x60]?(\w+)`)
+	sqliteGeneratedExpr = regexp.MustCompile(`(?i)\bAS\s*\(`)
+)
+
+// columnClauses splits the CREATE TABLE statement SQLite keeps for a table into the column
+// definitions, keyed by lower case column name. The pragmas do not report collations and
+// generated column expressions, they are read from here.
+func (s *SQLiteQueryGenerator) columnClauses(db *sql.DB, tablename string) (map[string]string, error) {
+	var createSQL sql.NullString
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?;", tablename).Scan(&createSQL)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	start := strings.Index(createSQL.String, "(")
+	end := strings.LastIndex(createSQL.String, ")")
+	if start < 0 || end < start {
+		return nil, nil
+	}
+	clauses := make(map[string]string)
+	for _, definition := range splitTopLevel(createSQL.String[start+1 : end]) {
+		name, rest := leadingIdentifier(definition)
+		clauses[strings.ToLower(name)] = rest
+	}
+	return clauses, nil
+}
+
+// generatedExpression returns the expression of a GENERATED ALWAYS AS (...) column clause.
+func generatedExpression(clause string) string {
+	loc := sqliteGeneratedExpr.FindStringIndex(clause)
+	if loc == nil {
+		return ""
+	}
+	open := loc[1] - 1
+	if end := closingParen(clause, open); end > open {
+		return strings.TrimSpace(clause[open+1 : end])
+	}
+	return ""
+}
+
+// applyIndexes reads the unique constraints and indexes of a table. A single column unique
+// constraint marks the column with UNI, indexes made by CREATE INDEX are kept as Indexes.
+func (s *SQLiteQueryGenerator) applyIndexes(db *sql.DB, tablename string, schema *configs.TableSchema) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA index_list(%s);", s.EscapeIdentifier(tablename)))
+	if err != nil {
+		return err
+	}
+	type sqliteIndex struct {
+		name   string
+		unique bool
+		origin string
+	}
+	var indexes []sqliteIndex
+	for rows.Next() {
+		var seq int
+		var name string
+		var unique int
+		var origin string
+		var partial int
+		if err := rows.Scan(&seq, &name, &unique, &origin, &partial); err != nil {
+			rows.Close()
+			return err
+		}
+		if origin != "pk" {
+			indexes = append(indexes, sqliteIndex{name: name, unique: unique == 1, origin: origin})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	// index_list reports the newest index first.
+	for i, j := 0, len(indexes)-1; i < j; i, j = i+1, j-1 {
+		indexes[i], indexes[j] = indexes[j], indexes[i]
+	}
+
+	for _, index := range indexes {
+		columns, err := s.indexColumns(db, index.name)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			// Expression indexes cannot be described by the schema.
+			continue
+		}
+		switch {
+		case index.origin == "u" && len(columns) == 1:
+			if fieldSchema, ok := schema.Column(columns[0]); ok && fieldSchema.Key == "" {
+				fieldSchema.Key = "UNI"
+				schema.SetColumn(columns[0], fieldSchema)
+			}
+		case index.origin == "u":
+			// Unique constraints are backed by automatic indexes, their names mean nothing.
+			schema.Uniques = append(schema.Uniques, configs.IndexDef{Columns: columns})
+		default:
+			indexDef := configs.IndexDef{Name: index.name, Columns: columns}
+			if index.unique {
+				indexDef.Type = "UNIQUE"
+			}
+			schema.Indexes = append(schema.Indexes, indexDef)
+		}
+	}
+	return nil
+}
+
+// applyForeignKeys reads the foreign keys of a table. SQLite does not report constraint names,
+// they are left empty. A reference without columns points at the primary key of the other table.
+func (s *SQLiteQueryGenerator) applyForeignKeys(db *sql.DB, tablename string, schema *configs.TableSchema) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA foreign_key_list(%s);", s.EscapeIdentifier(tablename)))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var ids []int
+	byID := make(map[int]*configs.ForeignKeyDef)
+	implicit := make(map[int]bool)
+	for rows.Next() {
+		var id, seq int
+		var referencedTable, column string
+		var referencedColumn sql.NullString
+		var onUpdate, onDelete, match string
+		if err := rows.Scan(&id, &seq, &referencedTable, &column, &referencedColumn, &onUpdate, &onDelete, &match); err != nil {
+			return err
+		}
+		foreignKey, ok := byID[id]
+		if !ok {
+			foreignKey = &configs.ForeignKeyDef{
+				ReferencedTable: referencedTable,
+				OnDelete:        referentialAction(onDelete),
+				OnUpdate:        referentialAction(onUpdate),
+			}
+			byID[id] = foreignKey
+			ids = append(ids, id)
+		}
+		foreignKey.Columns = append(foreignKey.Columns, column)
+		if referencedColumn.Valid {
+			foreignKey.ReferencedColumns = append(foreignKey.ReferencedColumns, referencedColumn.String)
+		} else {
+			implicit[id] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	// foreign_key_list reports the last declared key first.
+	for i := len(ids) - 1; i >= 0; i-- {
+		foreignKey := byID[ids[i]]
+		if implicit[ids[i]] {
+			keys, err := s.primaryKeyColumns(db, foreignKey.ReferencedTable)
+			if err != nil {
+				return err
+			}
+			foreignKey.ReferencedColumns = keys
+		}
+		schema.ForeignKeys = append(schema.ForeignKeys, *foreignKey)
+	}
+	return nil
+}
+
+// primaryKeyColumns returns the primary key columns of a table in key order.
+func (s *SQLiteQueryGenerator) primaryKeyColumns(db *sql.DB, tablename string) ([]string, error) {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk;", tablename)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		keys = append(keys, name)
+	}
+	return keys, rows.Err()
+}
+
+// indexColumns returns the columns of an index in index order, nil when it covers an expression.
+func (s *SQLiteQueryGenerator) indexColumns(db *sql.DB, index string) ([]string, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA index_info(%s);", s.EscapeIdentifier(index)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var columns []string
+	for rows.Next() {
+		var seqno int
+		var cid int
+		var name sql.NullString
+		if err := rows.Scan(&seqno, &cid, &name); err != nil {
+			return nil, err
+		}
+		if !name.Valid {
+			return nil, rows.Err()
+		}
+		columns = append(columns, name.String)
+	}
+	return columns, rows.Err()
+}
+
+func (s *SQLiteQueryGenerator) GenerateGetAllTablesQuery(db *sql.DB) ([]string, error) {
+	query := "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name;"
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+	tables := []string{}
+	var tableName string
+	for rows.Next() {
+		if err := rows.Scan(&tableName); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		tables = append(tables, tableName)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return tables, nil
+}
+
+func (s *SQLiteQueryGenerator) GenerateCreateTableQuery(nm string, schema configs.TableSchema) string {
+	statements := []string{fmt.Sprintf("CREATE TABLE %s (%s);", s.EscapeIdentifier(nm), strings.Join(s.columnDefinitions(nm, schema), ", "))}
+	// Indexes are not part of CREATE TABLE in SQLite, they follow as separate statements.
+	statements = append(statements, s.indexStatements(nm, schema)...)
+	return strings.Join(statements, "\n")
+}
+
+// columnDefinitions renders the column list and the table constraints of a CREATE TABLE
+// statement in schema order.
+func (s *SQLiteQueryGenerator) columnDefinitions(table string, schema configs.TableSchema) []string {
+	var columnStrings []string
+	for _, column := range schema.Columns {
+		columnStrings = append(columnStrings, s.columnDefinition(column.Name, column.FieldSchema, inlinePrimaryKey(schema, column.Name)))
+	}
+	return append(columnStrings, tableConstraints(s, table, schema)...)
+}
+
+// indexStatements renders the indexes of a schema. SQLite has no FULLTEXT index outside of
+// FTS virtual tables, it becomes a plain index.
+func (s *SQLiteQueryGenerator) indexStatements(table string, schema configs.TableSchema) []string {
+	var statements []string
+	for _, index := range schema.Indexes {
+		unique := strings.ToUpper(index.Type) == "UNIQUE"
+		statements = append(statements, s.GenerateCreateIndexQuery(indexName(table, index), table, index.Columns, unique))
+	}
+	return statements
+}
+
+func (s *SQLiteQueryGenerator) columnDefinition(column string, fieldSchema configs.FieldSchema, primary bool) string {
+	column = s.EscapeIdentifier(column)
+	autoIncrement := strings.Contains(strings.ToLower(fieldSchema.Extra), "auto_increment")
+	if primary && autoIncrement {
+		// AUTOINCREMENT is only accepted on an INTEGER PRIMARY KEY column.
+		return fmt.Sprintf("%s INTEGER PRIMARY KEY AUTOINCREMENT", column)
+	}
+
+	colDef := fmt.Sprintf("%s %s", column, sqliteColumnType(fieldSchema.Type))
+	if fieldSchema.Generated != "" {
+		colDef += fmt.Sprintf(" GENERATED ALWAYS AS (%s)", fieldSchema.Generated)
+		if fieldSchema.GeneratedType != "" {
+			colDef += " " + strings.ToUpper(fieldSchema.GeneratedType)
+		}
+	}
+	if fieldSchema.Null == "NO" {
+		colDef += " NOT NULL"
+	}
+	if fieldSchema.Default != nil && fieldSchema.Generated == "" {
+		colDef += " DEFAULT " + sqliteDefault(*fieldSchema.Default)
+	}
+	if fieldSchema.Collation != "" {
+		colDef += " COLLATE " + fieldSchema.Collation
+	}
+	if fieldSchema.Key == "UNI" {
+		colDef += " UNIQUE"
+	}
+	if primary {
+		colDef += " PRIMARY KEY"
+	}
+	if values := enumValues(fieldSchema.Type); values != "" {
+		colDef += fmt.Sprintf(" CHECK (%s IN (%s))", column, values)
+	}
+	return colDef
+}
+
+// sqliteDefault parenthesizes function call defaults, SQLite only takes literals, the
+// current time keywords and expressions in parentheses.
+func sqliteDefault(value string) string {
+	if isFunctionDefault(value) && !isCurrentTimeDefault(value) {
+		return "(" + value + ")"
+	}
+	return value
+}
+
+// sqliteColumnType maps a MySQL style column type onto one SQLite understands.
+// SQLite accepts most type names through type affinity, enums become TEXT with a CHECK constraint.
+func sqliteColumnType(columnType string) string {
+	lower := strings.ToLower(strings.TrimSpace(columnType))
+	switch {
+	case strings.HasPrefix(lower, "enum("), strings.HasPrefix(lower, "set("):
+		return "TEXT"
+	case lower == "int", lower == "integer", strings.HasPrefix(lower, "int("):
+		return "INTEGER"
+	}
+	return columnType
+}
+
+// enumValues returns the quoted value list of an enum('a','b') type, or "" for other types.
+func enumValues(columnType string) string {
+	lower := strings.ToLower(strings.TrimSpace(columnType))
+	if !strings.HasPrefix(lower, "enum(") || !strings.HasSuffix(lower, ")") {
+		return ""
+	}
+	trimmed := strings.TrimSpace(columnType)
+	return trimmed[len("enum(") : len(trimmed)-1]
+}
+
+func (s *SQLiteQueryGenerator) GenerateTableExistsQuery(table string) (string, []interface{}) {
+	return "SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?;", []interface{}{table}
+}
+
+func (s *SQLiteQueryGenerator) GenerateInsertQuery(table string, columns []string, values []interface{}) (string, []interface{}) {
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", s.EscapeIdentifier(table), escapeIdentifiers(s, columns), placeholders(len(values)))
+	return bindPlaceholders(query, s.Placeholder), values
+}
+
+func (s *SQLiteQueryGenerator) GenerateMultipleInsertQuery(table string, columns []string, values [][]interface{}) (string, []interface{}) {
+	var valueStrings []string
+	var args []interface{}
+	for _, val := range values {
+		valueStrings = append(valueStrings, fmt.Sprintf("(%s)", placeholders(len(val))))
+		args = append(args, val...)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s;", s.EscapeIdentifier(table), escapeIdentifiers(s, columns), strings.Join(valueStrings, ", "))
+	return bindPlaceholders(query, s.Placeholder), args
+}
+
+func (s *SQLiteQueryGenerator) GenerateUpdateQuery(table string, updates map[string]interface{}, condition Condition) (string, []interface{}, error) {
+	return generateUpdateQuery(s, table, updates, condition)
+}
+
+func (s *SQLiteQueryGenerator) GenerateDeleteQuery(table string, condition Condition) (string, []interface{}, error) {
+	return generateDeleteQuery(s, table, condition)
+}
+
+// GenerateMultipleDeleteQuery deletes the rows matching any of conditions, none deletes nothing.
+func (s *SQLiteQueryGenerator) GenerateMultipleDeleteQuery(table string, conditions []Condition) (string, []interface{}, error) {
+	return generateDeleteQuery(s, table, Or(conditions...))
+}
+
+func (s *SQLiteQueryGenerator) GenerateSelectQuery(table string, options SelectOptions) (string, []interface{}, error) {
+	return generateSelectQuery(s, table, options)
+}
+
+func (s *SQLiteQueryGenerator) GenerateBatchInsertQuery(table string, columns []string, batchValues [][]interface{}) (string, []interface{}) {
+	return s.GenerateMultipleInsertQuery(table, columns, batchValues)
+}
+
+func (s *SQLiteQueryGenerator) GenerateUpsertQuery(table string, columns []string, values []interface{}, conflictColumns []string, updates map[string]interface{}) (string, []interface{}) {
+	var setClauses []string
+	args := append([]interface{}{}, values...)
+	for _, column := range sortedKeys(updates) {
+		setClauses = append(setClauses, s.EscapeIdentifier(column)+" = ?")
+		args = append(args, updates[column])
+	}
+	if len(setClauses) == 0 {
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO NOTHING;", s.EscapeIdentifier(table), escapeIdentifiers(s, columns), placeholders(len(values)), escapeIdentifiers(s, conflictColumns))
+		return bindPlaceholders(query, s.Placeholder), args
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s;", s.EscapeIdentifier(table), escapeIdentifiers(s, columns), placeholders(len(values)), escapeIdentifiers(s, conflictColumns), strings.Join(setClauses, ", "))
+	return bindPlaceholders(query, s.Placeholder), args
+}
+
+func (s *SQLiteQueryGenerator) GenerateJoinQuery(table string, joins []Join, options SelectOptions) (string, []interface{}, error) {
+	return generateJoinQuery(s, table, joins, options)
+}
+
+func (s *SQLiteQueryGenerator) GenerateCountQuery(table string, options SelectOptions) (string, []interface{}, error) {
+	return generateCountQuery(s, table, options)
+}
+
+func (s *SQLiteQueryGenerator) GenerateExistsQuery(table string, options SelectOptions) (string, []interface{}, error) {
+	return generateExistsQuery(s, table, options)
+}
+
+func (s *SQLiteQueryGenerator) GenerateTransactionQuery(queries []string) string {
+	return fmt.Sprintf("BEGIN TRANSACTION;\n%s;\nCOMMIT;", strings.Join(queries, ";\n"))
+}
+
+func (s *SQLiteQueryGenerator) GenerateAggregationQuery(table string, aggregations map[string]string, options SelectOptions) (string, []interface{}, error) {
+	return generateAggregationQuery(s, table, aggregations, options)
+}
+
+// BuildConditionQuery returns the condition with "?" markers, the generator that consumes it binds them,
+// or the error of a condition that cannot be rendered.
+func (s *SQLiteQueryGenerator) BuildConditionQuery(condition Condition) (string, []interface{}, error) {
+	if err := conditionErr(condition); err != nil {
+		return "", nil, err
+	}
+	sql, args := buildCondition(s, condition)
+	return sql, args, nil
+}
+
+func (s *SQLiteQueryGenerator) GeneratePaginationQuery(table string, options SelectOptions, page int, pageSize int) (string, []interface{}, error) {
+	return generatePaginationQuery(s, table, options, page, pageSize)
+}
+
+func (s *SQLiteQueryGenerator) GenerateCreateIndexQuery(indexName string, table string, columns []string, unique bool) string {
+	uniqueClause := ""
+	if unique {
+		uniqueClause = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s);", uniqueClause, s.EscapeIdentifier(indexName), s.EscapeIdentifier(table), escapeIdentifiers(s, columns))
+}
+
+func (s *SQLiteQueryGenerator) GenerateDropIndexQuery(indexName string) string {
+	return fmt.Sprintf("DROP INDEX IF EXISTS %s;", s.EscapeIdentifier(indexName))
+}
+
+func (s *SQLiteQueryGenerator) GenerateAddColumnQuery(table string, columnName string, columnType string, defaultValue interface{}) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s DEFAULT %s;", s.EscapeIdentifier(table), s.EscapeIdentifier(columnName), sqliteColumnType(columnType), s.QuoteLiteral(defaultValue))
+}
+
+// GenerateModifyColumnQuery rebuilds the table because SQLite cannot alter a column in place.
+// The remaining columns are taken from the table metadata.
+func (s *SQLiteQueryGenerator) GenerateModifyColumnQuery(table string, columnName string, columnType string, nullable bool) string {
+	schema, ok := s.metaSchema(table)
+	if !ok {
+		// Without metadata there is nothing to rebuild from; let SQLite report the unsupported statement.
+		return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s;", s.EscapeIdentifier(table), s.EscapeIdentifier(columnName), columnType)
+	}
+	fieldSchema, _ := schema.Column(columnName)
+	fieldSchema.Type = columnType
+	fieldSchema.Null = "NO"
+	if nullable {
+		fieldSchema.Null = "YES"
+	}
+	schema.SetColumn(columnName, fieldSchema)
+	return s.rebuildTableQuery(table, schema)
+}
+
+func (s *SQLiteQueryGenerator) GenerateDropColumnQuery(table string, columnName string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", s.EscapeIdentifier(table), s.EscapeIdentifier(columnName))
+}
+
+func (s *SQLiteQueryGenerator) GenerateAddColumnDefinitionQuery(table string, column configs.ColumnDef) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", s.EscapeIdentifier(table), s.columnDefinition(column.Name, column.FieldSchema, false))
+}
+
+// GenerateAlterColumnQuery rebuilds the table from its metadata with the column replaced.
+func (s *SQLiteQueryGenerator) GenerateAlterColumnQuery(table string, column configs.ColumnDef) string {
+	schema, ok := s.metaSchema(table)
+	if !ok {
+		return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s;", s.EscapeIdentifier(table), s.EscapeIdentifier(column.Name), column.Type)
+	}
+	schema.SetColumn(column.Name, column.FieldSchema)
+	return s.rebuildTableQuery(table, schema)
+}
+
+// GenerateAlterPrimaryKeyQuery rebuilds the table from its metadata with the new primary key.
+func (s *SQLiteQueryGenerator) GenerateAlterPrimaryKeyQuery(table string, current []string, columns []string) string {
+	schema, ok := s.metaSchema(table)
+	if !ok {
+		return fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s);", s.EscapeIdentifier(table), escapeIdentifiers(s, columns))
+	}
+	for i, column := range schema.Columns {
+		if column.Key == "PRI" {
+			schema.Columns[i].Key = ""
+		}
+	}
+	schema.PrimaryKey = append([]string(nil), columns...)
+	return s.rebuildTableQuery(table, schema)
+}
+
+// GenerateAddUniqueQuery rebuilds the table from its metadata, a unique constraint can only be
+// declared with the table.
+func (s *SQLiteQueryGenerator) GenerateAddUniqueQuery(table string, unique configs.IndexDef) string {
+	schema, ok := s.metaSchema(table)
+	if !ok {
+		return s.GenerateCreateIndexQuery(indexName(table, unique), table, unique.Columns, true)
+	}
+	schema.Uniques = append(schema.Uniques, unique)
+	return s.rebuildTableQuery(table, schema)
+}
+
+// GenerateDropUniqueQuery rebuilds the table from its metadata without the unique constraint.
+func (s *SQLiteQueryGenerator) GenerateDropUniqueQuery(table string, unique configs.IndexDef) string {
+	schema, ok := s.metaSchema(table)
+	if !ok {
+		return s.GenerateDropIndexQuery(indexName(table, unique))
+	}
+	var kept []configs.IndexDef
+	for _, existing := range schema.Uniques {
+		if !strings.EqualFold(FormatColumns(existing.Columns), FormatColumns(unique.Columns)) {
+			kept = append(kept, existing)
+		}
+	}
+	schema.Uniques = kept
+	return s.rebuildTableQuery(table, schema)
+}
+
+func (s *SQLiteQueryGenerator) GenerateAddIndexQuery(table string, index configs.IndexDef) string {
+	return s.GenerateCreateIndexQuery(indexName(table, index), table, index.Columns, strings.ToUpper(index.Type) == "UNIQUE")
+}
+
+func (s *SQLiteQueryGenerator) GenerateDropTableIndexQuery(table string, index configs.IndexDef) string {
+	return s.GenerateDropIndexQuery(indexName(table, index))
+}
+
+// GenerateAddForeignKeyQuery rebuilds the table with the extra constraint, SQLite has no ADD CONSTRAINT.
+func (s *SQLiteQueryGenerator) GenerateAddForeignKeyQuery(table string, columnName string, referencedTable string, referencedColumn string, onDelete string, onUpdate string) string {
+	return s.GenerateAddForeignKeyConstraintQuery(table, configs.ForeignKeyDef{
+		Name:              "FK_" + columnName,
+		Columns:           []string{columnName},
+		ReferencedTable:   referencedTable,
+		ReferencedColumns: []string{referencedColumn},
+		OnDelete:          onDelete,
+		OnUpdate:          onUpdate,
+	})
+}
+
+// GenerateAddForeignKeyConstraintQuery rebuilds the table from its metadata with the foreign key added.
+// It returns "" when the metadata already declares the same foreign key; one of the same name
+// but another definition is replaced.
+func (s *SQLiteQueryGenerator) GenerateAddForeignKeyConstraintQuery(table string, foreignKey configs.ForeignKeyDef) string {
+	schema, ok := s.metaSchema(table)
+	if !ok {
+		// Without metadata there is nothing to rebuild from; let SQLite report the unsupported statement.
+		return fmt.Sprintf("ALTER TABLE %s ADD %s;", s.EscapeIdentifier(table), foreignKeyConstraint(s, table, foreignKey))
+	}
+	name := foreignKeyName(table, foreignKey)
+	for i, existing := range schema.ForeignKeys {
+		if sameForeignKey(existing, foreignKey) {
+			return ""
+		}
+		if strings.EqualFold(foreignKeyName(table, existing), name) {
+			schema.ForeignKeys[i] = foreignKey
+			return s.rebuildTableQuery(table, schema)
+		}
+	}
+	schema.ForeignKeys = append(schema.ForeignKeys, foreignKey)
+	return s.rebuildTableQuery(table, schema)
+}
+
+// GenerateDropForeignKeyQuery rebuilds the table from its metadata without the foreign key.
+func (s *SQLiteQueryGenerator) GenerateDropForeignKeyQuery(table string, name string) string {
+	schema, ok := s.metaSchema(table)
+	if !ok {
+		return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", s.EscapeIdentifier(table), s.EscapeIdentifier(name))
+	}
+	var kept []configs.ForeignKeyDef
+	for _, foreignKey := range schema.ForeignKeys {
+		if !strings.EqualFold(foreignKeyName(table, foreignKey), name) {
+			kept = append(kept, foreignKey)
+		}
+	}
+	schema.ForeignKeys = kept
+	return s.rebuildTableQuery(table, schema)
+}
+
+// metaSchema returns a copy of the stored schema of a table, ok is false when the table is unknown.
+func (s *SQLiteQueryGenerator) metaSchema(table string) (configs.TableSchema, bool) {
+	store := s.MetaStore
+	if store == nil {
+		store = configs.DefaultMetaStore()
+	}
+	details := store.FindMetaTable(table)
+	if details == nil {
+		return configs.TableSchema{}, false
+	}
+	return details.Schema.Copy(), true
+}
+
+// rebuildTableQuery follows the procedure recommended by SQLite for schema changes ALTER TABLE
+// cannot express: create the new layout, copy the rows, drop the old table and rename.
+func (s *SQLiteQueryGenerator) rebuildTableQuery(table string, schema configs.TableSchema) string {
+	// Generated columns are computed again by the new table.
+	var columns []string
+	for _, column := range schema.Columns {
+		if column.Generated == "" {
+			columns = append(columns, column.Name)
+		}
+	}
+	return s.GenerateRebuildTableQuery(table, schema, columns)
+}
+
+// GenerateRebuildTableQuery rebuilds a table with schema, copying the rows of the copied columns.
+// Columns of schema that are not copied take their default.
+func (s *SQLiteQueryGenerator) GenerateRebuildTableQuery(table string, schema configs.TableSchema, columns []string) string {
+	statements := []string{"PRAGMA foreign_keys = OFF", "BEGIN TRANSACTION"}
+	statements = append(statements, s.GenerateRebuildTableStatements(table, schema, columns)...)
+	statements = append(statements, "COMMIT", "PRAGMA foreign_keys = ON")
+	return strings.Join(statements, ";\n") + ";"
+}
+
+// GenerateRebuildTableStatements returns the statements of GenerateRebuildTableQuery that run
+// inside its transaction.
+func (s *SQLiteQueryGenerator) GenerateRebuildTableStatements(table string, schema configs.TableSchema, columns []string) []string {
+	tmpTable := s.EscapeIdentifier(table + "__sqldocify_new")
+	definitions := s.columnDefinitions(table, schema)
+	copied := escapeIdentifiers(s, columns)
+
+	statements := []string{
+		fmt.Sprintf("CREATE TABLE %s (%s)", tmpTable, strings.Join(definitions, ", ")),
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", tmpTable, copied, copied, s.EscapeIdentifier(table)),
+		fmt.Sprintf("DROP TABLE %s", s.EscapeIdentifier(table)),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", tmpTable, s.EscapeIdentifier(table)),
+	}
+	// Dropping the old table dropped its indexes as well.
+	for _, index := range s.indexStatements(table, schema) {
+		statements = append(statements, strings.TrimSuffix(index, ";"))
+	}
+	return statements
+}
+
+// TransactionalDDL reports that schema changes can be rolled back.
+func (s *SQLiteQueryGenerator) TransactionalDDL() bool {
+	return true
+}
+
+func (s *SQLiteQueryGenerator) QuoteLiteral(value interface{}) string {
+	return formatLiteral(value, quoteString)
+}
+
+func (s *SQLiteQueryGenerator) FormatColumns(columns []string) string {
+	return FormatColumns(columns)
+}
+
+func (s *SQLiteQueryGenerator) EscapeIdentifier(identifier string) string {
+	return fmt.Sprintf(`"%s"`, strings.ReplaceAll(identifier, `"`, `""`))
+}
+
+// GenerateLimitClause returns the LIMIT clause, limit and offset 0 are left out. An offset
+// needs a limit here, -1 stands for none.
+func (s *SQLiteQueryGenerator) GenerateLimitClause(limit int, offset int) string {
+	switch {
+	case limit > 0 && offset > 0:
+		return fmt.Sprintf("LIMIT %d OFFSET %d", limit, offset)
+	case limit > 0:
+		return fmt.Sprintf("LIMIT %d", limit)
+	case offset > 0:
+		return fmt.Sprintf("LIMIT -1 OFFSET %d", offset)
+	}
+	return ""
+}
+
+// Placeholder returns the bind parameter marker, which is positionless for this dialect.
+func (s *SQLiteQueryGenerator) Placeholder(index int) string {
+	return "?"
+}
@@ -0,0 +1,293 @@
+package queries
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"sqldocify/configs"
+
+	_ "modernc.org/sqlite"
+)
+
+// openMemoryDB opens an in-memory SQLite database limited to one connection, like
+// servers.NewDatabase does for :memory:.
+func openMemoryDB(t *testing.T, statements ...string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+	return db
+}
+
+func TestSQLiteGetSchemaInMemory(t *testing.T) {
+	db := openMemoryDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email VARCHAR(255) NOT NULL UNIQUE, name TEXT COLLATE NOCASE DEFAULT 'x')",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE)",
+		"CREATE INDEX idx_orders_user_id ON orders (user_id)",
+	)
+	qg := &SQLiteQueryGenerator{}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		schema, err := qg.GenerateGetSchemaQuery(db, "orders")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if got := schema.ColumnNames(); len(got) != 2 || got[0] != "id" || got[1] != "user_id" {
+			t.Errorf("columns = %v", got)
+		}
+		if len(schema.ForeignKeys) != 1 || schema.ForeignKeys[0].ReferencedTable != "users" || schema.ForeignKeys[0].OnDelete != "CASCADE" {
+			t.Errorf("foreign keys = %+v", schema.ForeignKeys)
+		}
+		if len(schema.Indexes) != 1 || schema.Indexes[0].Name != "idx_orders_user_id" {
+			t.Errorf("indexes = %+v", schema.Indexes)
+		}
+
+		schema, err = qg.GenerateGetSchemaQuery(db, "users")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		email, _ := schema.Column("email")
+		if email.Key != "UNI" || email.Null != "NO" {
+			t.Errorf("email = %+v", email)
+		}
+		name, _ := schema.Column("name")
+		if name.Collation != "NOCASE" || name.Default == nil || *name.Default != "'x'" {
+			t.Errorf("name = %+v", name)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("reading the schema of an in-memory database did not return")
+	}
+}
+
+func TestSQLiteConstraintsRoundTrip(t *testing.T) {
+	qg := &SQLiteQueryGenerator{}
+	db := openMemoryDB(t, strings.Split(qg.GenerateCreateTableQuery("members", membersSchema()), "\n")...)
+
+	schema, err := qg.GenerateGetSchemaQuery(db, "members")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := schema.PrimaryKeyColumns(); strings.Join(got, ",") != "user_id,group_id" {
+		t.Errorf("primary key = %v", got)
+	}
+	if len(schema.Uniques) != 1 || strings.Join(schema.Uniques[0].Columns, ",") != "group_id,slug" {
+		t.Errorf("uniques = %+v", schema.Uniques)
+	}
+	indexes := map[string]string{}
+	for _, index := range schema.Indexes {
+		indexes[index.Name] = strings.Join(index.Columns, ",")
+	}
+	if len(indexes) != 2 || indexes["idx_slug"] != "slug" || indexes["idx_members_body"] != "body" {
+		t.Errorf("indexes = %+v", schema.Indexes)
+	}
+	if _, err := db.Exec("INSERT INTO members (user_id, group_id, slug) VALUES (1, 1, 'a'), (2, 1, 'b')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO members (user_id, group_id, slug) VALUES (1, 1, 'c')"); err == nil {
+		t.Error("a duplicate composite primary key was accepted")
+	}
+	if _, err := db.Exec("INSERT INTO members (user_id, group_id, slug) VALUES (3, 1, 'a')"); err == nil {
+		t.Error("a duplicate composite unique constraint was accepted")
+	}
+}
+
+func TestSQLiteBoundLookups(t *testing.T) {
+	db := openMemoryDB(t,
+		`CREATE TABLE "it's" (a INTEGER, b INTEGER, PRIMARY KEY (b, a))`,
+		"CREATE TABLE other (id INTEGER PRIMARY KEY)",
+	)
+	qg := &SQLiteQueryGenerator{}
+
+	for name, want := range map[string]bool{"it's": true, "other": true, "' OR '1'='1": false} {
+		query, args := qg.GenerateTableExistsQuery(name)
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if got := rows.Next(); got != want {
+			t.Errorf("table %q exists = %v, want %v", name, got, want)
+		}
+		rows.Close()
+	}
+
+	keys, err := qg.primaryKeyColumns(db, "it's")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] != "b" || keys[1] != "a" {
+		t.Errorf("primary key of it's = %v", keys)
+	}
+
+	query := qg.GenerateAddColumnQuery("other", "note", "TEXT", `it's a \ note`)
+	if _, err := db.Exec(query); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	var note string
+	if _, err := db.Exec("INSERT INTO other (id) VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("SELECT note FROM other").Scan(&note); err != nil {
+		t.Fatal(err)
+	}
+	if note != `it's a \ note` {
+		t.Errorf("default = %q", note)
+	}
+}
+
+func TestSQLiteAddForeignKeyConstraint(t *testing.T) {
+	store := configs.NewMemoryMetaStore()
+	schema := configs.TableSchema{
+		Columns: []configs.ColumnDef{
+			{Name: "id", FieldSchema: configs.FieldSchema{Type: "INTEGER", Null: "NO", Key: "PRI"}},
+			{Name: "user_id", FieldSchema: configs.FieldSchema{Type: "INTEGER"}},
+			{Name: "team_id", FieldSchema: configs.FieldSchema{Type: "INTEGER"}},
+		},
+		ForeignKeys: []configs.ForeignKeyDef{
+			{Name: "fk_user", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+		},
+	}
+	if err := store.UpdateMetaTable("orders", configs.MetaTableDetails{Schema: schema}); err != nil {
+		t.Fatal(err)
+	}
+	qg := &SQLiteQueryGenerator{MetaStore: store}
+
+	identical := configs.ForeignKeyDef{Columns: []string{"USER_ID"}, ReferencedTable: "Users", ReferencedColumns: []string{"id"}}
+	if query := qg.GenerateAddForeignKeyConstraintQuery("orders", identical); query != "" {
+		t.Errorf("an identical foreign key rebuilds the table:\n%s", query)
+	}
+
+	renamed := configs.ForeignKeyDef{Name: "fk_user", Columns: []string{"user_id"}, ReferencedTable: "accounts", ReferencedColumns: []string{"id"}}
+	query := qg.GenerateAddForeignKeyConstraintQuery("orders", renamed)
+	if !strings.Contains(query, `REFERENCES "accounts"`) || strings.Contains(query, `REFERENCES "users"`) {
+		t.Errorf("a foreign key of the same name is not replaced:\n%s", query)
+	}
+
+	added := configs.ForeignKeyDef{Columns: []string{"team_id"}, ReferencedTable: "teams", ReferencedColumns: []string{"id"}}
+	query = qg.GenerateAddForeignKeyConstraintQuery("orders", added)
+	if !strings.Contains(query, `REFERENCES "teams"`) || !strings.Contains(query, `REFERENCES "users"`) {
+		t.Errorf("a new foreign key is not added next to the existing one:\n%s", query)
+	}
+}
+
+// queryStrings runs a built select and returns the first column of its rows as text.
+func queryStrings(t *testing.T, db *sql.DB, query string, args []interface{}, err error) []string {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var value sql.NullString
+		if err := rows.Scan(&value); err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, value.String)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func TestBuilderOnSQLite(t *testing.T) {
+	db := openMemoryDB(t,
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, "group" TEXT, hits INTEGER NOT NULL DEFAULT 0)`,
+		`CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL REFERENCES users (id), total INTEGER NOT NULL)`,
+	)
+	b := NewBuilder(&SQLiteQueryGenerator{})
+	run := func(query string, args []interface{}, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(query, args...); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+
+	run(b.Insert("users").Columns("id", "name", "group").Values(1, "ann", "admin").Values(2, "bob", nil).Build())
+	run(b.Insert("users").Record(map[string]interface{}{"id": 3, "name": "cy", "group": "staff"}).
+		Record(map[string]interface{}{"id": 4, "name": "dee's", "group": "staff"}).Build())
+	run(b.Insert("orders").Columns("id", "user_id", "total").Values(1, 1, 10).Values(2, 1, 30).Values(3, 3, 5).Values(4, 4, 7).Build())
+	run(b.Update("users").SetExpr("hits", "hits + ?", 5).Set("name", "bobby").Where(Eq("id", 2)).Build())
+	run(b.Update("users").SetMap(map[string]interface{}{"hits": 1}).Where(In("id", []int{3, 4})).Build())
+
+	tests := []struct {
+		name  string
+		build func() (string, []interface{}, error)
+		want  string
+	}{
+		{"reserved word column", func() (string, []interface{}, error) {
+			return b.Select("name").From("users").Where(IsNull("group")).Build()
+		}, "bobby"},
+		{"join, group and having", func() (string, []interface{}, error) {
+			return b.Select("u.name").From("users u").Join("orders o", "o.user_id = u.id AND o.total > ?", 6).
+				GroupBy("u.id", "u.name").Having("SUM(o.total) >= ?", 7).OrderBy("u.name DESC").Build()
+		}, "dee's,ann"},
+		{"left join", func() (string, []interface{}, error) {
+			return b.Select("u.name").From("users AS u").LeftJoin("orders o", "o.user_id = u.id").
+				Where(IsNull("o.id")).Build()
+		}, "bobby"},
+		{"limit and offset", func() (string, []interface{}, error) {
+			return b.Select("id").From("users").OrderBy("id").Limit(2).Offset(1).Build()
+		}, "2,3"},
+		{"offset only", func() (string, []interface{}, error) {
+			return b.Select("id").From("users").OrderBy("id").Offset(3).Build()
+		}, "4"},
+		{"nested conditions", func() (string, []interface{}, error) {
+			return b.Select("name").From("users").Where(Or(Eq("group", "admin"), And(Like("name", "d%"), Not(Between("hits", 2, 9))))).
+				OrderBy("id").Build()
+		}, "ann,dee's"},
+		{"conditions from a map", func() (string, []interface{}, error) {
+			return b.Select("id").From("users").Where(map[string]interface{}{"group": "staff", "hits": 1}).OrderBy("id").Build()
+		}, "3,4"},
+		{"sub-select", func() (string, []interface{}, error) {
+			return b.Select("name").From("users").
+				Where(InSelect("id", b.Select("user_id").From("orders").Where(Gt("total", 6)))).OrderBy("id").Build()
+		}, "ann,dee's"},
+		{"exists", func() (string, []interface{}, error) {
+			return b.Select("u.name").From("users u").
+				Where(NotExists(b.Select("1").From("orders o").Where(ColumnEq("o.user_id", "u.id")))).Build()
+		}, "bobby"},
+		{"common table expression and union", func() (string, []interface{}, error) {
+			return b.Select("name").From("big").With("big", b.Select("name").From("users").Where(Gte("hits", 5))).
+				Union(b.Select("name").From("users").Where(Eq("id", 1))).OrderBy("name").Build()
+		}, "ann,bobby"},
+		{"distinct", func() (string, []interface{}, error) {
+			return b.Select("user_id").Distinct().From("orders").Where(NotIn("user_id", 3)).OrderBy("user_id").Build()
+		}, "1,4"},
+	}
+	for _, tt := range tests {
+		query, args, err := tt.build()
+		if got := strings.Join(queryStrings(t, db, query, args, err), ","); got != tt.want {
+			t.Errorf("%s: %s = %s, want %s", tt.name, query, got, tt.want)
+		}
+	}
+
+	run(b.Delete("orders").Where(Lt("total", 8)).Where("user_id <> ?", 1).Build())
+	if got := queryStrings(t, db, "SELECT COUNT(*) FROM orders", nil, nil); got[0] != "2" {
+		t.Errorf("%s orders left after the delete, want 2", got[0])
+	}
+}
@@ -0,0 +1,147 @@
+package queries
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CTE is a common table expression of a WITH clause, a named query the statement can select from
+// like a table. A recursive CTE refers to itself, its Query is the anchor select UNION ALL the
+// recursive select:
+//
+//	tree := b.Select("id", "parent_id", "name").From("categories").Where(IsNull("parent_id")).
+//		UnionAll(b.Select("c.id", "c.parent_id", "c.name").From("categories c").Join("tree t", "c.parent_id = t.id"))
+//	query, args, err := b.Select().From("tree").WithRecursive("tree", tree).Build()
+type CTE struct {
+	Name string
+	// Columns name the columns of the query, its own column names when empty.
+	Columns   []string
+	Recursive bool
+	Query     *SelectBuilder
+}
+
+// Union appends the rows of Query to a select, without duplicates unless All is set.
+type Union struct {
+	All   bool
+	Query *SelectBuilder
+}
+
+// With adds a common table expression named name, columns name the columns of query.
+func (s *SelectBuilder) With(name string, query *SelectBuilder, columns ...string) *SelectBuilder {
+	s.with = append(s.with, CTE{Name: name, Columns: columns, Query: query})
+	return s
+}
+
+// WithRecursive adds a common table expression that refers to itself, which makes the WITH
+// clause WITH RECURSIVE.
+func (s *SelectBuilder) WithRecursive(name string, query *SelectBuilder, columns ...string) *SelectBuilder {
+	s.with = append(s.with, CTE{Name: name, Columns: columns, Recursive: true, Query: query})
+	return s
+}
+
+// Union appends the distinct rows of query. OrderBy, Limit and Offset apply to the whole union.
+func (s *SelectBuilder) Union(query *SelectBuilder) *SelectBuilder {
+	s.unions = append(s.unions, Union{Query: query})
+	return s
+}
+
+// UnionAll appends every row of query like Union, duplicates are kept.
+func (s *SelectBuilder) UnionAll(query *SelectBuilder) *SelectBuilder {
+	s.unions = append(s.unions, Union{All: true, Query: query})
+	return s
+}
+
+// renderWith returns the WITH clause followed by a space, empty without common table expressions.
+func (s *SelectBuilder) renderWith() fragment {
+	var query fragment
+	if len(s.with) == 0 {
+		return query
+	}
+	recursive := false
+	parts := make([]string, 0, len(s.with))
+	for _, cte := range s.with {
+		recursive = recursive || cte.Recursive
+		name := escapeName(s.qg, cte.Name)
+		if len(cte.Columns) > 0 {
+			name += " (" + escapeList(s.qg, cte.Columns, escapeName) + ")"
+		}
+		body := cte.Query.render()
+		parts = append(parts, name+" AS ("+body.sql+")")
+		query.args = append(query.args, body.args...)
+	}
+	// RECURSIVE belongs to the WITH clause, not to a single expression, in every dialect.
+	if recursive {
+		query.sql = "WITH RECURSIVE " + strings.Join(parts, ", ") + " "
+	} else {
+		query.sql = "WITH " + strings.Join(parts, ", ") + " "
+	}
+	return query
+}
+
+func (u Union) keyword() string {
+	if u.All {
+		return "UNION ALL"
+	}
+	return "UNION"
+}
+
+// operand renders the query of the union. SQLite allows no WITH, ORDER BY, LIMIT or nested union
+// in an operand, such operands are selected from a subquery, which every dialect accepts.
+func (u Union) operand(n int) fragment {
+	q := u.Query
+	if len(q.with) == 0 && len(q.unions) == 0 && len(q.orderBy) == 0 && q.limit == 0 && q.offset == 0 {
+		return q.render()
+	}
+	inner := q.render()
+	return fragment{sql: fmt.Sprintf("SELECT * FROM (%s) AS %s", inner.sql, q.qg.EscapeIdentifier(fmt.Sprintf("union_%d", n))), args: inner.args}
+}
+
+type inSelect struct {
+	column string
+	not    bool
+	query  *SelectBuilder
+}
+
+func (c inSelect) Build(qg QueryGenerator) (string, []interface{}) {
+	operator := " IN "
+	if c.not {
+		operator = " NOT IN "
+	}
+	query := c.query.render()
+	return escapeName(qg, c.column) + operator + "(" + query.sql + ")", query.args
+}
+
+// InSelect matches column IN (query), query selects a single column.
+func InSelect(column string, query *SelectBuilder) Condition {
+	return inSelect{column: column, query: query}
+}
+
+// NotInSelect matches column NOT IN (query). Like in SQL, a NULL among the selected values
+// matches no row.
+func NotInSelect(column string, query *SelectBuilder) Condition {
+	return inSelect{column: column, not: true, query: query}
+}
+
+type exists struct {
+	not   bool
+	query *SelectBuilder
+}
+
+func (c exists) Build(qg QueryGenerator) (string, []interface{}) {
+	query := c.query.render()
+	if c.not {
+		return "NOT EXISTS (" + query.sql + ")", query.args
+	}
+	return "EXISTS (" + query.sql + ")", query.args
+}
+
+// Exists matches when query returns a row. The query is usually correlated with the outer
+// select through a condition like ColumnEq("o.user_id", "u.id").
+func Exists(query *SelectBuilder) Condition {
+	return exists{query: query}
+}
+
+// NotExists matches when query returns no row.
+func NotExists(query *SelectBuilder) Condition {
+	return exists{not: true, query: query}
+}
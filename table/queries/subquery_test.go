@@ -0,0 +1,302 @@
+package queries
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubqueries(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(b *Builder) (string, []interface{}, error)
+		want  dialectSQL
+		args  []interface{}
+	}{
+		{
+			name: "with",
+			build: func(b *Builder) (string, []interface{}, error) {
+				active := b.Select("id", "name").From("users").Where(Eq("status", "active"))
+				return b.Select("name").From("active").With("active", active).Where(Gt("id", 10)).Build()
+			},
+			want: sameSQL(`WITH "active" AS (SELECT "id", "name" FROM "users" WHERE "status" = ?) SELECT "name" FROM "active" WHERE "id" > ?;`),
+			args: []interface{}{"active", 10},
+		},
+		{
+			name: "recursive with columns",
+			build: func(b *Builder) (string, []interface{}, error) {
+				tree := b.Select("id", "parent_id").From("categories").Where(Eq("id", 1)).
+					UnionAll(b.Select("c.id", "c.parent_id").From("categories c").Join("tree t", "c.parent_id = t.id"))
+				plain := b.Select("id").From("roots")
+				return b.Select().From("tree").With("r", plain).WithRecursive("tree", tree, "id", "parent").Build()
+			},
+			want: sameSQL(`WITH RECURSIVE "r" AS (SELECT "id" FROM "roots"), "tree" ("id", "parent") AS (SELECT "id", "parent_id" FROM "categories" WHERE "id" = ? ` +
+				`UNION ALL SELECT "c"."id", "c"."parent_id" FROM "categories" AS "c" INNER JOIN "tree" AS "t" ON c.parent_id = t.id) SELECT * FROM "tree";`),
+			args: []interface{}{1},
+		},
+		{
+			name: "union ordered as a whole",
+			build: func(b *Builder) (string, []interface{}, error) {
+				return b.Select("email").From("users").Where(Eq("a", 1)).
+					Union(b.Select("email").From("invites").Where(Eq("b", 2))).
+					OrderBy("email").Limit(5).Build()
+			},
+			want: sameSQL(`SELECT "email" FROM "users" WHERE "a" = ? UNION SELECT "email" FROM "invites" WHERE "b" = ? ORDER BY "email" LIMIT 5;`),
+			args: []interface{}{1, 2},
+		},
+		{
+			name: "limited union operand is a subquery",
+			build: func(b *Builder) (string, []interface{}, error) {
+				return b.Select("id").From("a").
+					UnionAll(b.Select("id").From("b").OrderBy("id DESC").Limit(3)).
+					UnionAll(b.Select("id").From("c").Where(Eq("x", 1))).Build()
+			},
+			want: sameSQL(`SELECT "id" FROM "a" UNION ALL SELECT * FROM (SELECT "id" FROM "b" ORDER BY "id" DESC LIMIT 3) AS "union_1" ` +
+				`UNION ALL SELECT "id" FROM "c" WHERE "x" = ?;`),
+			args: []interface{}{1},
+		},
+		{
+			name: "in select and exists",
+			build: func(b *Builder) (string, []interface{}, error) {
+				banned := b.Select("user_id").From("bans").Where(Gt("until", 100))
+				orders := b.Select("1").From("orders o").Where(ColumnEq("o.user_id", "u.id")).Where(Eq("o.status", "open"))
+				return b.Select("u.id").From("users u").
+					Where(Eq("u.kind", "member")).Where(NotInSelect("u.id", banned)).Where(Exists(orders)).
+					Where(Or(InSelect("u.id", b.Select("id").From("admins")), NotExists(b.Select("1").From("locks")))).Build()
+			},
+			want: sameSQL(`SELECT "u"."id" FROM "users" AS "u" WHERE "u"."kind" = ? AND "u"."id" NOT IN (SELECT "user_id" FROM "bans" WHERE "until" > ?) ` +
+				`AND EXISTS (SELECT 1 FROM "orders" AS "o" WHERE "o"."user_id" = "u"."id" AND "o"."status" = ?) ` +
+				`AND ("u"."id" IN (SELECT "id" FROM "admins") OR NOT EXISTS (SELECT 1 FROM "locks"));`),
+			args: []interface{}{"member", 100, "open"},
+		},
+	}
+	for _, tt := range tests {
+		for _, dialect := range dialects {
+			t.Run(tt.name+"/"+dialect.name, func(t *testing.T) {
+				query, args, err := tt.build(NewBuilder(dialect.qg))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := tt.want.of(dialect.name); query != want {
+					t.Errorf("query\n got %s\nwant %s", query, want)
+				}
+				if !reflect.DeepEqual(args, tt.args) {
+					t.Errorf("args = %#v, want %#v", args, tt.args)
+				}
+			})
+		}
+	}
+}
+
+func TestSelectOptionQueries(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(qg QueryGenerator) (string, []interface{}, error)
+		want  dialectSQL
+		args  []interface{}
+	}{
+		{
+			name: "select",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GenerateSelectQuery("users", SelectOptions{
+					Columns: []string{"id"}, Where: Eq("id", 1), OrderBy: []OrderBy{Desc("id")}, Limit: 1,
+				})
+			},
+			want: sameSQL(`SELECT "id" FROM "users" WHERE "id" = ? ORDER BY "id" DESC LIMIT 1;`),
+			args: []interface{}{1},
+		},
+		{
+			name: "count drops the order",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GenerateCountQuery("users", SelectOptions{Where: Eq("a", 1), OrderBy: OrderByColumns("id")})
+			},
+			want: sameSQL(`SELECT COUNT(*) FROM "users" WHERE "a" = ?;`),
+			args: []interface{}{1},
+		},
+		{
+			name: "count of a limited select",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GenerateCountQuery("users", SelectOptions{OrderBy: OrderByColumns("id"), Limit: 10})
+			},
+			want: sameSQL(`SELECT COUNT(*) FROM (SELECT * FROM "users" ORDER BY "id" LIMIT 10) AS counted;`),
+		},
+		{
+			name: "count of a union with a CTE",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				b := NewBuilder(qg)
+				return qg.GenerateCountQuery("users", SelectOptions{
+					With:    []CTE{{Name: "recent", Query: b.Select("user_id").From("orders").Where(Gt("created_at", 5))}},
+					Columns: []string{"id"},
+					Where:   Eq("active", true),
+					Unions:  []Union{{Query: b.Select("user_id").From("recent")}},
+				})
+			},
+			want: sameSQL(`WITH "recent" AS (SELECT "user_id" FROM "orders" WHERE "created_at" > ?) ` +
+				`SELECT COUNT(*) FROM (SELECT "id" FROM "users" WHERE "active" = ? UNION SELECT "user_id" FROM "recent") AS counted;`),
+			args: []interface{}{5, true},
+		},
+		{
+			name: "exists",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GenerateExistsQuery("users", SelectOptions{Where: Eq("email", "a@b.c"), OrderBy: OrderByColumns("id")})
+			},
+			want: sameSQL(`SELECT EXISTS(SELECT 1 FROM "users" WHERE "email" = ?);`),
+			args: []interface{}{"a@b.c"},
+		},
+		{
+			name: "exists of a union",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GenerateExistsQuery("users", SelectOptions{
+					Columns: []string{"id"},
+					Unions:  []Union{{All: true, Query: NewBuilder(qg).Select("id").From("admins")}},
+				})
+			},
+			want: sameSQL(`SELECT EXISTS(SELECT "id" FROM "users" UNION ALL SELECT "id" FROM "admins");`),
+		},
+		{
+			name: "join",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GenerateJoinQuery("users u", []Join{
+					{Table: "orders o", On: And(ColumnEq("o.user_id", "u.id"), Eq("o.status", "paid"))},
+					{Type: LeftJoin, Table: "teams t", On: ColumnEq("t.id", "u.team_id")},
+					{Type: CrossJoin, Table: "regions", On: Eq("ignored", 1)},
+				}, SelectOptions{Columns: []string{"u.id", "t.name"}, Where: Gt("u.age", 18)})
+			},
+			want: sameSQL(`SELECT "u"."id", "t"."name" FROM "users" AS "u" INNER JOIN "orders" AS "o" ON "o"."user_id" = "u"."id" AND "o"."status" = ? ` +
+				`LEFT JOIN "teams" AS "t" ON "t"."id" = "u"."team_id" CROSS JOIN "regions" WHERE "u"."age" > ?;`),
+			args: []interface{}{"paid", 18},
+		},
+	}
+	for _, tt := range tests {
+		for _, dialect := range dialects {
+			t.Run(tt.name+"/"+dialect.name, func(t *testing.T) {
+				query, args, err := tt.build(dialect.qg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := tt.want.of(dialect.name); query != want {
+					t.Errorf("query\n got %s\nwant %s", query, want)
+				}
+				if !reflect.DeepEqual(args, tt.args) {
+					t.Errorf("args = %#v, want %#v", args, tt.args)
+				}
+			})
+		}
+	}
+}
+
+func TestUpdateAndDeleteQueries(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(qg QueryGenerator) (string, []interface{}, error)
+		want  dialectSQL
+		args  []interface{}
+	}{
+		{
+			name: "update",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GenerateUpdateQuery("users", map[string]interface{}{"status": "inactive", "age": 30},
+					And(Eq("id", 1), InSelect("team_id", NewBuilder(qg).Select("id").From("teams").Where(Eq("closed", true)))))
+			},
+			want: sameSQL(`UPDATE "users" SET "age" = ?, "status" = ? WHERE "id" = ? AND "team_id" IN (SELECT "id" FROM "teams" WHERE "closed" = ?);`),
+			args: []interface{}{30, "inactive", 1, true},
+		},
+		{
+			name: "delete",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GenerateDeleteQuery("users", Or(Lt("last_login", 5), IsNull("last_login")))
+			},
+			want: sameSQL(`DELETE FROM "users" WHERE "last_login" < ? OR "last_login" IS NULL;`),
+			args: []interface{}{5},
+		},
+		{
+			name: "multiple delete",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GenerateMultipleDeleteQuery("users", []Condition{Eq("id", 1), And(Eq("email", "a@b.c"), Raw("age > ?", 18))})
+			},
+			want: sameSQL(`DELETE FROM "users" WHERE "id" = ? OR ("email" = ? AND (age > ?));`),
+			args: []interface{}{1, "a@b.c", 18},
+		},
+		{
+			name: "multiple delete of no condition",
+			build: func(qg QueryGenerator) (string, []interface{}, error) {
+				return qg.GenerateMultipleDeleteQuery("users", nil)
+			},
+			want: sameSQL(`DELETE FROM "users" WHERE 1 = 0;`),
+		},
+	}
+	for _, tt := range tests {
+		for _, dialect := range dialects {
+			t.Run(tt.name+"/"+dialect.name, func(t *testing.T) {
+				query, args, err := tt.build(dialect.qg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := tt.want.of(dialect.name); query != want {
+					t.Errorf("query\n got %s\nwant %s", query, want)
+				}
+				if !reflect.DeepEqual(args, tt.args) {
+					t.Errorf("args = %#v, want %#v", args, tt.args)
+				}
+			})
+		}
+	}
+}
+
+func TestSubqueriesRunOnSQLite(t *testing.T) {
+	db := openMemoryDB(t,
+		"CREATE TABLE categories (id INTEGER PRIMARY KEY, parent_id INTEGER, name TEXT)",
+		"INSERT INTO categories VALUES (1, NULL, 'root'), (2, 1, 'a'), (3, 2, 'b'), (4, NULL, 'other'), (5, 4, 'c')",
+	)
+	qg := &SQLiteQueryGenerator{}
+	b := NewBuilder(qg)
+
+	tree := b.Select("id", "name").From("categories").Where(Eq("id", 1)).
+		UnionAll(b.Select("c.id", "c.name").From("categories c").Join("tree t", "c.parent_id = t.id"))
+	query, args, err := b.Select("name").From("tree").WithRecursive("tree", tree).OrderBy("id").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if !reflect.DeepEqual(names, []string{"root", "a", "b"}) {
+		t.Errorf("tree = %v", names)
+	}
+
+	var count int
+	query, args, err = qg.GenerateCountQuery("categories", SelectOptions{
+		Where:  IsNull("parent_id"),
+		Unions: []Union{{All: true, Query: b.Select("*").From("categories").Where(Gt("id", 3)).OrderBy("id").Limit(1)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(query, args...).Scan(&count); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	if count != 3 {
+		t.Errorf("count = %d, want 3", count)
+	}
+
+	var exists bool
+	query, args, err = qg.GenerateExistsQuery("categories", SelectOptions{Where: InSelect("parent_id", b.Select("id").From("categories").Where(Eq("name", "other")))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(query, args...).Scan(&exists); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	if !exists {
+		t.Error("a child of other exists")
+	}
+}
@@ -6,33 +6,73 @@ import (
 )
 
 type QueryGenerator interface { // Get schema of a table
-	GenerateCreateTableQuery(nm string, schema map[string]configs.FieldSchema) string                                                                                // Single create table
-	GenerateGetSchemaQuery(db *sql.DB, nm string) (map[string]configs.FieldSchema, error)                                                                            // Get schema of a table
-	GenerateGetAllTablesQuery(db *sql.DB) ([]string, error)                                                                                                          // Get all tables
-	GenerateTableExistsQuery(table string) string                                                                                                                    // Single table exists
-	GenerateInsertQuery(table string, columns []string, values []interface{}) string                                                                                 // Single insert
-	GenerateMultipleInsertQuery(table string, columns []string, values [][]interface{}) string                                                                       // Bulk insert
-	GenerateUpdateQuery(table string, updates map[string]interface{}, condition string) string                                                                       // Single update
-	GenerateDeleteQuery(table string, condition string) string                                                                                                       // Single delete
-	GenerateMultipleDeleteQuery(table string, conditions []string) string                                                                                            // Bulk delete
-	GenerateSelectQuery(table string, columns []string, condition string, orderBy string, limit int, offset int) string                                              // Single select
-	GenerateBatchInsertQuery(table string, columns []string, batchValues [][]interface{}) string                                                                     // Batch insert
-	GenerateUpsertQuery(table string, columns []string, values []interface{}, conflictColumns []string, updates map[string]interface{}) string                       // Single upsert
-	GenerateJoinQuery(mainTable string, joinType string, joinTable string, onCondition string, columns []string, condition string, orderBy string, limit int) string // Single join
-	GenerateCountQuery(table string, condition string) string                                                                                                        // Single count
-	GenerateExistsQuery(table string, condition string) string                                                                                                       // Single exists
-	GenerateTransactionQuery(queries []string) string                                                                                                                // Single transaction
-	GenerateAggregationQuery(table string, columns []string, aggregations map[string]string, condition string, groupBy []string, orderBy string) string              // Single aggregation
-	BuildConditionQuery(conditions map[string]interface{}, logicalOperator string) string                                                                            // Build condition query
-	GeneratePaginationQuery(table string, columns []string, condition string, orderBy string, page int, pageSize int) string                                         // Single pagination
-	GenerateCreateIndexQuery(indexName string, table string, columns []string, unique bool) string                                                                   // Single create index
-	GenerateDropIndexQuery(indexName string) string                                                                                                                  // Single drop index
-	GenerateAddColumnQuery(table string, columnName string, columnType string, defaultValue interface{}) string                                                      // Single add column
-	GenerateModifyColumnQuery(table string, columnName string, columnType string, nullable bool) string                                                              // Single modify column
-	GenerateDropColumnQuery(table string, columnName string) string                                                                                                  // Single drop column
-	GenerateAddForeignKeyQuery(table string, columnName string, referencedTable string, referencedColumn string, onDelete string, onUpdate string) string            // Single add foreign key
-	GenerateDropForeignKeyQuery(table string, foreignKeyName string) string                                                                                          // Single drop foreign key
-	SanitizeValue(value interface{}) string                                                                                                                          // Sanitize value
-	FormatColumns(columns []string) string                                                                                                                           // Format columns
-	EscapeIdentifier(identifier string) string                                                                                                                       // Escape identifier
+	GenerateCreateTableQuery(nm string, schema configs.TableSchema) string                                                                                      // Single create table
+	GenerateGetSchemaQuery(db *sql.DB, nm string) (configs.TableSchema, error)                                                                                  // Get schema of a table
+	NormalizeSchema(schema configs.TableSchema) configs.TableSchema                                                                                             // Schema as the database reports it
+	GenerateGetAllTablesQuery(db *sql.DB) ([]string, error)                                                                                                     // Get all tables
+	GenerateTableExistsQuery(table string) (string, []interface{})                                                                                              // Single table exists
+	GenerateInsertQuery(table string, columns []string, values []interface{}) (string, []interface{})                                                           // Single insert
+	GenerateMultipleInsertQuery(table string, columns []string, values [][]interface{}) (string, []interface{})                                                 // Bulk insert
+	GenerateUpdateQuery(table string, updates map[string]interface{}, condition Condition) (string, []interface{}, error)                                       // Single update
+	GenerateDeleteQuery(table string, condition Condition) (string, []interface{}, error)                                                                       // Single delete
+	GenerateMultipleDeleteQuery(table string, conditions []Condition) (string, []interface{}, error)                                                            // Bulk delete
+	GenerateSelectQuery(table string, options SelectOptions) (string, []interface{}, error)                                                                     // Single select
+	GenerateBatchInsertQuery(table string, columns []string, batchValues [][]interface{}) (string, []interface{})                                               // Batch insert
+	GenerateUpsertQuery(table string, columns []string, values []interface{}, conflictColumns []string, updates map[string]interface{}) (string, []interface{}) // Single upsert
+	GenerateJoinQuery(table string, joins []Join, options SelectOptions) (string, []interface{}, error)                                                         // Any number of joins
+	GenerateCountQuery(table string, options SelectOptions) (string, []interface{}, error)                                                                      // Single count
+	GenerateExistsQuery(table string, options SelectOptions) (string, []interface{}, error)                                                                     // Single exists
+	GenerateTransactionQuery(queries []string) string                                                                                                           // Single transaction
+	GenerateAggregationQuery(table string, aggregations map[string]string, options SelectOptions) (string, []interface{}, error)                                // Single aggregation
+	BuildConditionQuery(condition Condition) (string, []interface{}, error)                                                                                     // Build condition query
+	GeneratePaginationQuery(table string, options SelectOptions, page int, pageSize int) (string, []interface{}, error)                                         // Single pagination
+	GenerateCreateIndexQuery(indexName string, table string, columns []string, unique bool) string                                                              // Single create index
+	GenerateDropIndexQuery(indexName string) string                                                                                                             // Single drop index
+	GenerateAddColumnQuery(table string, columnName string, columnType string, defaultValue interface{}) string                                                 // Single add column
+	GenerateModifyColumnQuery(table string, columnName string, columnType string, nullable bool) string                                                         // Single modify column
+	GenerateDropColumnQuery(table string, columnName string) string                                                                                             // Single drop column
+	GenerateAddColumnDefinitionQuery(table string, column configs.ColumnDef) string                                                                             // Add column with all attributes
+	GenerateAlterColumnQuery(table string, column configs.ColumnDef) string                                                                                     // Change column to a definition
+	GenerateAlterPrimaryKeyQuery(table string, current []string, columns []string) string                                                                       // Replace primary key
+	GenerateAddUniqueQuery(table string, unique configs.IndexDef) string                                                                                        // Add unique constraint
+	GenerateDropUniqueQuery(table string, unique configs.IndexDef) string                                                                                       // Drop unique constraint
+	GenerateAddIndexQuery(table string, index configs.IndexDef) string                                                                                          // Add index of a schema
+	GenerateDropTableIndexQuery(table string, index configs.IndexDef) string                                                                                    // Drop index of a table
+	GenerateAddForeignKeyQuery(table string, columnName string, referencedTable string, referencedColumn string, onDelete string, onUpdate string) string       // Single add foreign key
+	GenerateAddForeignKeyConstraintQuery(table string, foreignKey configs.ForeignKeyDef) string                                                                 // Add foreign key constraint
+	GenerateDropForeignKeyQuery(table string, foreignKeyName string) string                                                                                     // Single drop foreign key
+	QuoteLiteral(value interface{}) string                                                                                                                      // Literal for DDL, which takes no bind parameters
+	FormatColumns(columns []string) string                                                                                                                      // Format columns
+	EscapeIdentifier(identifier string) string                                                                                                                  // Escape identifier
+	GenerateLimitClause(limit int, offset int) string                                                                                                           // LIMIT/OFFSET, 0 leaves out
+	Placeholder(index int) string                                                                                                                               // Bind parameter marker
+}
+
+// ReturningInsertGenerator is implemented by dialects whose drivers do not report LastInsertId,
+// the generated key is read back through INSERT ... RETURNING instead.
+type ReturningInsertGenerator interface {
+	GenerateInsertReturningQuery(table string, columns []string, values []interface{}, returning string) (string, []interface{})
+}
+
+// TableRebuilder is implemented by dialects whose ALTER TABLE cannot change columns or
+// constraints in place. The table is created again with schema and the values of columns are
+// copied over.
+type TableRebuilder interface {
+	GenerateRebuildTableQuery(table string, schema configs.TableSchema, columns []string) string
+	// GenerateRebuildTableStatements returns the statements of the rebuild without the
+	// transaction and foreign key pragmas GenerateRebuildTableQuery wraps them in, for callers
+	// that run them one by one in a transaction of their own.
+	GenerateRebuildTableStatements(table string, schema configs.TableSchema, columns []string) []string
+}
+
+// TransactionalDDL is implemented by dialects whose schema changes take part in transactions,
+// so a failing migration can be rolled back as a whole. MySQL commits on every DDL statement.
+type TransactionalDDL interface {
+	TransactionalDDL() bool
+}
+
+// MetaStoreReader is implemented by dialects that build some queries from the stored schema of
+// a table. WithMetaStore returns a generator reading the schema from store.
+type MetaStoreReader interface {
+	WithMetaStore(store configs.MetaStore) QueryGenerator
 }
@@ -3,65 +3,317 @@ package queries
 import (
 	"database/sql"
 	"fmt"
+	"regexp"
+	"sort"
+	"sqldocify/configs"
 	"strings"
+	"unicode"
 )
 
-func SanitizeValues(values []interface{}) string {
-	var sanitizedValues []string
-	for _, value := range values {
-		switch v := value.(type) {
-		case string:
-			// Escape single quotes by doubling them
-			sanitizedValues = append(sanitizedValues, fmt.Sprintf("'%s'", escapeSQLString(v)))
-		case int, int32, int64, float32, float64:
-			// Numbers and floats are used directly
-			sanitizedValues = append(sanitizedValues, fmt.Sprintf("%v", v))
-		case bool:
-			// Booleans are converted to 1 or 0
-			if v {
-				sanitizedValues = append(sanitizedValues, "1")
-			} else {
-				sanitizedValues = append(sanitizedValues, "0")
-			}
-		case nil:
-			// Handle null values
-			sanitizedValues = append(sanitizedValues, "NULL")
-		default:
-			// For other types, try to convert them to string, but may need further handling
-			sanitizedValues = append(sanitizedValues, fmt.Sprintf("'%v'", v))
-		}
-	}
-	return strings.Join(sanitizedValues, ", ")
-}
-
-func escapeSQLString(input string) string {
-	return strings.ReplaceAll(input, "'", "''")
-}
-
 func FormatColumns(columns []string) string {
 	return strings.Join(columns, ", ")
 }
 
-func SanitizeValue(value interface{}) string {
+// escapeIdentifiers escapes every name of a column list. Unlike escapeName, which leaves
+// expressions alone, any name is quoted, so reserved words and odd characters are safe.
+func escapeIdentifiers(qg QueryGenerator, names []string) string {
+	escaped := make([]string, len(names))
+	for i, name := range names {
+		escaped[i] = qg.EscapeIdentifier(name)
+	}
+	return strings.Join(escaped, ", ")
+}
+
+// formatLiteral writes value as a SQL literal for the statements that take no bind parameters,
+// like DEFAULT clauses and comments. Strings go through quote, the quoting of the dialect;
+// booleans are 1 and 0 and nil is NULL.
+func formatLiteral(value interface{}, quote func(string) string) string {
 	switch v := value.(type) {
+	case nil:
+		return "NULL"
 	case string:
-		return fmt.Sprintf("'%s'", escapeSQLString(v))
-	case int, int32, int64, float32, float64:
-		return fmt.Sprintf("%v", v)
+		return quote(v)
 	case bool:
 		if v {
 			return "1"
 		}
 		return "0"
-	case nil:
-		return "NULL"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
 	default:
-		return fmt.Sprintf("'%v'", v)
+		return quote(fmt.Sprint(v))
 	}
 }
+
+// quoteString quotes s as a standard SQL string literal, embedded quotes are doubled.
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
 func nilIfEmpty(nullString sql.NullString) interface{} {
 	if nullString.Valid {
 		return nullString.String
 	}
 	return nil
 }
+
+// placeholders returns n comma separated "?" markers for a VALUES list.
+func placeholders(n int) string {
+	markers := make([]string, n)
+	for i := range markers {
+		markers[i] = "?"
+	}
+	return strings.Join(markers, ", ")
+}
+
+// sortedKeys returns the keys of a column/value map in a stable order, so the
+// generated SQL and its bind arguments line up the same way on every call.
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// bindPlaceholders rewrites every "?" outside of quoted literals and identifiers into the
+// dialect's bind parameter marker. Generators build their SQL with "?" and call this last,
+// which also lets callers write conditions with "?" regardless of the database in use.
+func bindPlaceholders(query string, placeholder func(int) string) string {
+	if placeholder(1) == "?" {
+		return query
+	}
+	var sb strings.Builder
+	index := 0
+	var quote rune
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?':
+			index++
+			sb.WriteString(placeholder(index))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+var (
+	currentTimeDefault = regexp.MustCompile(`(?i)^((current_timestamp|current_date|current_time|localtimestamp|localtime)(\(\d*\))?|now\(\d*\))$`)
+	numericDefault     = regexp.MustCompile(`^[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?$`)
+	functionDefault    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*\s*\(.*\)$`)
+)
+
+// generateUpdateQuery is GenerateUpdateQuery for every dialect, the updates are set in sorted
+// column order.
+func generateUpdateQuery(qg QueryGenerator, table string, updates map[string]interface{}, condition Condition) (string, []interface{}, error) {
+	if len(updates) == 0 {
+		return "", nil, fmt.Errorf("update of %s sets no column", table)
+	}
+	if err := conditionErr(condition); err != nil {
+		return "", nil, err
+	}
+	setClauses := make([]string, 0, len(updates))
+	var args []interface{}
+	for _, column := range sortedKeys(updates) {
+		setClauses = append(setClauses, qg.EscapeIdentifier(column)+" = ?")
+		args = append(args, updates[column])
+	}
+	where, whereArgs := buildCondition(qg, condition)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s;", qg.EscapeIdentifier(table), strings.Join(setClauses, ", "), where)
+	return bindPlaceholders(query, qg.Placeholder), append(args, whereArgs...), nil
+}
+
+// generateDeleteQuery is GenerateDeleteQuery for every dialect.
+func generateDeleteQuery(qg QueryGenerator, table string, condition Condition) (string, []interface{}, error) {
+	if err := conditionErr(condition); err != nil {
+		return "", nil, err
+	}
+	where, args := buildCondition(qg, condition)
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s;", qg.EscapeIdentifier(table), where)
+	return bindPlaceholders(query, qg.Placeholder), args, nil
+}
+
+// isCurrentTimeDefault reports whether a default is one of the current time keywords,
+// which are accepted without parentheses.
+func isCurrentTimeDefault(value string) bool {
+	return currentTimeDefault.MatchString(strings.TrimSpace(value))
+}
+
+func isNumericDefault(value string) bool {
+	return numericDefault.MatchString(strings.TrimSpace(value))
+}
+
+// isFunctionDefault reports whether a default is a bare function call like uuid().
+func isFunctionDefault(value string) bool {
+	return functionDefault.MatchString(strings.TrimSpace(value))
+}
+
+// splitTopLevel splits a comma separated SQL list, commas inside parentheses, quoted
+// literals and quoted identifiers do not split.
+func splitTopLevel(list string) []string {
+	var parts []string
+	depth := 0
+	var quote rune
+	start := 0
+	for i, r := range list {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '[':
+			quote = ']'
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(list[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(list[start:]))
+}
+
+// closingParen returns the index of the parenthesis closing the one at open, or -1.
+func closingParen(text string, open int) int {
+	depth := 0
+	var quote rune
+	for i, r := range text[open:] {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth == 0 {
+				return open + i
+			}
+		}
+	}
+	return -1
+}
+
+// leadingIdentifier splits a definition into its first, possibly quoted, identifier and the rest.
+func leadingIdentifier(definition string) (string, string) {
+	definition = strings.TrimSpace(definition)
+	if definition == "" {
+		return "", ""
+	}
+	closing := map[byte]byte{'"': '"', '`': '`', '[': ']'}
+	if end, quoted := closing[definition[0]]; quoted {
+		if i := strings.IndexByte(definition[1:], end); i >= 0 {
+			return definition[1 : i+1], strings.TrimSpace(definition[i+2:])
+		}
+	}
+	if i := strings.IndexFunc(definition, unicode.IsSpace); i >= 0 {
+		return definition[:i], strings.TrimSpace(definition[i:])
+	}
+	return definition, ""
+}
+
+// inlinePrimaryKey reports whether column is the single primary key column of the table,
+// which is declared on the column itself. Composite keys become a table constraint.
+func inlinePrimaryKey(schema configs.TableSchema, column string) bool {
+	keys := schema.PrimaryKeyColumns()
+	return len(keys) == 1 && keys[0] == column
+}
+
+// tableConstraints renders the composite primary key, the unique constraints and the foreign
+// keys of a CREATE TABLE.
+func tableConstraints(qg QueryGenerator, table string, schema configs.TableSchema) []string {
+	var constraints []string
+	if keys := schema.PrimaryKeyColumns(); len(keys) > 1 {
+		constraints = append(constraints, fmt.Sprintf("PRIMARY KEY (%s)", escapeIdentifiers(qg, keys)))
+	}
+	for _, unique := range schema.Uniques {
+		constraint := fmt.Sprintf("UNIQUE (%s)", escapeIdentifiers(qg, unique.Columns))
+		if unique.Name != "" {
+			constraint = fmt.Sprintf("CONSTRAINT %s %s", qg.EscapeIdentifier(unique.Name), constraint)
+		}
+		constraints = append(constraints, constraint)
+	}
+	for _, foreignKey := range schema.ForeignKeys {
+		constraints = append(constraints, foreignKeyConstraint(qg, table, foreignKey))
+	}
+	return constraints
+}
+
+// foreignKeyConstraint renders a foreign key as a named table constraint.
+func foreignKeyConstraint(qg QueryGenerator, table string, foreignKey configs.ForeignKeyDef) string {
+	constraint := fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)", qg.EscapeIdentifier(foreignKeyName(table, foreignKey)),
+		escapeIdentifiers(qg, foreignKey.Columns), qg.EscapeIdentifier(foreignKey.ReferencedTable), escapeIdentifiers(qg, foreignKey.ReferencedColumns))
+	if foreignKey.OnDelete != "" {
+		constraint += " ON DELETE " + strings.ToUpper(foreignKey.OnDelete)
+	}
+	if foreignKey.OnUpdate != "" {
+		constraint += " ON UPDATE " + strings.ToUpper(foreignKey.OnUpdate)
+	}
+	return constraint
+}
+
+// foreignKeyName returns the name of a foreign key, an unnamed one is named after its table and columns.
+func foreignKeyName(table string, foreignKey configs.ForeignKeyDef) string {
+	if foreignKey.Name != "" {
+		return foreignKey.Name
+	}
+	return fmt.Sprintf("fk_%s_%s", table, strings.Join(foreignKey.Columns, "_"))
+}
+
+// sameForeignKey reports whether two foreign keys link the same columns to the same referenced
+// columns, whatever their names and actions.
+func sameForeignKey(a configs.ForeignKeyDef, b configs.ForeignKeyDef) bool {
+	return strings.EqualFold(a.ReferencedTable, b.ReferencedTable) &&
+		strings.EqualFold(FormatColumns(a.Columns), FormatColumns(b.Columns)) &&
+		strings.EqualFold(FormatColumns(a.ReferencedColumns), FormatColumns(b.ReferencedColumns))
+}
+
+// referentialAction normalizes a reported ON DELETE/ON UPDATE rule, the default NO ACTION is left empty.
+func referentialAction(rule string) string {
+	rule = strings.ToUpper(strings.TrimSpace(rule))
+	if rule == "NO ACTION" {
+		return ""
+	}
+	return rule
+}
+
+// indexName returns the name of an index, an unnamed index is named after its table and columns.
+// The name is escaped where it is written into a statement.
+func indexName(table string, index configs.IndexDef) string {
+	if index.Name != "" {
+		return index.Name
+	}
+	return fmt.Sprintf("idx_%s_%s", table, strings.Join(index.Columns, "_"))
+}
+
+// indexList collects the columns of the indexes of a table while keeping the order in which
+// the indexes were first seen.
+type indexList struct {
+	names   []string
+	columns map[string][]string
+}
+
+func (l *indexList) add(name string, column string) {
+	if l.columns == nil {
+		l.columns = make(map[string][]string)
+	}
+	if _, ok := l.columns[name]; !ok {
+		l.names = append(l.names, name)
+	}
+	l.columns[name] = append(l.columns[name], column)
+}
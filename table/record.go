@@ -0,0 +1,168 @@
+package table
+
+import (
+	"fmt"
+	"reflect"
+	"sqldocify/configs"
+	"sync"
+)
+
+// structField is one column backed by a (possibly embedded) struct field.
+type structField struct {
+	Column string
+	Index  []int
+	Tagged bool
+}
+
+var structFieldCache sync.Map // map[reflect.Type][]structField
+
+// structFields lists the column mapped fields of a struct type following the configs.TagName
+// tag, embedded structs are flattened.
+func structFields(t reflect.Type) []structField {
+	if cached, ok := structFieldCache.Load(t); ok {
+		return cached.([]structField)
+	}
+	var fields []structField
+	collectStructFields(t, nil, &fields)
+	structFieldCache.Store(t, fields)
+	return fields
+}
+
+func collectStructFields(t reflect.Type, index []int, fields *[]structField) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, tagged := field.Tag.Lookup(configs.TagName)
+		name, _ := configs.ParseTag(tag)
+		if name == "-" {
+			continue
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct && embedded != timeType {
+				collectStructFields(embedded, fieldIndex, fields)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = configs.SnakeCase(field.Name)
+		}
+		*fields = append(*fields, structField{Column: name, Index: fieldIndex, Tagged: tagged})
+	}
+}
+
+// toRecord turns a struct, a pointer to a struct or a column/value map into column/value pairs.
+// For structs the struct value itself is returned as well, addressable when dt was a pointer.
+// When schema is known untagged fields without a matching column are left out, while an
+// unknown column that was asked for explicitly is an error. Generated columns cannot be
+// written, struct fields mapped to one are left out and a map naming one is an error.
+func toRecord(dt interface{}, tableName string, schema *configs.TableSchema) (map[string]interface{}, reflect.Value, error) {
+	if record, ok := dt.(map[string]interface{}); ok {
+		if schema != nil {
+			for column := range record {
+				fieldSchema, known := schema.Column(column)
+				if !known {
+					return nil, reflect.Value{}, fmt.Errorf("column %s does not exist in table %s", column, tableName)
+				}
+				if fieldSchema.Generated != "" {
+					return nil, reflect.Value{}, fmt.Errorf("column %s of table %s is generated and cannot be written", column, tableName)
+				}
+			}
+		}
+		return record, reflect.Value{}, nil
+	}
+
+	value := reflect.ValueOf(dt)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, reflect.Value{}, fmt.Errorf("cannot use a nil %T as a record", dt)
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, reflect.Value{}, fmt.Errorf("unsupported record type %T", dt)
+	}
+
+	record := make(map[string]interface{})
+	for _, field := range structFields(value.Type()) {
+		if schema != nil {
+			fieldSchema, known := schema.Column(field.Column)
+			if !known {
+				if field.Tagged {
+					return nil, reflect.Value{}, fmt.Errorf("column %s does not exist in table %s", field.Column, tableName)
+				}
+				continue
+			}
+			if fieldSchema.Generated != "" {
+				continue
+			}
+		}
+		fieldValue, ok := fieldByIndex(value, field.Index)
+		if !ok {
+			// The field sits behind a nil embedded pointer, there is no value to store.
+			record[field.Column] = nil
+			continue
+		}
+		record[field.Column] = fieldValue.Interface()
+	}
+	return record, value, nil
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex without the panic on nil embedded pointers.
+func fieldByIndex(value reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return reflect.Value{}, false
+			}
+			value = value.Elem()
+		}
+		value = value.Field(x)
+	}
+	return value, true
+}
+
+// isZeroValue reports whether v is nil or the zero value of its type.
+func isZeroValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	return reflect.ValueOf(v).IsZero()
+}
+
+// setGeneratedID stores a database generated id in the struct field mapped to column.
+func setGeneratedID(structValue reflect.Value, column string, id int64) error {
+	if !structValue.IsValid() || !structValue.CanSet() {
+		return nil
+	}
+	for _, field := range structFields(structValue.Type()) {
+		if field.Column != column {
+			continue
+		}
+		fieldValue, ok := fieldByIndex(structValue, field.Index)
+		if !ok {
+			return nil
+		}
+		if fieldValue.Kind() == reflect.Ptr {
+			fieldValue.Set(reflect.New(fieldValue.Type().Elem()))
+			fieldValue = fieldValue.Elem()
+		}
+		switch fieldValue.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			fieldValue.SetInt(id)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			fieldValue.SetUint(uint64(id))
+		default:
+			return fmt.Errorf("cannot store generated id in field of type %s", fieldValue.Type())
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package table
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// timeLayouts are the textual time formats drivers hand back when a column is not parsed for us,
+// MySQL without parseTime=true and SQLite TEXT timestamps among them.
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC3339Nano,
+}
+
+// scanInto reads every row into dest, a pointer to a struct or to a slice of structs
+// or struct pointers. Columns without a matching field are skipped. A pointer to a single
+// struct receives the first row and sql.ErrNoRows when there is none.
+func scanInto(rows *sql.Rows, dest interface{}) error {
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
+		return fmt.Errorf("fetch result must be a non-nil pointer, got %T", dest)
+	}
+	target := destValue.Elem()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case target.Kind() == reflect.Struct:
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return sql.ErrNoRows
+		}
+		return scanStruct(rows, columns, target)
+
+	case target.Kind() == reflect.Slice:
+		elemType := target.Type().Elem()
+		isPtr := elemType.Kind() == reflect.Ptr
+		structType := elemType
+		if isPtr {
+			structType = elemType.Elem()
+		}
+		if structType.Kind() != reflect.Struct {
+			return fmt.Errorf("unsupported fetch result type %T", dest)
+		}
+		items := reflect.MakeSlice(target.Type(), 0, 0)
+		for rows.Next() {
+			item := reflect.New(structType).Elem()
+			if err := scanStruct(rows, columns, item); err != nil {
+				return err
+			}
+			if isPtr {
+				item = item.Addr()
+			}
+			items = reflect.Append(items, item)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		target.Set(items)
+		return nil
+	}
+	return fmt.Errorf("unsupported fetch result type %T", dest)
+}
+
+// scanStruct scans the current row and assigns every column to the field mapped to it.
+func scanStruct(rows *sql.Rows, columns []string, target reflect.Value) error {
+	raw := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range raw {
+		pointers[i] = &raw[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return err
+	}
+
+	fieldIndex := make(map[string][]int)
+	for _, field := range structFields(target.Type()) {
+		fieldIndex[field.Column] = field.Index
+	}
+	for i, column := range columns {
+		index, ok := fieldIndex[column]
+		if !ok {
+			continue
+		}
+		field := fieldByIndexAlloc(target, index)
+		if err := assignValue(field, raw[i]); err != nil {
+			return fmt.Errorf("column %s: %w", column, err)
+		}
+	}
+	return nil
+}
+
+// fieldByIndexAlloc walks to a field, allocating nil embedded struct pointers on the way.
+func fieldByIndexAlloc(value reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(x)
+	}
+	return value
+}
+
+// assignValue stores a raw driver value in field, converting between the representations
+// the drivers use (e.g. []byte for text and numbers, int64 for booleans).
+// NULL leaves the zero value, nil for pointers.
+func assignValue(field reflect.Value, raw interface{}) error {
+	if field.CanAddr() && field.Addr().Type().Implements(scannerType) {
+		return field.Addr().Interface().(sql.Scanner).Scan(raw)
+	}
+	if raw == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if field.Kind() == reflect.Ptr {
+		elem := reflect.New(field.Type().Elem())
+		if err := assignValue(elem.Elem(), raw); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+
+	if field.Type() == timeType {
+		t, err := toTime(raw)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(toText(raw))
+		return nil
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.Uint8 {
+			switch v := raw.(type) {
+			case []byte:
+				field.SetBytes(append([]byte{}, v...))
+				return nil
+			case string:
+				field.SetBytes([]byte(v))
+				return nil
+			}
+		}
+	case reflect.Bool:
+		switch v := raw.(type) {
+		case bool:
+			field.SetBool(v)
+			return nil
+		case int64:
+			field.SetBool(v != 0)
+			return nil
+		case []byte, string:
+			b, err := strconv.ParseBool(toText(v))
+			if err != nil {
+				return err
+			}
+			field.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch v := raw.(type) {
+		case int64:
+			field.SetInt(v)
+			return nil
+		case float64:
+			field.SetInt(int64(v))
+			return nil
+		case bool:
+			if v {
+				field.SetInt(1)
+			} else {
+				field.SetInt(0)
+			}
+			return nil
+		case []byte, string:
+			n, err := strconv.ParseInt(toText(v), 10, field.Type().Bits())
+			if err != nil {
+				return err
+			}
+			field.SetInt(n)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch v := raw.(type) {
+		case int64:
+			field.SetUint(uint64(v))
+			return nil
+		case uint64:
+			field.SetUint(v)
+			return nil
+		case []byte, string:
+			n, err := strconv.ParseUint(toText(v), 10, field.Type().Bits())
+			if err != nil {
+				return err
+			}
+			field.SetUint(n)
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch v := raw.(type) {
+		case float64:
+			field.SetFloat(v)
+			return nil
+		case int64:
+			field.SetFloat(float64(v))
+			return nil
+		case []byte, string:
+			f, err := strconv.ParseFloat(toText(v), field.Type().Bits())
+			if err != nil {
+				return err
+			}
+			field.SetFloat(f)
+			return nil
+		}
+	}
+
+	rawValue := reflect.ValueOf(raw)
+	if rawValue.Type().ConvertibleTo(field.Type()) {
+		field.Set(rawValue.Convert(field.Type()))
+		return nil
+	}
+	return fmt.Errorf("cannot assign %T to field of type %s", raw, field.Type())
+}
+
+func toText(raw interface{}) string {
+	switch v := raw.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(raw)
+}
+
+func toTime(raw interface{}) (time.Time, error) {
+	switch v := raw.(type) {
+	case time.Time:
+		return v, nil
+	case int64:
+		return time.Unix(v, 0).UTC(), nil
+	case []byte, string:
+		text := strings.TrimSpace(toText(v))
+		if text == "" || strings.HasPrefix(text, "0000-00-00") {
+			// MySQL zero dates carry no instant, they map onto the zero time.
+			return time.Time{}, nil
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, text); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot parse %q as time", text)
+	}
+	return time.Time{}, fmt.Errorf("cannot assign %T to a time field", raw)
+}
@@ -2,21 +2,28 @@ package table
 
 import "sqldocify/configs"
 
-type UpdateDiffs struct {
+// FieldDiff is one column changed by an update with its value before and after.
+type FieldDiff struct {
 	FieldName string
 	OldValue  interface{}
 	NewValue  interface{}
 }
 
+// UpdateDiffs lists every column an update changed, in column order.
+type UpdateDiffs []FieldDiff
+
 type ITableSpec interface {
-	GetMetaDataSchema() (interface{}, error)
+	GetMetaDataSchema(db *configs.Database) (interface{}, error)
 	TableExists(nm string, db *configs.Database) bool
-	CreateTable(db *configs.Database, nm string, schema map[string]configs.FieldSchema) error
+	CreateTable(db *configs.Database, nm string, schema configs.TableSchema) error
+	CreateTables(db *configs.Database, schemas map[string]configs.TableSchema) ([]string, error)
+	AddForeignKey(db *configs.Database, nm string, foreignKey configs.ForeignKeyDef) error
 	Insert(dt interface{}, db *configs.Database) error
 	Update(dt interface{}, onUpdate func() error, db *configs.Database) (UpdateDiffs, error)
 	Delete(condition interface{}, db *configs.Database) error
 	Fetch(condition interface{}, result interface{}, db *configs.Database) error
-	BeginTransaction(db *configs.Database) error
+	FetchJoined(query JoinQuery, result interface{}, db *configs.Database) error
+	BeginTransaction(db *configs.Database) (*TableSpec, error)
 	CommitTransaction(db *configs.Database) error
 	RollbackTransaction(db *configs.Database) error
 	BatchInsert(dts []interface{}, db *configs.Database) error
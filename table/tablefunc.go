@@ -1,5 +1,16 @@
 package table
 
+import (
+	"database/sql"
+	"database/sql/driver"
+	"math/big"
+	"reflect"
+	"sort"
+	"sqldocify/configs"
+	"strconv"
+	"time"
+)
+
 func contains(slice []string, item string) bool {
 	for _, v := range slice {
 		if v == item {
@@ -8,3 +19,229 @@ func contains(slice []string, item string) bool {
 	}
 	return false
 }
+
+func sortedColumns(record map[string]interface{}) []string {
+	columns := make([]string, 0, len(record))
+	for column := range record {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// sameColumns reports whether record has exactly the given columns.
+func sameColumns(columns []string, record map[string]interface{}) bool {
+	if len(columns) != len(record) {
+		return false
+	}
+	for _, column := range columns {
+		if _, ok := record[column]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// isEmptyCondition reports whether a condition would match every row.
+func isEmptyCondition(condition interface{}) bool {
+	switch c := condition.(type) {
+	case nil:
+		return true
+	case string:
+		return c == ""
+	case map[string]interface{}:
+		return len(c) == 0
+	}
+	return false
+}
+
+// scanRowMaps reads every row into a column/value map. Drivers hand text columns back as
+// []byte, those are turned into strings so the maps are usable without knowing the driver.
+func scanRowMaps(rows *sql.Rows) ([]map[string]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	records := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		record := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				record[column] = string(b)
+				continue
+			}
+			record[column] = values[i]
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// singleResult reports whether a fetch result holds one row rather than a slice of them.
+func singleResult(result interface{}) bool {
+	if _, ok := result.(*map[string]interface{}); ok {
+		return true
+	}
+	value := reflect.ValueOf(result)
+	return value.Kind() == reflect.Ptr && value.Elem().Kind() == reflect.Struct
+}
+
+// sameValue compares a value read from the database with the Go value about to be written.
+// Drivers return their own representations (int64 for booleans, text for times and numbers),
+// so both sides are brought to a common form before comparing.
+func sameValue(stored interface{}, value interface{}) bool {
+	// The converter rejects unsigned values above math.MaxInt64.
+	if u, ok := unsignedValue(value); ok {
+		if stored == nil {
+			return false
+		}
+		if same, ok := sameInteger(stored, new(big.Rat).SetUint64(u)); ok {
+			return same
+		}
+		return toText(stored) == strconv.FormatUint(u, 10)
+	}
+	converted, err := driver.DefaultParameterConverter.ConvertValue(value)
+	if err != nil {
+		return false
+	}
+	if stored == nil || converted == nil {
+		return stored == nil && converted == nil
+	}
+
+	switch v := converted.(type) {
+	case bool:
+		switch s := stored.(type) {
+		case bool:
+			return s == v
+		case int64:
+			return (s != 0) == v
+		}
+	case time.Time:
+		storedTime, err := toTime(stored)
+		return err == nil && storedTime.Equal(v)
+	case int64:
+		if same, ok := sameInteger(stored, new(big.Rat).SetInt64(v)); ok {
+			return same
+		}
+	case float64:
+		if f, ok := toFloat(stored); ok {
+			return f == v
+		}
+	case []byte:
+		return toText(stored) == string(v)
+	}
+	return toText(stored) == toText(converted)
+}
+
+// unsignedValue returns the value of an unsigned integer, directly or behind pointers.
+// A driver.Valuer gives its own value.
+func unsignedValue(value interface{}) (uint64, bool) {
+	if _, ok := value.(driver.Valuer); ok {
+		return 0, false
+	}
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), true
+	}
+	return 0, false
+}
+
+// sameInteger compares a stored number with an integer exactly, a float64 holds integers
+// above 2^53 only approximately. ok is false when stored is not a number.
+func sameInteger(stored interface{}, integer *big.Rat) (same bool, ok bool) {
+	var number *big.Rat
+	switch s := stored.(type) {
+	case int64:
+		number = new(big.Rat).SetInt64(s)
+	case uint64:
+		number = new(big.Rat).SetUint64(s)
+	case float64:
+		number = new(big.Rat).SetFloat64(s)
+	case string:
+		// DECIMAL columns come back as text.
+		number, _ = new(big.Rat).SetString(s)
+	}
+	if number == nil {
+		return false, false
+	}
+	return number.Cmp(integer) == 0, true
+}
+
+// toFloat reads a stored number, DECIMAL columns come back as text.
+func toFloat(stored interface{}) (float64, bool) {
+	switch s := stored.(type) {
+	case int64:
+		return float64(s), true
+	case float64:
+		return s, true
+	case string:
+		f, err := strconv.ParseFloat(s, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// creationOrder orders tables so that a referenced table comes before the tables referencing it.
+// Foreign keys still pointing at a table created later, which only happens for reference cycles,
+// are returned per table to be added once every table exists. References to the table itself
+// and to tables outside of schemas stay in place.
+func creationOrder(schemas map[string]configs.TableSchema) ([]string, map[string][]configs.ForeignKeyDef) {
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	visited := make(map[string]bool)
+	var order []string
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		for _, foreignKey := range schemas[name].ForeignKeys {
+			if _, ok := schemas[foreignKey.ReferencedTable]; ok {
+				visit(foreignKey.ReferencedTable)
+			}
+		}
+		order = append(order, name)
+	}
+	for _, name := range names {
+		visit(name)
+	}
+
+	created := make(map[string]bool)
+	deferred := make(map[string][]configs.ForeignKeyDef)
+	for _, name := range order {
+		created[name] = true
+		for _, foreignKey := range schemas[name].ForeignKeys {
+			if _, ok := schemas[foreignKey.ReferencedTable]; ok && !created[foreignKey.ReferencedTable] {
+				deferred[name] = append(deferred[name], foreignKey)
+			}
+		}
+	}
+	return order, deferred
+}
+
+// containsForeignKey reports whether foreignKeys has a key over the same columns to the same table.
+func containsForeignKey(foreignKeys []configs.ForeignKeyDef, foreignKey configs.ForeignKeyDef) bool {
+	for _, existing := range foreignKeys {
+		if existing.ReferencedTable == foreignKey.ReferencedTable && reflect.DeepEqual(existing.Columns, foreignKey.Columns) {
+			return true
+		}
+	}
+	return false
+}
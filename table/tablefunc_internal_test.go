@@ -0,0 +1,42 @@
+package table
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSameValue(t *testing.T) {
+	const big = int64(1) << 60
+	tests := []struct {
+		name   string
+		stored interface{}
+		value  interface{}
+		want   bool
+	}{
+		{"equal int64", big, big, true},
+		{"int64 above 2^53", big, big + 1, false},
+		{"int from float", float64(big), big + 1, false},
+		{"int from decimal text", "42", 42, true},
+		{"int from decimal text with scale", "42.00", 42, true},
+		{"int64 from long decimal text", "1152921504606846976", big + 1, false},
+		{"int32", int64(7), int32(7), true},
+		{"pointer", int64(7), func() *int { v := 7; return &v }(), true},
+		{"max uint64", "18446744073709551615", uint64(math.MaxUint64), true},
+		{"max uint64 changed", "18446744073709551614", uint64(math.MaxUint64), false},
+		{"uint64 stored as uint64", uint64(math.MaxUint64), uint64(math.MaxUint64), true},
+		{"small uint", int64(3), uint8(3), true},
+		{"uint from nil", nil, uint(0), false},
+		{"float", 1.5, 1.5, true},
+		{"float from text", "0.1", 0.1, true},
+		{"bool", int64(1), true, true},
+		{"time", "2024-01-02 03:04:05", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), true},
+		{"nil", nil, nil, true},
+		{"text", "a", "b", false},
+	}
+	for _, tt := range tests {
+		if got := sameValue(tt.stored, tt.value); got != tt.want {
+			t.Errorf("%s: sameValue(%#v, %#v) = %v, want %v", tt.name, tt.stored, tt.value, got, tt.want)
+		}
+	}
+}